@@ -0,0 +1,309 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/oracle/zfssa-csi-driver/pkg/utils"
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// The driver can provision volumes on several appliances. The appliance designated
+// by ZFSSA_TARGET is the default appliance. Additional appliances are listed in the
+// file pointed to by ZFSSA_APPLIANCES (defaults to "/mnt/config/appliances.yaml"):
+//
+//	appliances:
+//	  - target: zfssa2.example.com
+//	    credentials: /mnt/zfssa2/zfssa.yaml
+//	    certificate: /mnt/certs2/zfssa.crt
+//	  - target: 10.0.0.12
+//	    credentials: /mnt/zfssa3/zfssa.yaml
+//	    insecure: true
+//
+// The name of the appliance is the second component of the volume and snapshot IDs
+// and is what the StorageClass parameter "appliance" designates. Every request
+// carrying a volume or snapshot ID is routed to the appliance named in the ID.
+
+// An appliance known to the driver with its own REST client and credentials file.
+type zAppliance struct {
+	name         string
+	client       *zfssarest.Client
+	credLocation string
+	certLocation string
+	secure       bool
+	scopes       []listScope // Pools and projects listed, all if empty (see scope.go)
+	// Client certificate and key presented to the appliance (mutual TLS), if any
+	clientCertLocation string
+	clientKeyLocation  string
+	creds              atomic.Pointer[ZfssaCredentials] // Cached content of the credentials file
+	reloadMtx          sync.Mutex                       // Serializes the reloads of the files
+	credHash           [sha256.Size]byte                // Hash of the credentials file loaded
+	certHash           [sha256.Size]byte                // Hash of the certificate loaded
+	clientCertHash     [sha256.Size]byte                // Hash of the client certificate and key loaded
+	reloadErr          string                           // Last reload error reported
+}
+
+// Structured data of the appliances file.
+type appliancesConfig struct {
+	Appliances []applianceConfig `yaml:"appliances"`
+}
+
+type applianceConfig struct {
+	Target            string    `yaml:"target"`
+	Credentials       string    `yaml:"credentials"`
+	Certificate       string    `yaml:"certificate"`
+	Insecure          bool      `yaml:"insecure"`
+	Pin               pinConfig `yaml:",inline"`
+	ClientCertificate string    `yaml:"clientCertificate"`
+	ClientKey         string    `yaml:"clientKey"`
+	Scopes            []string  `yaml:"scopes"`
+}
+
+// Adds the default appliance and the appliances of the appliances file (if present)
+// to the driver.
+func (zd *ZFSSADriver) initAppliances() error {
+
+	err := zd.addAppliance(&applianceConfig{
+		Target:            zd.config.Appliance,
+		Credentials:       zd.config.CredLocation,
+		Certificate:       zd.config.CertLocation,
+		Insecure:          !zd.config.Secure,
+		Pin:               zd.config.Pin,
+		ClientCertificate: zd.config.ClientCertLocation,
+		ClientKey:         zd.config.ClientKeyLocation,
+		Scopes:            zd.config.ListScopes,
+	})
+	if err != nil {
+		return err
+	}
+
+	yamlData, err := os.ReadFile(zd.config.AppliancesLocation)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.New(fmt.Sprintf("the appliances file <%s> could not be read: <%s>",
+			zd.config.AppliancesLocation, err))
+	}
+
+	var appliances appliancesConfig
+	err = yaml.Unmarshal(yamlData, &appliances)
+	if err != nil {
+		return errors.New(fmt.Sprintf("the appliances file <%s> could not be parsed: <%s>",
+			zd.config.AppliancesLocation, err))
+	}
+
+	for i := range appliances.Appliances {
+		appliance := &appliances.Appliances[i]
+		appliance.Target = strings.TrimSpace(appliance.Target)
+		target := appliance.Target
+		if len(target) == 0 {
+			return errors.New(fmt.Sprintf("an appliance of <%s> has no target",
+				zd.config.AppliancesLocation))
+		}
+		appliance.Credentials = strings.TrimSpace(appliance.Credentials)
+		if _, err := os.Stat(appliance.Credentials); err != nil {
+			return errors.New(fmt.Sprintf("the credentials file of appliance <%s> is not present at location: <%s>",
+				target, appliance.Credentials))
+		}
+		appliance.Certificate = strings.TrimSpace(appliance.Certificate)
+		if !appliance.Insecure && !appliance.Pin.enabled() {
+			if _, err := os.Stat(appliance.Certificate); err != nil {
+				return errors.New(fmt.Sprintf("the certificate of appliance <%s> is not present at location: <%s>",
+					target, appliance.Certificate))
+			}
+		}
+		appliance.ClientCertificate = strings.TrimSpace(appliance.ClientCertificate)
+		appliance.ClientKey = strings.TrimSpace(appliance.ClientKey)
+		if err := zd.addAppliance(appliance); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Creates the REST client of an appliance and adds the appliance to the driver. If the
+// certificate of the appliance is pinned, the certificate location is ignored.
+func (zd *ZFSSADriver) addAppliance(config *applianceConfig) error {
+
+	name := config.Target
+	pin := &config.Pin
+	secure := !config.Insecure
+	if zd.appliances == nil {
+		zd.appliances = make(map[string]*zAppliance)
+	}
+	if _, ok := zd.appliances[name]; ok {
+		return errors.New(fmt.Sprintf("appliance <%s> is configured more than once", name))
+	}
+
+	if pin.enabled() {
+		secure = false
+	}
+	client, err := zfssarest.NewClient(name, config.Certificate, secure)
+	if err != nil {
+		return err
+	}
+	client.SetTimeouts(zd.config.Timeouts)
+	client.SetRetryPolicy(zd.config.RetryPolicy)
+	client.SetSessionTimeout(zd.config.SessionTimeout)
+	if pin.enabled() {
+		if err := pin.apply(client); err != nil {
+			return errors.New(fmt.Sprintf("the certificate pinning of appliance <%s> is invalid: <%s>",
+				name, err))
+		}
+	}
+
+	appliance := &zAppliance{
+		name:               name,
+		client:             client,
+		credLocation:       config.Credentials,
+		certLocation:       config.Certificate,
+		secure:             secure,
+		clientCertLocation: config.ClientCertificate,
+		clientKeyLocation:  config.ClientKey,
+	}
+	appliance.scopes, err = parseScopes(config.Scopes)
+	if err != nil {
+		return errors.New(fmt.Sprintf("the scopes of appliance <%s> are invalid: <%s>", name, err))
+	}
+	if err := appliance.loadCredentials(nil); err != nil {
+		return err
+	}
+	if secure {
+		if data, err := os.ReadFile(config.Certificate); err == nil {
+			appliance.certHash = sha256.Sum256(data)
+		}
+	}
+	if (config.ClientCertificate == "") != (config.ClientKey == "") {
+		return errors.New(fmt.Sprintf("appliance <%s> requires both a client certificate and a client key",
+			name))
+	}
+	if err := appliance.loadClientCertificate(nil); err != nil {
+		return err
+	}
+
+	zd.appliances[name] = appliance
+	return nil
+}
+
+// Returns the appliance whose name is passed in. An empty name designates the
+// default appliance.
+func (zd *ZFSSADriver) getAppliance(name string) (*zAppliance, error) {
+	if len(name) == 0 {
+		name = zd.config.Appliance
+	}
+	appliance, ok := zd.appliances[name]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "appliance (%s) is not configured", name)
+	}
+	return appliance, nil
+}
+
+// Returns the names of the appliances known to the driver, sorted.
+func (zd *ZFSSADriver) getApplianceNames() []string {
+	names := make([]string, 0, len(zd.appliances))
+	for name := range zd.appliances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Calls update for each appliance concurrently and returns an error if one of the calls
+// failed.
+func (zd *ZFSSADriver) updateAppliances(ctx context.Context,
+	update func(ctx context.Context, zfssa string) error) error {
+
+	names := zd.getApplianceNames()
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, zfssa := range names {
+		wg.Add(1)
+		go func(i int, zfssa string) {
+			defer wg.Done()
+			errs[i] = update(ctx, zfssa)
+		}(i, zfssa)
+	}
+	wg.Wait()
+
+	var err error
+	for _, errApp := range errs {
+		if errApp != nil {
+			err = errApp
+		}
+	}
+	return err
+}
+
+// Closes the sessions opened on the appliances.
+func (zd *ZFSSADriver) closeSessions(ctx context.Context) {
+	for _, name := range zd.getApplianceNames() {
+		zd.appliances[name].client.CloseSessions(ctx)
+	}
+}
+
+// Returns the REST client of the appliance passed in and the token of the account to
+// use with it. The account is taken from the secrets if present, otherwise from the
+// credentials file of the appliance.
+func (zd *ZFSSADriver) lookUpToken(ctx context.Context, name string, secrets map[string]string) (
+	*zfssarest.Client, *zfssarest.Token, error) {
+
+	appliance, err := zd.getAppliance(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, password, err := appliance.getUserLogin(ctx, secrets)
+	if err != nil {
+		return nil, nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+	}
+
+	return appliance.client, appliance.client.LookUpToken(ctx, user, password), nil
+}
+
+// Check the secrets map (typically in a request context) for a change in the username
+// and password or retrieve the username/password from the cached content of the
+// credentials file.
+func (za *zAppliance) getUserLogin(ctx context.Context, secrets map[string]string) (string, string, error) {
+	if secrets != nil {
+		user, ok := secrets["username"]
+		if ok {
+			password := secrets["password"]
+			return user, password, nil
+		}
+	}
+
+	creds := za.creds.Load()
+	if creds == nil {
+		utils.GetLogCTRL(ctx, 2).Println("ZFSSA credentials not loaded", "appliance", za.name)
+		return "", "", errors.New(fmt.Sprintf("the credentials of appliance <%s> are not loaded", za.name))
+	}
+
+	return creds.Username, creds.Password, nil
+}
+
+// Returns the name of the appliance embedded in a volume or snapshot ID. If the ID
+// cannot be parsed an empty string (the default appliance) is returned, the lookup
+// of the volume or snapshot will then fail.
+func applianceFromId(id string) string {
+	result := strings.Split(id, "/")
+	if len(result) < 3 {
+		return ""
+	}
+	return result[2]
+}
@@ -0,0 +1,211 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest"
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest/zfssatest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestInitAppliances(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+	other := zfssatest.NewServer(testUser, testPassword)
+	t.Cleanup(other.Close)
+
+	// The default appliance is added again by initAppliances.
+	zd.appliances = nil
+	dir := t.TempDir()
+	zd.config.AppliancesLocation = filepath.Join(dir, "appliances.yaml")
+	if err := zd.initAppliances(); err != nil {
+		t.Fatalf("initAppliances without an appliances file failed: %v", err)
+	}
+	if names := zd.getApplianceNames(); len(names) != 1 || names[0] != zfssa.Name() {
+		t.Fatalf("unexpected appliances %v", names)
+	}
+
+	appliances := "appliances:\n" +
+		"  - target: " + other.Name() + "\n" +
+		"    credentials: " + zd.config.CredLocation + "\n" +
+		"    certificate: " + other.CertFile() + "\n"
+	if err := os.WriteFile(zd.config.AppliancesLocation, []byte(appliances), 0600); err != nil {
+		t.Fatalf("cannot write the appliances file: %v", err)
+	}
+	zd.appliances = nil
+	if err := zd.initAppliances(); err != nil {
+		t.Fatalf("initAppliances failed: %v", err)
+	}
+	if len(zd.appliances) != 2 || zd.appliances[other.Name()] == nil {
+		t.Fatalf("unexpected appliances %v", zd.getApplianceNames())
+	}
+
+	// An appliance cannot be configured twice.
+	appliances += "  - target: " + other.Name() + "\n    insecure: true\n" +
+		"    credentials: " + zd.config.CredLocation + "\n"
+	if err := os.WriteFile(zd.config.AppliancesLocation, []byte(appliances), 0600); err != nil {
+		t.Fatalf("cannot write the appliances file: %v", err)
+	}
+	zd.appliances = nil
+	if err := zd.initAppliances(); err == nil {
+		t.Errorf("initAppliances accepted a duplicate appliance")
+	}
+}
+
+func TestApplianceFromId(t *testing.T) {
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{"/mnt/zfssa1/p0/k8s/pvc-1", "zfssa1"},
+		{"/lun/10.0.0.1:215/p0/k8s/pvc-1/snap-1", "10.0.0.1:215"},
+		{"/nfs/zfssa2/pvc-1/p0/local/k8s/pvc-1", "zfssa2"},
+		{"garbage", ""},
+	}
+	for _, tt := range tests {
+		if got := applianceFromId(tt.id); got != tt.want {
+			t.Errorf("applianceFromId(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestMultipleAppliances(t *testing.T) {
+	zd, zfssa1 := newTestDriver(t)
+	zfssa2 := addTestAppliance(t, zd)
+	ctx := testContext()
+
+	// The same name is used on both appliances.
+	volumeIds := make(map[string]string)
+	for _, zfssa := range []*zfssatest.Server{zfssa1, zfssa2} {
+		parameters := filesystemParameters()
+		parameters["appliance"] = zfssa.Name()
+		vol, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+			Name:               "pvc-1",
+			CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+			VolumeCapabilities: mountCapabilities(),
+			Parameters:         parameters,
+		})
+		if err != nil {
+			t.Fatalf("CreateVolume on %s failed: %v", zfssa.Name(), err)
+		}
+		volumeIds[zfssa.Name()] = vol.GetVolume().GetVolumeId()
+		if !strings.HasPrefix(volumeIds[zfssa.Name()], "/mnt/"+zfssa.Name()+"/") {
+			t.Errorf("volume ID %s does not designate %s", volumeIds[zfssa.Name()], zfssa.Name())
+		}
+	}
+
+	const href = "/api/storage/v2/pools/p0/projects/k8s/filesystems/pvc-1"
+	snap, err := zd.CreateSnapshot(ctx, &csi.CreateSnapshotRequest{
+		SourceVolumeId: volumeIds[zfssa2.Name()],
+		Name:           "snapshot-1",
+	})
+	if err != nil {
+		t.Fatalf("CreateSnapshot failed: %v", err)
+	}
+	if _, found := zfssa2.Lookup(href + "/snapshots/snapshot-1"); !found {
+		t.Errorf("snapshot not created on the second appliance")
+	}
+	if _, found := zfssa1.Lookup(href + "/snapshots/snapshot-1"); found {
+		t.Errorf("snapshot created on the default appliance")
+	}
+
+	// A volume cannot be created from a snapshot of another appliance.
+	_, err = zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-2",
+		VolumeCapabilities: mountCapabilities(),
+		Parameters:         filesystemParameters(),
+		VolumeContentSource: &csi.VolumeContentSource{
+			Type: &csi.VolumeContentSource_Snapshot{
+				Snapshot: &csi.VolumeContentSource_SnapshotSource{SnapshotId: snap.GetSnapshot().GetSnapshotId()},
+			},
+		},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument cloning across appliances, got %v", err)
+	}
+
+	list, err := zd.ListVolumes(ctx, &csi.ListVolumesRequest{})
+	if err != nil || len(list.GetEntries()) != 2 {
+		t.Fatalf("ListVolumes returned %v, %v", list, err)
+	}
+
+	if _, err = zd.DeleteSnapshot(ctx, &csi.DeleteSnapshotRequest{
+		SnapshotId: snap.GetSnapshot().GetSnapshotId()}); err != nil {
+		t.Fatalf("DeleteSnapshot failed: %v", err)
+	}
+	if _, err = zd.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: volumeIds[zfssa2.Name()]}); err != nil {
+		t.Fatalf("DeleteVolume failed: %v", err)
+	}
+	if _, found := zfssa2.Lookup(href); found {
+		t.Errorf("volume still present on the second appliance")
+	}
+	if _, found := zfssa1.Lookup(href); !found {
+		t.Errorf("volume of the default appliance was deleted")
+	}
+}
+
+func TestUnknownAppliance(t *testing.T) {
+	zd, _ := newTestDriver(t)
+	ctx := testContext()
+
+	parameters := filesystemParameters()
+	parameters["appliance"] = "unknown"
+	_, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-1",
+		VolumeCapabilities: mountCapabilities(),
+		Parameters:         parameters,
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got %v", err)
+	}
+
+	// A volume of an appliance no longer configured is not reported as deleted.
+	_, err = zd.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: "/mnt/unknown/p0/k8s/pvc-1"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestRetryPolicyFromEnv(t *testing.T) {
+	policy, err := getRetryPolicy()
+	if err != nil || policy != zfssarest.DefaultRetryPolicy {
+		t.Errorf("unexpected default policy %+v, %v", policy, err)
+	}
+
+	t.Setenv("ZFSSA_RETRY_ATTEMPTS", "6")
+	t.Setenv("ZFSSA_RETRY_BASE_DELAY", "1s")
+	t.Setenv("ZFSSA_RETRY_MAX_DELAY", "30s")
+	t.Setenv("ZFSSA_RETRY_BUDGET", "20")
+	policy, err = getRetryPolicy()
+	if err != nil {
+		t.Fatalf("getRetryPolicy failed: %v", err)
+	}
+	if policy.MaxAttempts != 6 || policy.BaseDelay != time.Second || policy.MaxDelay != 30*time.Second ||
+		policy.Budget != 20 || policy.BudgetRatio != zfssarest.DefaultRetryPolicy.BudgetRatio {
+		t.Errorf("unexpected policy %+v", policy)
+	}
+
+	for key, value := range map[string]string{
+		"ZFSSA_RETRY_ATTEMPTS":   "0",
+		"ZFSSA_RETRY_BASE_DELAY": "1 minute",
+		"ZFSSA_RETRY_MAX_DELAY":  "500ms",
+		"ZFSSA_RETRY_BUDGET":     "-1",
+	} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			if _, err := getRetryPolicy(); err == nil {
+				t.Errorf("%s %s accepted", key, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/oracle/zfssa-csi-driver/pkg/utils"
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"path"
+	"strings"
+)
+
+// A volume is cloned from another volume by taking a snapshot of the source volume and
+// cloning the snapshot. The snapshot is managed by the driver: it is named after the
+// clone ("zcsi-clone-<name of the clone>"), it is not reported to the CO and it is
+// deleted when the last clone depending on it is deleted. As long as the clone exists,
+// the source volume cannot be deleted.
+
+// Prefix of the names of the snapshots taken to clone a volume.
+const cloneSnapshotPrefix = "zcsi-clone-"
+
+// Returns true if the snapshot whose name is passed in was taken by the driver to clone
+// a volume.
+func isCloneSnapshot(name string) bool {
+	return strings.HasPrefix(name, cloneSnapshotPrefix)
+}
+
+// Returns the size of the clone of the volume passed in. The clone gets the size of its
+// source unless a larger size is required. A size of 0 is returned when the size of the
+// source is kept.
+func cloneSize(capacityRange *csi.CapacityRange, zsrc zVolumeInterface) (int64, error) {
+	capacity := zsrc.getCapacity()
+	if capacityRange == nil {
+		return 0, nil
+	}
+	if capacityRange.LimitBytes > 0 && capacityRange.LimitBytes < capacity {
+		return 0, status.Errorf(codes.OutOfRange,
+			"Volume (%s) is larger (%d) than the limit of the clone (%d)",
+			zsrc.getVolumeID().String(), capacity, capacityRange.LimitBytes)
+	}
+	if capacityRange.RequiredBytes > capacity {
+		return capacityRange.RequiredBytes, nil
+	}
+	return 0, nil
+}
+
+// Takes the snapshot of the source volume the clone named after the name passed in will
+// be created from. The snapshot is returned if it already exists (retried request).
+func createCloneSnapshot(ctx context.Context, token *zfssarest.Token, zsrc zVolumeInterface,
+	name string) (*zfssarest.Snapshot, error) {
+
+	snapName := cloneSnapshotPrefix + name
+	utils.GetLogCTRL(ctx, 5).Println("Taking snapshot to clone volume", "volume", zsrc.getVolumeID().String(),
+		"snapshot", snapName)
+
+	client := zsrc.getClient()
+	snapinfo, _, err := client.CreateSnapshot(ctx, token, zsrc.getHref(), snapName)
+	if status.Code(err) == codes.AlreadyExists {
+		snapinfo, _, err = client.GetSnapshot(ctx, token, zsrc.getHref(), snapName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return snapinfo, nil
+}
+
+// Deletes the snapshot passed in if it was taken by the driver to clone a volume and no
+// clone depends on it anymore. A failure is only logged: the snapshot is deleted at the
+// latest with its volume.
+func releaseCloneSnapshot(ctx context.Context, client *zfssarest.Client, token *zfssarest.Token,
+	href string) {
+
+	if !isCloneSnapshot(path.Base(href)) {
+		return
+	}
+
+	dependents, err := client.GetSnapshotDependents(ctx, token, href)
+	if err != nil {
+		if status.Code(err) != codes.NotFound {
+			utils.GetLogCTRL(ctx, 2).Println("Dependents of clone snapshot unknown", "snapshot", href,
+				"error", err.Error())
+		}
+		return
+	}
+	if len(*dependents) > 0 {
+		return
+	}
+
+	utils.GetLogCTRL(ctx, 5).Println("Deleting clone snapshot", "snapshot", href)
+	_, _, err = client.DeleteSnapshot(ctx, token, href)
+	if err != nil && status.Code(err) != codes.NotFound {
+		utils.GetLogCTRL(ctx, 2).Println("Clone snapshot could not be deleted", "snapshot", href,
+			"error", err.Error())
+	}
+}
@@ -0,0 +1,200 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func volumeSource(volumeId string) *csi.VolumeContentSource {
+	return &csi.VolumeContentSource{
+		Type: &csi.VolumeContentSource_Volume{
+			Volume: &csi.VolumeContentSource_VolumeSource{VolumeId: volumeId},
+		},
+	}
+}
+
+func TestFilesystemVolumeCloneFlow(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+	ctx := testContext()
+
+	vol, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-src",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+		VolumeCapabilities: mountCapabilities(),
+		Parameters:         filesystemParameters(),
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume failed: %v", err)
+	}
+	volumeId := vol.GetVolume().GetVolumeId()
+
+	cloneReq := &csi.CreateVolumeRequest{
+		Name:                "pvc-clone",
+		CapacityRange:       &csi.CapacityRange{RequiredBytes: 2 * Gib},
+		VolumeCapabilities:  mountCapabilities(),
+		Parameters:          filesystemParameters(),
+		VolumeContentSource: volumeSource(volumeId),
+	}
+	clone, err := zd.CreateVolume(ctx, cloneReq)
+	if err != nil {
+		t.Fatalf("CreateVolume from a volume failed: %v", err)
+	}
+	cloneId := clone.GetVolume().GetVolumeId()
+	if clone.GetVolume().GetCapacityBytes() != 2*Gib {
+		t.Errorf("unexpected capacity of the clone %d", clone.GetVolume().GetCapacityBytes())
+	}
+	if clone.GetVolume().GetContentSource().GetVolume().GetVolumeId() != volumeId {
+		t.Errorf("unexpected content source %v", clone.GetVolume().GetContentSource())
+	}
+	fs, _ := zfssa.Lookup("/api/storage/v2/pools/p0/projects/k8s/filesystems/pvc-clone")
+	if fmt.Sprint(fs["quota"]) != fmt.Sprint(2*Gib) {
+		t.Errorf("unexpected quota of the clone %v", fs["quota"])
+	}
+	if fs["custom:zcsiNfsExport"] != nfsExportNode {
+		t.Errorf("export mode of the clone not recorded: %v", fs["custom:zcsiNfsExport"])
+	}
+
+	snapHref := "/api/storage/v2/pools/p0/projects/k8s/filesystems/pvc-src/snapshots/zcsi-clone-pvc-clone"
+	if _, found := zfssa.Lookup(snapHref); !found {
+		t.Fatalf("snapshot %s not found", snapHref)
+	}
+
+	// The snapshot taken to clone the volume is not reported.
+	list, err := zd.ListSnapshots(ctx, &csi.ListSnapshotsRequest{SourceVolumeId: volumeId})
+	if err != nil || len(list.GetEntries()) != 0 {
+		t.Errorf("ListSnapshots returned %v, %v", list, err)
+	}
+
+	// Cloning the same volume again is idempotent.
+	again, err := zd.CreateVolume(ctx, cloneReq)
+	if err != nil || again.GetVolume().GetVolumeId() != cloneId {
+		t.Fatalf("CreateVolume from a volume is not idempotent: %v, %v", again, err)
+	}
+
+	// The source volume cannot be deleted as long as the clone exists.
+	_, err = zd.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: volumeId})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("DeleteVolume of the source: FailedPrecondition expected, got %v", err)
+	}
+
+	if _, err = zd.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: cloneId}); err != nil {
+		t.Fatalf("DeleteVolume of the clone failed: %v", err)
+	}
+	if _, found := zfssa.Lookup(snapHref); found {
+		t.Errorf("snapshot %s still present on the appliance", snapHref)
+	}
+	if _, err = zd.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: volumeId}); err != nil {
+		t.Errorf("DeleteVolume of the source failed: %v", err)
+	}
+}
+
+func TestCloneVolumeCapacity(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+	ctx := testContext()
+
+	vol, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-src",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: 2 * Gib},
+		VolumeCapabilities: mountCapabilities(),
+		Parameters:         filesystemParameters(),
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume failed: %v", err)
+	}
+
+	// A clone smaller than its source is refused, no snapshot is left behind.
+	_, err = zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:                "pvc-small",
+		CapacityRange:       &csi.CapacityRange{RequiredBytes: Gib, LimitBytes: Gib},
+		VolumeCapabilities:  mountCapabilities(),
+		Parameters:          filesystemParameters(),
+		VolumeContentSource: volumeSource(vol.GetVolume().GetVolumeId()),
+	})
+	if status.Code(err) != codes.OutOfRange {
+		t.Errorf("CreateVolume of a smaller clone: OutOfRange expected, got %v", err)
+	}
+	if _, found := zfssa.Lookup(
+		"/api/storage/v2/pools/p0/projects/k8s/filesystems/pvc-src/snapshots/zcsi-clone-pvc-small"); found {
+		t.Errorf("snapshot of the refused clone present on the appliance")
+	}
+
+	// Without a larger size required, the clone gets the size of its source.
+	clone, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:                "pvc-same",
+		CapacityRange:       &csi.CapacityRange{RequiredBytes: Gib},
+		VolumeCapabilities:  mountCapabilities(),
+		Parameters:          filesystemParameters(),
+		VolumeContentSource: volumeSource(vol.GetVolume().GetVolumeId()),
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume from a volume failed: %v", err)
+	}
+	if clone.GetVolume().GetCapacityBytes() != 2*Gib {
+		t.Errorf("unexpected capacity of the clone %d", clone.GetVolume().GetCapacityBytes())
+	}
+
+	// A block volume cannot be cloned from a mount volume.
+	parameters := filesystemParameters()
+	parameters["targetGroup"] = "tg0"
+	_, err = zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:                "pvc-block",
+		CapacityRange:       &csi.CapacityRange{RequiredBytes: 2 * Gib},
+		VolumeCapabilities:  blockCapabilities(),
+		Parameters:          parameters,
+		VolumeContentSource: volumeSource(vol.GetVolume().GetVolumeId()),
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CreateVolume of a block clone: InvalidArgument expected, got %v", err)
+	}
+}
+
+func TestBlockVolumeClone(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+	ctx := testContext()
+
+	parameters := filesystemParameters()
+	parameters["targetGroup"] = "tg0"
+	vol, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-lun",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+		VolumeCapabilities: blockCapabilities(),
+		Parameters:         parameters,
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume failed: %v", err)
+	}
+
+	clone, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:                "pvc-lun-clone",
+		CapacityRange:       &csi.CapacityRange{RequiredBytes: 3 * Gib},
+		VolumeCapabilities:  blockCapabilities(),
+		Parameters:          parameters,
+		VolumeContentSource: volumeSource(vol.GetVolume().GetVolumeId()),
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume from a volume failed: %v", err)
+	}
+	if clone.GetVolume().GetCapacityBytes() != 3*Gib {
+		t.Errorf("unexpected capacity of the clone %d", clone.GetVolume().GetCapacityBytes())
+	}
+
+	snapHref := "/api/storage/v2/pools/p0/projects/k8s/luns/pvc-lun/snapshots/zcsi-clone-pvc-lun-clone"
+	if _, found := zfssa.Lookup(snapHref); !found {
+		t.Fatalf("snapshot %s not found", snapHref)
+	}
+	if _, err = zd.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: clone.GetVolume().GetVolumeId()}); err != nil {
+		t.Fatalf("DeleteVolume of the clone failed: %v", err)
+	}
+	if _, found := zfssa.Lookup(snapHref); found {
+		t.Errorf("snapshot %s still present on the appliance", snapHref)
+	}
+}
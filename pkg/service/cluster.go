@@ -7,16 +7,19 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
 var (
 	clusterConfig *rest.Config
-	clientset     *kubernetes.Clientset
+	clientset     kubernetes.Interface
 )
 
 // Initializes the cluster interface.
@@ -27,10 +30,11 @@ func InitClusterInterface() error {
 	if err != nil {
 		fmt.Print("not in cluster mode")
 	} else {
-		clientset, err = kubernetes.NewForConfig(clusterConfig)
+		cs, err := kubernetes.NewForConfig(clusterConfig)
 		if err != nil {
 			return errors.New("could not get Clientset for Kubernetes work")
 		}
+		clientset = cs
 	}
 
 	return nil
@@ -75,3 +79,74 @@ func GetNodeList(ctx context.Context) ([]string, error) {
 
 	return nodeNameList, nil
 }
+
+// Returns the value of an annotation of the node passed in, an empty string if the node
+// doesn't have the annotation or if the driver is not running in a cluster.
+func GetNodeAnnotation(ctx context.Context, nodeName, key string) (string, error) {
+	if clientset == nil {
+		return "", nil
+	}
+
+	node, err := clientset.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	return node.Annotations[key], nil
+}
+
+// Sets annotations of the node passed in.
+func SetNodeAnnotations(ctx context.Context, nodeName string, annotations map[string]string) error {
+	if clientset == nil {
+		return errors.New("not running in a cluster")
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = clientset.CoreV1().Nodes().Patch(ctx, nodeName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// Returns the internal IP addresses of the node passed in, its external addresses if it
+// doesn't have any internal address.
+func GetNodeAddresses(ctx context.Context, nodeName string) ([]string, error) {
+	if clientset == nil {
+		return nil, errors.New("not running in a cluster")
+	}
+
+	node, err := clientset.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var addresses []string
+	for _, addrType := range []corev1.NodeAddressType{corev1.NodeInternalIP, corev1.NodeExternalIP} {
+		for _, address := range node.Status.Addresses {
+			if address.Type == addrType {
+				addresses = append(addresses, address.Address)
+			}
+		}
+		if len(addresses) > 0 {
+			break
+		}
+	}
+	return addresses, nil
+}
+
+// Returns the UID of the kube-system namespace, which identifies the cluster.
+func GetClusterUID(ctx context.Context) (string, error) {
+	if clientset == nil {
+		return "", errors.New("not running in a cluster")
+	}
+
+	namespace, err := clientset.CoreV1().Namespaces().Get(ctx, "kube-system", metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	return string(namespace.UID), nil
+}
@@ -26,7 +26,7 @@ var (
 		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
 		csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT,
 		csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS,
-		// csi.ControllerServiceCapability_RPC_CLONE_VOLUME,
+		csi.ControllerServiceCapability_RPC_CLONE_VOLUME,
 	}
 )
 
@@ -41,22 +41,32 @@ func (zd *ZFSSADriver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRe
 	utils.GetLogCTRL(ctx, 5).Println("CreateVolume", "request", protosanitizer.StripSecrets(req))
 
 	// Token retrieved
-	user, password, err := zd.getUserLogin(ctx, req.Secrets)
+	parameters := req.GetParameters()
+	client, token, err := zd.lookUpToken(ctx, parameters["appliance"], req.Secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	// The project may be derived from the namespace of the PVC (see project.go)
+	namespaceProject, err := resolveProject(req)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+		return nil, err
 	}
-	token := zfssarest.LookUpToken(ctx, user, password)
 
 	// Validate the parameters
-	if err := validateCreateVolumeReq(ctx, token, req); err != nil {
+	if err := validateCreateVolumeReq(ctx, client, token, req, !namespaceProject); err != nil {
 		return nil, err
 	}
 
 	// TODO: check if pool/project are populated if the storage class is left out on volume cloneVolume
-	parameters := req.GetParameters()
 	pool := parameters["pool"]
 	project := parameters["project"]
-	zvol, err := zd.newVolume(ctx, pool, project,
+	if namespaceProject {
+		if err := ensureProject(ctx, client, token, pool, project); err != nil {
+			return nil, err
+		}
+	}
+	zvol, err := zd.newVolume(ctx, token.Name, pool, project,
 		req.GetName(), isBlock(req.GetVolumeCapabilities()))
 	if err != nil {
 		return nil, err
@@ -69,6 +79,10 @@ func (zd *ZFSSADriver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRe
 		case *csi.VolumeContentSource_Snapshot:
 			snapshot := volumeContentSource.GetSnapshot()
 			utils.GetLogCTRL(ctx, 5).Println("CreateSnapshot", "request", snapshot)
+			if applianceFromId(snapshot.GetSnapshotId()) != token.Name {
+				return nil, status.Errorf(codes.InvalidArgument,
+					"snapshot (%s) is not on appliance (%s)", snapshot.GetSnapshotId(), token.Name)
+			}
 			zsnap, err := zd.lookupSnapshot(ctx, token, snapshot.GetSnapshotId())
 			if err != nil {
 				return nil, err
@@ -78,8 +92,24 @@ func (zd *ZFSSADriver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRe
 		case *csi.VolumeContentSource_Volume:
 			volume := volumeContentSource.GetVolume()
 			utils.GetLogCTRL(ctx, 5).Println("CreateVolumeClone", "request", volume)
-			// cloneVolume creation is complex, delegate out to it
-			return zvol.cloneVolume(ctx, token, req)
+			if applianceFromId(volume.GetVolumeId()) != token.Name {
+				return nil, status.Errorf(codes.InvalidArgument,
+					"volume (%s) is not on appliance (%s)", volume.GetVolumeId(), token.Name)
+			}
+			zsrc, err := zd.lookupVolume(ctx, token, volume.GetVolumeId())
+			if err != nil {
+				return nil, err
+			}
+			defer zd.releaseVolume(ctx, zsrc)
+			if zsrc.isBlock() != zvol.isBlock() {
+				return nil, status.Errorf(codes.InvalidArgument,
+					"volume (%s) and its clone must both be block or mount volumes", volume.GetVolumeId())
+			}
+			if zsrc.getVolumeID().Pool != pool {
+				return nil, status.Errorf(codes.InvalidArgument,
+					"volume (%s) is not in pool (%s)", volume.GetVolumeId(), pool)
+			}
+			return zvol.cloneVolume(ctx, token, req, zsrc)
 		default:
 			return nil, status.Errorf(codes.InvalidArgument, "%v type not implemented in driver",
 				volumeContentSource.GetType())
@@ -115,8 +145,10 @@ func isBlock(capabilities []*csi.VolumeCapability) bool {
 	return true
 }
 
-// Validates as much of the "create volume request" as possible
-func validateCreateVolumeReq(ctx context.Context, token *zfssarest.Token, req *csi.CreateVolumeRequest) error {
+// Validates as much of the "create volume request" as possible. The project is only
+// required to exist on the appliance if projectExists is true.
+func validateCreateVolumeReq(ctx context.Context, client *zfssarest.Client, token *zfssarest.Token,
+	req *csi.CreateVolumeRequest, projectExists bool) error {
 
 	log5 := utils.GetLogCTRL(ctx, 5)
 
@@ -154,7 +186,7 @@ func validateCreateVolumeReq(ctx context.Context, token *zfssarest.Token, req *c
 		return status.Errorf(codes.InvalidArgument, "project name is invalid (%s)", projectName)
 	}
 
-	pool, err := zfssarest.GetPool(ctx, token, poolName)
+	pool, err := client.GetPool(ctx, token, poolName)
 	if err != nil {
 		return err
 	}
@@ -164,14 +196,16 @@ func validateCreateVolumeReq(ctx context.Context, token *zfssarest.Token, req *c
 		return status.Errorf(codes.InvalidArgument, "pool %s in an error state (%s)", poolName, pool.Status)
 	}
 
-	_, err = zfssarest.GetProject(ctx, token, poolName, projectName)
-	if err != nil {
-		return err
+	if projectExists {
+		_, err = client.GetProject(ctx, token, poolName, projectName)
+		if err != nil {
+			return err
+		}
 	}
 
 	// If this is a block request, the storage class must have the target group set and it must be on the target
 	if isBlock(reqCaps) {
-		err = validateCreateBlockVolumeReq(ctx, token, req)
+		err = validateCreateBlockVolumeReq(ctx, client, token, req)
 	} else {
 		err = validateCreateFilesystemVolumeReq(ctx, req)
 	}
@@ -187,19 +221,18 @@ func (zd *ZFSSADriver) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRe
 
 	log2 := utils.GetLogCTRL(ctx, 2)
 
-	// The account to be used for this operation is determined.
-	user, password, err := zd.getUserLogin(ctx, req.Secrets)
-	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
-	}
-	token := zfssarest.LookUpToken(ctx, user, password)
-
 	volumeID := req.GetVolumeId()
 	if len(volumeID) == 0 {
 		log2.Println("VolumeID not provided, will return")
 		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
 	}
 
+	// The account to be used for this operation is determined.
+	_, token, err := zd.lookUpToken(ctx, applianceFromId(volumeID), req.Secrets)
+	if err != nil {
+		return nil, err
+	}
+
 	zvol, err := zd.lookupVolume(ctx, token, volumeID)
 	if err != nil {
 		if status.Convert(err).Code() == codes.NotFound {
@@ -256,11 +289,10 @@ func (zd *ZFSSADriver) ControllerPublishVolume(ctx context.Context, req *csi.Con
 	}
 
 	// The account to be used for this operation is determined.
-	user, password, err := zd.getUserLogin(ctx, req.Secrets)
+	_, token, err := zd.lookUpToken(ctx, applianceFromId(volumeID), req.Secrets)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+		return nil, err
 	}
-	token := zfssarest.LookUpToken(ctx, user, password)
 
 	zvol, err := zd.lookupVolume(ctx, token, volumeID)
 	if err != nil {
@@ -287,11 +319,10 @@ func (zd *ZFSSADriver) ControllerUnpublishVolume(ctx context.Context, req *csi.C
 	}
 
 	// The account to be used for this operation is determined.
-	user, password, err := zd.getUserLogin(ctx, req.Secrets)
+	_, token, err := zd.lookUpToken(ctx, applianceFromId(volumeID), req.Secrets)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+		return nil, err
 	}
-	token := zfssarest.LookUpToken(ctx, user, password)
 
 	zvol, err := zd.lookupVolume(ctx, token, volumeID)
 	if err != nil {
@@ -324,15 +355,14 @@ func (zd *ZFSSADriver) ValidateVolumeCapabilities(ctx context.Context, req *csi.
 		return nil, status.Errorf(codes.InvalidArgument, "no accessModes provided")
 	}
 
-	user, password, err := zd.getUserLogin(ctx, req.Secrets)
+	_, token, err := zd.lookUpToken(ctx, applianceFromId(volumeID), req.Secrets)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+		return nil, err
 	}
-	token := zfssarest.LookUpToken(ctx, user, password)
 
 	zvol, err := zd.lookupVolume(ctx, token, volumeID)
 	if err != nil {
-		return nil, status.Errorf(codes.NotFound, "Volume (%s) was not found: %v", volumeID)
+		return nil, status.Errorf(codes.NotFound, "Volume (%s) was not found: %v", volumeID, err)
 	}
 	defer zd.releaseVolume(ctx, zvol)
 
@@ -416,13 +446,12 @@ func (zd *ZFSSADriver) GetCapacity(ctx context.Context, req *csi.GetCapacityRequ
 	}
 
 	var availableCapacity int64
-	user, password, err := zd.getUserLogin(ctx, nil)
+	parameters := req.GetParameters()
+	client, token, err := zd.lookUpToken(ctx, parameters["appliance"], nil)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+		return nil, err
 	}
-	token := zfssarest.LookUpToken(ctx, user, password)
 
-	parameters := req.GetParameters()
 	projectName, ok := parameters["project"]
 	if !ok || len(projectName) == 0 {
 		// No project name provided the capacity returned will be the capacity
@@ -431,7 +460,7 @@ func (zd *ZFSSADriver) GetCapacity(ctx context.Context, req *csi.GetCapacityRequ
 		if !ok || len(poolName) == 0 {
 			// No pool name provided. In this case the sum of the space
 			// available in each pool is returned.
-			pools, err := zfssarest.GetPools(ctx, token)
+			pools, err := client.GetPools(ctx, token)
 			if err != nil {
 				return nil, err
 			}
@@ -440,7 +469,7 @@ func (zd *ZFSSADriver) GetCapacity(ctx context.Context, req *csi.GetCapacityRequ
 			}
 		} else {
 			// A pool name was provided. The space available in the pool is returned.
-			pool, err := zfssarest.GetPool(ctx, token, poolName)
+			pool, err := client.GetPool(ctx, token, poolName)
 			if err != nil {
 				return nil, err
 			}
@@ -453,7 +482,7 @@ func (zd *ZFSSADriver) GetCapacity(ctx context.Context, req *csi.GetCapacityRequ
 		if !ok || len(poolName) == 0 {
 			return nil, status.Error(codes.InvalidArgument, "a pool name is required")
 		}
-		project, err := zfssarest.GetProject(ctx, token, poolName, projectName)
+		project, err := client.GetProject(ctx, token, poolName, projectName)
 		if err != nil {
 			return nil, err
 		}
@@ -494,11 +523,10 @@ func (zd *ZFSSADriver) CreateSnapshot(ctx context.Context, req *csi.CreateSnapsh
 		return nil, status.Error(codes.InvalidArgument, "Source or snapshot ID missing")
 	}
 
-	user, password, err := zd.getUserLogin(ctx, req.Secrets)
+	_, token, err := zd.lookUpToken(ctx, applianceFromId(sourceId), req.Secrets)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+		return nil, err
 	}
-	token := zfssarest.LookUpToken(ctx, user, password)
 
 	zsnap, err := zd.newSnapshot(ctx, token, snapName, sourceId)
 	if err != nil {
@@ -521,11 +549,10 @@ func (zd *ZFSSADriver) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapsh
 	log2 := utils.GetLogCTRL(ctx, 2)
 
 	// Retrieve Token
-	user, password, err := zd.getUserLogin(ctx, req.Secrets)
+	_, token, err := zd.lookUpToken(ctx, applianceFromId(req.GetSnapshotId()), req.Secrets)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+		return nil, err
 	}
-	token := zfssarest.LookUpToken(ctx, user, password)
 
 	// Get exclusive access to the snapshot.
 	zsnap, err := zd.lookupSnapshot(ctx, token, req.SnapshotId)
@@ -572,18 +599,15 @@ func (zd *ZFSSADriver) ListSnapshots(ctx context.Context, req *csi.ListSnapshots
 		maxIndex = (1 << 31) - 1
 	}
 
-	// Retrieve Token
-	user, password, err := zd.getUserLogin(ctx, req.Secrets)
-	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
-	}
-	token := zfssarest.LookUpToken(ctx, user, password)
-
 	var entries []*csi.ListSnapshotsResponse_Entry
 
 	snapshotId := req.GetSnapshotId()
 	if len(snapshotId) > 0 {
 		// Only this snapshot is requested.
+		_, token, err := zd.lookUpToken(ctx, applianceFromId(snapshotId), req.Secrets)
+		if err != nil {
+			return nil, err
+		}
 		zsnap, err := zd.lookupSnapshot(ctx, token, snapshotId)
 		if err == nil {
 			entry := new(csi.ListSnapshotsResponse_Entry)
@@ -600,6 +624,10 @@ func (zd *ZFSSADriver) ListSnapshots(ctx context.Context, req *csi.ListSnapshots
 		}
 	} else if len(req.GetSourceVolumeId()) > 0 {
 		// Only snapshots of this volume are requested.
+		_, token, err := zd.lookUpToken(ctx, applianceFromId(req.GetSourceVolumeId()), req.Secrets)
+		if err != nil {
+			return nil, err
+		}
 		zvol, err := zd.lookupVolume(ctx, token, req.GetSourceVolumeId())
 		if err == nil {
 			entries, err = zvol.getSnapshotsList(ctx, token)
@@ -653,11 +681,10 @@ func (zd *ZFSSADriver) ControllerExpandVolume(ctx context.Context, req *csi.Cont
 		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
 	}
 
-	user, password, err := zd.getUserLogin(ctx, req.Secrets)
+	_, token, err := zd.lookUpToken(ctx, applianceFromId(volumeID), req.Secrets)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+		return nil, err
 	}
-	token := zfssarest.LookUpToken(ctx, user, password)
 
 	zvol, err := zd.lookupVolume(ctx, token, volumeID)
 	if err != nil {
@@ -684,11 +711,10 @@ func (zd *ZFSSADriver) ControllerGetVolume(ctx context.Context, req *csi.Control
 	}
 
 	// req does not contain a secret map
-	user, password, err := zd.getUserLogin(ctx, nil)
+	_, token, err := zd.lookUpToken(ctx, applianceFromId(volumeID), nil)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+		return nil, err
 	}
-	token := zfssarest.LookUpToken(ctx, user, password)
 
 	zvol, err := zd.lookupVolume(ctx, token, volumeID)
 	if err != nil {
@@ -707,31 +733,3 @@ func (zd *ZFSSADriver) ControllerGetVolume(ctx context.Context, req *csi.Control
 		//	are not implemented
 	}, nil
 }
-
-// Check the secrets map (typically in a request context) for a change in the username
-// and password or retrieve the username/password from the credentials file, the username
-// and password should be scrubbed quickly after use and not remain in memory
-func (zd *ZFSSADriver) getUserLogin(ctx context.Context, secrets map[string]string) (string, string, error) {
-	if secrets != nil {
-		user, ok := secrets["username"]
-		if ok {
-			password := secrets["password"]
-			return user, password, nil
-		}
-	}
-
-	username, err := zd.GetUsernameFromCred()
-	if err != nil {
-		utils.GetLogCTRL(ctx, 2).Println("ZFSSA username error:", err)
-		username = "INVALID_USERNAME"
-		return "", "", err
-	}
-
-	password, err := zd.GetPasswordFromCred()
-	if err != nil {
-		utils.GetLogCTRL(ctx, 2).Println("ZFSSA password error:", err)
-		return "", "", err
-	}
-
-	return username, password, nil
-}
@@ -7,14 +7,13 @@ package service
 
 import (
 	"context"
-	"fmt"
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/oracle/zfssa-csi-driver/pkg/utils"
 	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest"
 	context2 "golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
-	"net/http"
+	"strings"
 	"sync/atomic"
 )
 
@@ -25,25 +24,37 @@ type zLUN struct {
 	state          volumeState
 	href           string
 	id             *utils.VolumeId
+	client         *zfssarest.Client
 	capacity       int64
 	accessModes    []csi.VolumeCapability_AccessMode
 	source         *csi.VolumeContentSource
 	initiatorgroup []string
 	targetgroup    string ``
+	owner          zfssarest.Owner
+	origin         zfssarest.Origin
 }
 
+// Protocols the LUNs are exported with, selected by the parameter "protocol".
+const (
+	protocolISCSI = "iscsi"
+	protocolFC    = "fc"
+)
+
 var (
 	// access modes supported by block volumes.
 	blockVolumeCaps = []csi.VolumeCapability_AccessMode{
 		{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+		{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY},
+		{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
 	}
 )
 
 // Creates a new LUN structure. If no information is provided (luninfo is nil), this
 // method cannot fail. If information is provided, it will fail if it cannot create
 // a volume ID.
-func newLUN(vid *utils.VolumeId) *zLUN {
+func newLUN(client *zfssarest.Client, vid *utils.VolumeId) *zLUN {
 	lun := new(zLUN)
+	lun.client = client
 	lun.id = vid
 	lun.bolt = utils.NewBolt()
 	lun.state = stateCreating
@@ -58,10 +69,10 @@ func (lun *zLUN) create(ctx context.Context, token *zfssarest.Token,
 	capacityRange := req.GetCapacityRange()
 	capabilities := req.GetVolumeCapabilities()
 
-	_, luninfo, httpStatus, err := zfssarest.CreateLUN(ctx, token,
-		req.GetName(), getVolumeSize(capacityRange), &req.Parameters)
+	_, luninfo, _, err := lun.client.CreateLUN(ctx, token,
+		req.GetName(), getVolumeSize(capacityRange), &req.Parameters, shareOwner(ctx, lun.client, token, req))
 	if err != nil {
-		if httpStatus != http.StatusConflict {
+		if status.Code(err) != codes.AlreadyExists {
 			lun.state = stateDeleted
 			return nil, err
 		}
@@ -71,7 +82,7 @@ func (lun *zLUN) create(ctx context.Context, token *zfssarest.Token,
 		// with the same name. We get the information from the appliance,
 		// update the LUN context and check its compatibility with the request.
 		if lun.state == stateCreated {
-			luninfo, _, err := zfssarest.GetLun(ctx, token,
+			luninfo, _, err := lun.client.GetLun(ctx, token,
 				req.Parameters["pool"], req.Parameters["project"], req.GetName())
 			if err != nil {
 				return nil, err
@@ -115,12 +126,41 @@ func (lun *zLUN) cloneSnapshot(ctx context.Context, token *zfssarest.Token,
 
 	utils.GetLogCTRL(ctx, 5).Println("lun.cloneSnapshot")
 
-	parameters := make(map[string]interface{})
+	return lun.clone(ctx, token, req, zsnap.getHref(), 0)
+}
+
+// Creates the LUN from the snapshot whose HREF is passed in. The size of the snapshot's
+// LUN is kept if the size passed in is 0.
+func (lun *zLUN) clone(ctx context.Context, token *zfssarest.Token,
+	req *csi.CreateVolumeRequest, snapHref string, size int64) (*csi.CreateVolumeResponse, error) {
+
+	parameters, err := zfssarest.LunProperties(req.Parameters)
+	if err != nil {
+		return nil, err
+	}
 	parameters["project"] = req.Parameters["project"]
 	parameters["share"] = req.GetName()
 	parameters["initiatorgroup"] = []string{zfssarest.MaskAll}
+	if size > 0 {
+		parameters["volsize"] = size
+	}
+
+	for name, value := range shareOwner(ctx, lun.client, token, req).Properties() {
+		parameters[name] = value
+	}
 
-	luninfo, _, err := zfssarest.CloneLunSnapshot(ctx, token, zsnap.getHref(), parameters)
+	luninfo, _, err := lun.client.CloneLunSnapshot(ctx, token, snapHref, parameters)
+	if status.Code(err) == codes.AlreadyExists {
+		// The LUN is only accepted if it was cloned from the same snapshot (retried
+		// request).
+		luninfo, _, err = lun.client.GetLun(ctx, token,
+			req.Parameters["pool"], req.Parameters["project"], req.GetName())
+		if err == nil && luninfo.Origin.SnapshotHref("luns") != snapHref {
+			return nil, status.Errorf(codes.AlreadyExists,
+				"Volume (%s) is already on target (%s), not cloned from the source requested",
+				lun.id.Name, lun.id.Zfssa)
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -140,27 +180,50 @@ func (lun *zLUN) delete(ctx context.Context, token *zfssarest.Token) (*csi.Delet
 
 	utils.GetLogCTRL(ctx, 5).Println("lun.delete")
 
+	if err := checkOwner(lun.id, &lun.owner); err != nil {
+		return nil, err
+	}
+
 	if lun.state == stateCreated {
-		_, httpStatus, err := zfssarest.DeleteLun(ctx, token, lun.id.Pool, lun.id.Project, lun.id.Name)
-		if err != nil && httpStatus != http.StatusNotFound {
+		_, _, err := lun.client.DeleteLun(ctx, token, lun.id.Pool, lun.id.Project, lun.id.Name)
+		if err != nil && status.Code(err) != codes.NotFound {
 			return nil, err
 		}
 
 		lun.state = stateDeleted
+
+		if lun.origin.IsClone() {
+			releaseCloneSnapshot(ctx, lun.client, token, lun.origin.SnapshotHref("luns"))
+		}
 	}
 
 	return &csi.DeleteVolumeResponse{}, nil
 }
 
+// Creates the LUN as a clone of the source LUN passed in.
 func (lun *zLUN) cloneVolume(ctx context.Context, token *zfssarest.Token,
-	req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
+	req *csi.CreateVolumeRequest, zsrc zVolumeInterface) (*csi.CreateVolumeResponse, error) {
+
+	utils.GetLogCTRL(ctx, 5).Println("lun.cloneVolume")
+
+	size, err := cloneSize(req.GetCapacityRange(), zsrc)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create a snapshot to base the clone on
+	snapinfo, err := createCloneSnapshot(ctx, token, zsrc, req.GetName())
+	if err != nil {
+		return nil, err
+	}
 
 	// Clone the snapshot to the volume
-
-	utils.GetLogCTRL(ctx, 5).Println("lun.cloneVolume")
-	return nil, status.Error(codes.Unimplemented, "LUN cloneVolume not implemented yet")
+	rsp, err := lun.clone(ctx, token, req, snapinfo.Href, size)
+	if err != nil {
+		releaseCloneSnapshot(ctx, lun.client, token, snapinfo.Href)
+		return nil, err
+	}
+	return rsp, nil
 }
 
 func (lun *zLUN) controllerPublishVolume(ctx context.Context, token *zfssarest.Token,
@@ -168,36 +231,55 @@ func (lun *zLUN) controllerPublishVolume(ctx context.Context, token *zfssarest.T
 
 	utils.GetLogCTRL(ctx, 5).Println("lun.controllerPublishVolume")
 
+	// A file system on a LUN would be corrupted if several nodes mounted it: only the raw
+	// block device can be shared.
+	if isMultiNode(req.GetVolumeCapability()) && req.GetVolumeCapability().GetBlock() == nil {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"Volume (%s) can only be published to several nodes as a block device", lun.id)
+	}
+
 	pool := lun.id.Pool
 	project := lun.id.Project
 	name := lun.id.Name
 
-	list, err := zfssarest.GetInitiatorGroupList(ctx, token, pool, project, name)
+	list, err := lun.client.GetInitiatorGroupList(ctx, token, pool, project, name)
 	if err != nil {
 		// Log something
 		return nil, err
 	}
+	if len(list) == 0 {
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"Volume (%s) did not return an initiator group list", lun.id)
+	}
 
 	// When the driver creates a LUN or clones a Lun from a snapshot of another Lun,
-	// it masks the initiator group of the Lun using zfssarest.MaskAll value.
-	// When the driver unpublishes the Lun, it also masks the initiator group.
-	// This block is to test if the Lun to publish was created or unpublished
-	// by the driver. Publishing a Lun with unmasked initiator group fails
-	// to avoid mistakenly publishing a Lun that may be in use by other entity.
-	utils.GetLogCTRL(ctx, 5).Printf("Volume to publish: %s:%s", lun.id, list[0])
-	if len(list) != 1 || list[0] != zfssarest.MaskAll {
-		var msg string
-		if len(list) > 0 {
-			msg = fmt.Sprintf("Volume (%s:%s) may already be published", lun.id, list[0])
-		} else {
-			msg = fmt.Sprintf("Volume (%s) did not return an initiator group list", lun.id)
-		}
-		return nil, status.Error(codes.FailedPrecondition, msg)
+	// it masks the initiator group of the Lun using zfssarest.MaskAll value. The
+	// initiator groups of the nodes the LUN is published to then replace the mask,
+	// and the mask is restored when the LUN is unpublished from the last node.
+	// A LUN that is not masked can only be published to another node if it is used
+	// by several nodes: publishing it fails to avoid mistakenly publishing a LUN that
+	// may be in use by other entity.
+	groups := initiatorGroupsOf(list)
+	utils.GetLogCTRL(ctx, 5).Printf("Volume to publish: %s:%v", lun.id, list)
+	if containsString(groups, nodeName) {
+		return &csi.ControllerPublishVolumeResponse{}, nil
+	}
+	if len(groups) > 0 && !isMultiNode(req.GetVolumeCapability()) {
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"Volume (%s:%s) may already be published", lun.id, groups[0])
 	}
 
-	// Reset the masked initiator group with one named by the current node name.
-	// There must be initiator groups on ZFSSA defined by the node names.
-	_, err = zfssarest.SetInitiatorGroupList(ctx, token, pool, project, name, nodeName)
+	// Add the initiator group named by the current node name. The initiator group is
+	// created if the node published its IQN, otherwise it must be defined on the ZFSSA.
+	protocol, err := blockProtocol(req.GetVolumeContext())
+	if err != nil {
+		return nil, err
+	}
+	err = ensureNodeInitiatorGroup(ctx, lun.client, token, protocol, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	_, err = lun.client.SetInitiatorGroupList(ctx, token, pool, project, name, append(groups, nodeName)...)
 	if err != nil {
 		// Log something
 		return nil, err
@@ -215,10 +297,35 @@ func (lun *zLUN) controllerUnpublishVolume(ctx context.Context, token *zfssarest
 	project := lun.id.Project
 	name := lun.id.Name
 
-	code, err := zfssarest.SetInitiatorGroupList(ctx, token, pool, project, name, zfssarest.MaskAll)
+	list, err := lun.client.GetInitiatorGroupList(ctx, token, pool, project, name)
+	if err != nil {
+		if status.Code(err) != codes.NotFound {
+			return nil, err
+		}
+		utils.GetLogCTRL(ctx, 5).Println("Unpublish failed because LUN was deleted, return success")
+		return &csi.ControllerUnpublishVolumeResponse{}, nil
+	}
+
+	// The initiator group of the node is removed from the list, the LUN is masked
+	// again when it is not published to any node anymore.
+	groups := initiatorGroupsOf(list)
+	remaining := make([]string, 0, len(groups))
+	for _, group := range groups {
+		if group != req.GetNodeId() {
+			remaining = append(remaining, group)
+		}
+	}
+	if len(remaining) == len(groups) && len(groups) > 0 {
+		return &csi.ControllerUnpublishVolumeResponse{}, nil
+	}
+	if len(remaining) == 0 {
+		remaining = append(remaining, zfssarest.MaskAll)
+	}
+
+	code, err := lun.client.SetInitiatorGroupList(ctx, token, pool, project, name, remaining...)
 	if err != nil {
 		utils.GetLogCTRL(ctx, 5).Println("Could not unpublish volume {}, code {}", lun, code)
-		if code != 404 {
+		if status.Code(err) != codes.NotFound {
 			return nil, err
 		}
 		utils.GetLogCTRL(ctx, 5).Println("Unpublish failed because LUN was deleted, return success")
@@ -227,6 +334,28 @@ func (lun *zLUN) controllerUnpublishVolume(ctx context.Context, token *zfssarest
 	return &csi.ControllerUnpublishVolumeResponse{}, nil
 }
 
+// Returns the initiator groups of the list passed in, without the mask.
+func initiatorGroupsOf(list []string) []string {
+	groups := make([]string, 0, len(list))
+	for _, group := range list {
+		if group != zfssarest.MaskAll && group != "" {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// Returns true if the capability passed in allows the volume to be used by several nodes.
+func isMultiNode(capability *csi.VolumeCapability) bool {
+	switch capability.GetAccessMode().GetMode() {
+	case csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY,
+		csi.VolumeCapability_AccessMode_MULTI_NODE_SINGLE_WRITER,
+		csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER:
+		return true
+	}
+	return false
+}
+
 func (lun *zLUN) validateVolumeCapabilities(ctx context.Context, token *zfssarest.Token,
 	req *csi.ValidateVolumeCapabilitiesRequest) (*csi.ValidateVolumeCapabilitiesResponse, error) {
 
@@ -246,9 +375,33 @@ func (lun *zLUN) validateVolumeCapabilities(ctx context.Context, token *zfssares
 	}
 }
 
+// Expands the LUN. The node then has to rescan the device (and grow the file system it
+// may hold), a node expansion is always required.
 func (lun *zLUN) controllerExpandVolume(ctx context.Context, token *zfssarest.Token,
 	req *csi.ControllerExpandVolumeRequest) (*csi.ControllerExpandVolumeResponse, error) {
-	return nil, status.Error(codes.OutOfRange, "Not allowed for block devices")
+
+	utils.GetLogCTRL(ctx, 5).Println("lun.controllerExpandVolume")
+
+	reqCapacity := req.GetCapacityRange().RequiredBytes
+	if lun.capacity >= reqCapacity {
+		return &csi.ControllerExpandVolumeResponse{
+			CapacityBytes:         lun.capacity,
+			NodeExpansionRequired: true,
+		}, nil
+	}
+
+	parameters := make(map[string]interface{})
+	parameters["volsize"] = reqCapacity
+	luninfo, _, err := lun.client.ModifyLun(ctx, token, lun.href, &parameters)
+	if err != nil {
+		return nil, err
+	}
+	lun.capacity = int64(luninfo.VolumeSize)
+
+	return &csi.ControllerExpandVolumeResponse{
+		CapacityBytes:         lun.capacity,
+		NodeExpansionRequired: true,
+	}, nil
 }
 
 func (lun *zLUN) nodeStageVolume(ctx context.Context, token *zfssarest.Token,
@@ -276,19 +429,19 @@ func (lun *zLUN) nodeGetVolumeStats(ctx context.Context, token *zfssarest.Token,
 	return nil, nil
 }
 
-func (lun *zLUN) getDetails(ctx context2.Context, token *zfssarest.Token) (int, error) {
-	lunInfo, httpStatus, err := zfssarest.GetLun(ctx, token, lun.id.Pool, lun.id.Project, lun.id.Name)
+func (lun *zLUN) getDetails(ctx context2.Context, token *zfssarest.Token) error {
+	lunInfo, _, err := lun.client.GetLun(ctx, token, lun.id.Pool, lun.id.Project, lun.id.Name)
 	if err != nil {
-		return httpStatus, err
+		return err
 	}
 	lun.setInfo(lunInfo)
-	return httpStatus, nil
+	return nil
 }
 
 func (lun *zLUN) getSnapshotsList(ctx context.Context, token *zfssarest.Token) (
 	[]*csi.ListSnapshotsResponse_Entry, error) {
 
-	snapList, err := zfssarest.GetSnapshots(ctx, token, lun.href)
+	snapList, err := lun.client.GetSnapshots(ctx, token, lun.href)
 	if err != nil {
 		return nil, err
 	}
@@ -301,10 +454,11 @@ func (lun *zLUN) getName() string              { return lun.id.Name }
 func (lun *zLUN) getHref() string              { return lun.href }
 func (lun *zLUN) getVolumeID() *utils.VolumeId { return lun.id }
 func (lun *zLUN) getCapacity() int64           { return lun.capacity }
+func (lun *zLUN) getClient() *zfssarest.Client { return lun.client }
 func (lun *zLUN) isBlock() bool                { return true }
 
 func (lun *zLUN) getSnapshots(ctx context.Context, token *zfssarest.Token) ([]zfssarest.Snapshot, error) {
-	return zfssarest.GetSnapshots(ctx, token, lun.href)
+	return lun.client.GetSnapshots(ctx, token, lun.href)
 }
 
 func (lun *zLUN) setInfo(volInfo interface{}) {
@@ -314,6 +468,9 @@ func (lun *zLUN) setInfo(volInfo interface{}) {
 		lun.href = luninfo.Href
 		lun.initiatorgroup = luninfo.InitiatorGroup
 		lun.targetgroup = luninfo.TargetGroup
+		lun.owner = luninfo.Owner
+		lun.origin = luninfo.Origin
+		lun.accessModes = blockVolumeCaps
 		lun.state = stateCreated
 	default:
 		panic("lun.setInfo called with wrong type")
@@ -347,7 +504,8 @@ func (lun *zLUN) unlock(ctx context.Context) (int32, volumeState) {
 }
 
 // Validates the block specific parameters of the create request.
-func validateCreateBlockVolumeReq(ctx context.Context, token *zfssarest.Token, req *csi.CreateVolumeRequest) error {
+func validateCreateBlockVolumeReq(ctx context.Context, client *zfssarest.Client, token *zfssarest.Token,
+	req *csi.CreateVolumeRequest) error {
 
 	reqCaps := req.GetVolumeCapabilities()
 	if !areBlockVolumeCapsValid(reqCaps) {
@@ -360,7 +518,16 @@ func validateCreateBlockVolumeReq(ctx context.Context, token *zfssarest.Token, r
 		return status.Error(codes.InvalidArgument, "a valid ZFSSA target group is required ")
 	}
 
-	_, err := zfssarest.GetTargetGroup(ctx, token, "iscsi", tg)
+	protocol, err := blockProtocol(parameters)
+	if err != nil {
+		return err
+	}
+
+	if _, err := zfssarest.LunProperties(parameters); err != nil {
+		return err
+	}
+
+	_, err = client.GetTargetGroup(ctx, token, protocol, tg)
 	if err != nil {
 		return err
 	}
@@ -368,10 +535,27 @@ func validateCreateBlockVolumeReq(ctx context.Context, token *zfssarest.Token, r
 	return nil
 }
 
-// Checks whether the capability list is all supported.
+// Returns the protocol the LUN is exported with, iSCSI if the parameters passed in
+// don't specify it.
+func blockProtocol(parameters map[string]string) (string, error) {
+	switch protocol := strings.ToLower(parameters["protocol"]); protocol {
+	case "", protocolISCSI:
+		return protocolISCSI, nil
+	case protocolFC:
+		return protocolFC, nil
+	default:
+		return "", status.Errorf(codes.InvalidArgument, "unsupported protocol (%s)", parameters["protocol"])
+	}
+}
+
+// Checks whether the capability list is all supported. The access modes allowing several
+// nodes to use the LUN are only supported with the block access type.
 func areBlockVolumeCapsValid(volCaps []*csi.VolumeCapability) bool {
 
 	hasSupport := func(cap *csi.VolumeCapability) bool {
+		if isMultiNode(cap) && cap.GetBlock() == nil {
+			return false
+		}
 		for _, c := range blockVolumeCaps {
 			if c.GetMode() == cap.AccessMode.GetMode() {
 				return true
@@ -14,7 +14,6 @@ import (
 	context2 "golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
-	"net/http"
 	"sync/atomic"
 )
 
@@ -35,16 +34,20 @@ type zFilesystem struct {
 	state       volumeState
 	href        string
 	id          *utils.VolumeId
+	client      *zfssarest.Client
 	capacity    int64
 	accessModes []csi.VolumeCapability_AccessMode
 	source      *csi.VolumeContentSource
 	mountpoint  string
+	owner       zfssarest.Owner
+	origin      zfssarest.Origin
 }
 
 // Creates a new filesysyem structure. If no information is provided (fsinfo is nil), this
 // method cannot fail. If information is provided, it will fail if it cannot create a volume ID
-func newFilesystem(vid *utils.VolumeId) *zFilesystem {
+func newFilesystem(client *zfssarest.Client, vid *utils.VolumeId) *zFilesystem {
 	fs := new(zFilesystem)
+	fs.client = client
 	fs.id = vid
 	fs.bolt = utils.NewBolt()
 	fs.state = stateCreating
@@ -64,15 +67,46 @@ func (fs *zFilesystem) create(ctx context.Context, token *zfssarest.Token,
 		req.Parameters["restrictChown"] = "false"
 	}
 
-	if _, ok := req.Parameters["shareNFS"]; !ok {
-		utils.GetLogCTRL(ctx, 5).Println("Adding shareNFS to CreateFilesystem req parameters")
-		req.Parameters["shareNFS"] = "on"
+	protocol, err := filesystemProtocol(req.Parameters)
+	if err != nil {
+		return nil, err
 	}
 
-	fsinfo, httpStatus, err := zfssarest.CreateFilesystem(ctx, token,
-		req.GetName(), getVolumeSize(capacityRange), &req.Parameters)
+	// The sharenfs and sharesmb properties may be built from other parameters, they are
+	// only set in the parameters of the request to the appliance.
+	parameters := make(map[string]string, len(req.Parameters)+2)
+	for key, value := range req.Parameters {
+		parameters[key] = value
+	}
+
+	if protocol == protocolSMB {
+		req.Parameters[smbShareKey] = req.GetName()
+		parameters["shareNFS"] = "off"
+		parameters["shareSMB"] = "name=" + req.GetName()
+	} else {
+		exportMode, shareNFS, err := initialShareNFS(req.Parameters)
+		if err != nil {
+			return nil, err
+		}
+		delete(parameters, nfsExportKey)
+		if exportMode == nfsExportNode {
+			if err := ensureNFSExportSchema(ctx, fs.client, token); err != nil {
+				return nil, err
+			}
+			req.Parameters[nfsExportKey] = nfsExportNode
+			parameters[nfsExportKey] = nfsExportNode
+		} else if shareNFS == "" {
+			utils.GetLogCTRL(ctx, 5).Println("Adding shareNFS to CreateFilesystem req parameters")
+			req.Parameters["shareNFS"] = "on"
+			shareNFS = "on"
+		}
+		parameters["shareNFS"] = shareNFS
+	}
+
+	fsinfo, _, err := fs.client.CreateFilesystem(ctx, token,
+		req.GetName(), getVolumeSize(capacityRange), &parameters, shareOwner(ctx, fs.client, token, req))
 	if err != nil {
-		if httpStatus != http.StatusConflict {
+		if status.Code(err) != codes.AlreadyExists {
 			fs.state = stateDeleted
 			return nil, err
 		}
@@ -82,7 +116,7 @@ func (fs *zFilesystem) create(ctx context.Context, token *zfssarest.Token,
 		// with the same name. We get the information from the appliance, update
 		// the file system context and check its compatibility with the request.
 		if fs.state == stateCreated {
-			fsinfo, _, err = zfssarest.GetFilesystem(ctx, token,
+			fsinfo, _, err = fs.client.GetFilesystem(ctx, token,
 				req.Parameters["pool"], req.Parameters["project"], req.GetName())
 			if err != nil {
 				return nil, err
@@ -126,11 +160,68 @@ func (fs *zFilesystem) cloneSnapshot(ctx context.Context, token *zfssarest.Token
 
 	utils.GetLogCTRL(ctx, 5).Println("fs.cloneSnapshot")
 
-	parameters := make(map[string]interface{})
+	return fs.clone(ctx, token, req, zsnap.getHref(), 0)
+}
+
+// Creates the file system from the snapshot whose HREF is passed in. The quota of the
+// snapshot's file system is kept if the size passed in is 0.
+func (fs *zFilesystem) clone(ctx context.Context, token *zfssarest.Token,
+	req *csi.CreateVolumeRequest, snapHref string, size int64) (*csi.CreateVolumeResponse, error) {
+
+	parameters, err := zfssarest.FilesystemProperties(req.Parameters)
+	if err != nil {
+		return nil, err
+	}
 	parameters["project"] = req.Parameters["project"]
 	parameters["share"] = req.GetName()
+	if size > 0 {
+		parameters["quota"] = size
+		parameters["reservation"] = size
+	}
+
+	protocol, err := filesystemProtocol(req.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	if protocol == protocolSMB {
+		req.Parameters[smbShareKey] = req.GetName()
+		parameters["sharenfs"] = "off"
+		parameters["sharesmb"] = "name=" + req.GetName()
+	} else {
+		exportMode, shareNFS, err := initialShareNFS(req.Parameters)
+		if err != nil {
+			return nil, err
+		}
+		if exportMode == nfsExportNode {
+			if err := ensureNFSExportSchema(ctx, fs.client, token); err != nil {
+				return nil, err
+			}
+			req.Parameters[nfsExportKey] = nfsExportNode
+			parameters["custom:"+zfssarest.NfsExport] = nfsExportNode
+			// The clone is exported to none of the nodes of its source.
+			parameters["custom:"+zfssarest.NfsNodes] = ""
+		}
+		if shareNFS != "" {
+			parameters["sharenfs"] = shareNFS
+		}
+	}
 
-	fsinfo, _, err := zfssarest.CloneFileSystemSnapshot(ctx, token, zsnap.getHref(), parameters)
+	for name, value := range shareOwner(ctx, fs.client, token, req).Properties() {
+		parameters[name] = value
+	}
+
+	fsinfo, _, err := fs.client.CloneFileSystemSnapshot(ctx, token, snapHref, parameters)
+	if status.Code(err) == codes.AlreadyExists {
+		// The file system is only accepted if it was cloned from the same snapshot
+		// (retried request).
+		fsinfo, _, err = fs.client.GetFilesystem(ctx, token,
+			req.Parameters["pool"], req.Parameters["project"], req.GetName())
+		if err == nil && fsinfo.Origin.SnapshotHref("filesystems") != snapHref {
+			return nil, status.Errorf(codes.AlreadyExists,
+				"Volume (%s) is already on target (%s), not cloned from the source requested",
+				fs.id.Name, fs.id.Zfssa)
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -152,52 +243,98 @@ func (fs *zFilesystem) delete(ctx context.Context, token *zfssarest.Token) (*csi
 
 	utils.GetLogCTRL(ctx, 5).Println("fs.delete")
 
-	// Check first if the filesystem has snapshots.
-	snaplist, err := zfssarest.GetSnapshots(ctx, token, fs.href)
+	if err := checkOwner(fs.id, &fs.owner); err != nil {
+		return nil, err
+	}
+
+	// Check first if the filesystem has snapshots. The snapshots taken to clone the file
+	// system that no clone depends on anymore are deleted with it.
+	snaplist, err := fs.client.GetSnapshots(ctx, token, fs.href)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(snaplist) > 0 {
-		return nil, status.Errorf(codes.FailedPrecondition, "filesysytem (%s) has snapshots", fs.id.String())
+	for _, snapinfo := range snaplist {
+		if !isCloneSnapshot(snapinfo.Name) || snapinfo.NumClones > 0 {
+			return nil, status.Errorf(codes.FailedPrecondition, "filesysytem (%s) has snapshots", fs.id.String())
+		}
 	}
 
-	_, httpStatus, err := zfssarest.DeleteFilesystem(ctx, token, fs.href)
-	if err != nil && httpStatus != http.StatusNotFound {
+	_, _, err = fs.client.DeleteFilesystem(ctx, token, fs.href)
+	if err != nil && status.Code(err) != codes.NotFound {
 		return nil, err
 	}
 
 	fs.state = stateDeleted
+
+	if fs.origin.IsClone() {
+		releaseCloneSnapshot(ctx, fs.client, token, fs.origin.SnapshotHref("filesystems"))
+	}
 	return &csi.DeleteVolumeResponse{}, nil
 }
 
-func (lun *zFilesystem) cloneVolume(ctx context.Context, token *zfssarest.Token,
-	req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
-	utils.GetLogCTRL(ctx, 5).Println("cloneVolume", "request", protosanitizer.StripSecrets(req))
+// Creates the file system as a clone of the source file system passed in.
+func (fs *zFilesystem) cloneVolume(ctx context.Context, token *zfssarest.Token,
+	req *csi.CreateVolumeRequest, zsrc zVolumeInterface) (*csi.CreateVolumeResponse, error) {
+
+	utils.GetLogCTRL(ctx, 5).Println("fs.cloneVolume", "request", protosanitizer.StripSecrets(req))
+
+	size, err := cloneSize(req.GetCapacityRange(), zsrc)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create a snapshot to base the clone on
+	snapinfo, err := createCloneSnapshot(ctx, token, zsrc, req.GetName())
+	if err != nil {
+		return nil, err
+	}
 
 	// Clone the snapshot to the volume
-
-	utils.GetLogCTRL(ctx, 5).Println("fs.cloneVolume")
-	return nil, status.Error(codes.Unimplemented, "Filesystem cloneVolume not implemented yet")
+	rsp, err := fs.clone(ctx, token, req, snapinfo.Href, size)
+	if err != nil {
+		releaseCloneSnapshot(ctx, fs.client, token, snapinfo.Href)
+		return nil, err
+	}
+	return rsp, nil
 }
 
-// Publishes a file system. In this case there's nothing to do.
+// Publishes a file system. The node is granted access to the file system if its export is
+// maintained per node, there's nothing to do otherwise.
 func (fs *zFilesystem) controllerPublishVolume(ctx context.Context, token *zfssarest.Token,
 	req *csi.ControllerPublishVolumeRequest, nodeName string) (*csi.ControllerPublishVolumeResponse, error) {
 
 	// Note: the volume context of the volume provisioned from an existing share does not have the mountpoint.
 	// Use the share (corresponding to volumeAttributes.share of PV configuration) to define the mountpoint.
 
+	utils.GetLogCTRL(ctx, 5).Println("fs.controllerPublishVolume")
+
+	// The file system is exported to the node if its export is maintained per node.
+	if req.GetVolumeContext()[nfsExportKey] == nfsExportNode {
+		policy, err := parseNFSPolicy(req.GetVolumeContext())
+		if err != nil {
+			return nil, err
+		}
+		err = fs.exportToNode(ctx, token, nodeName, isReadOnlyPublish(req), policy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &csi.ControllerPublishVolumeResponse{}, nil
 }
 
-// Unpublishes a file system. In this case there's nothing to do.
+// Unpublishes a file system. The node loses its access if the file system is exported
+// per node.
 func (fs *zFilesystem) controllerUnpublishVolume(ctx context.Context, token *zfssarest.Token,
 	req *csi.ControllerUnpublishVolumeRequest) (*csi.ControllerUnpublishVolumeResponse, error) {
 	utils.GetLogCTRL(ctx, 5).Println("fs.controllerUnpublishVolume")
 
+	err := fs.unexportFromNode(ctx, token, req.GetNodeId())
+	if err != nil && status.Code(err) != codes.NotFound {
+		return nil, err
+	}
+
 	return &csi.ControllerUnpublishVolumeResponse{}, nil
 }
 
@@ -234,7 +371,7 @@ func (fs *zFilesystem) controllerExpandVolume(ctx context.Context, token *zfssar
 	parameters := make(map[string]interface{})
 	parameters["quota"] = reqCapacity
 	parameters["reservation"] = reqCapacity
-	fsinfo, _, err := zfssarest.ModifyFilesystem(ctx, token, fs.href, &parameters)
+	fsinfo, _, err := fs.client.ModifyFilesystem(ctx, token, fs.href, &parameters)
 	if err != nil {
 		return nil, err
 	}
@@ -271,19 +408,19 @@ func (fs *zFilesystem) nodeGetVolumeStats(ctx context.Context, token *zfssarest.
 	return nil, nil
 }
 
-func (fs *zFilesystem) getDetails(ctx context2.Context, token *zfssarest.Token) (int, error) {
-	fsinfo, httpStatus, err := zfssarest.GetFilesystem(ctx, token, fs.id.Pool, fs.id.Project, fs.id.Name)
+func (fs *zFilesystem) getDetails(ctx context2.Context, token *zfssarest.Token) error {
+	fsinfo, _, err := fs.client.GetFilesystem(ctx, token, fs.id.Pool, fs.id.Project, fs.id.Name)
 	if err != nil {
-		return httpStatus, err
+		return err
 	}
 	fs.setInfo(fsinfo)
-	return httpStatus, nil
+	return nil
 }
 
 func (fs *zFilesystem) getSnapshotsList(ctx context.Context, token *zfssarest.Token) (
 	[]*csi.ListSnapshotsResponse_Entry, error) {
 
-	snapList, err := zfssarest.GetSnapshots(ctx, token, fs.href)
+	snapList, err := fs.client.GetSnapshots(ctx, token, fs.href)
 	if err != nil {
 		return nil, err
 	}
@@ -295,6 +432,7 @@ func (fs *zFilesystem) getName() string              { return fs.id.Name }
 func (fs *zFilesystem) getHref() string              { return fs.href }
 func (fs *zFilesystem) getVolumeID() *utils.VolumeId { return fs.id }
 func (fs *zFilesystem) getCapacity() int64           { return fs.capacity }
+func (fs *zFilesystem) getClient() *zfssarest.Client { return fs.client }
 func (fs *zFilesystem) isBlock() bool                { return false }
 
 func (fs *zFilesystem) setInfo(volInfo interface{}) {
@@ -304,6 +442,8 @@ func (fs *zFilesystem) setInfo(volInfo interface{}) {
 		fs.capacity = fsinfo.Quota
 		fs.mountpoint = fsinfo.MountPoint
 		fs.href = fsinfo.Href
+		fs.owner = fsinfo.Owner
+		fs.origin = fsinfo.Origin
 		if fsinfo.ReadOnly {
 			fs.accessModes = filesystemAccessModes[2:len(filesystemAccessModes)]
 		} else {
@@ -348,6 +488,20 @@ func validateCreateFilesystemVolumeReq(ctx context.Context, req *csi.CreateVolum
 	if !areFilesystemVolumeCapsValid(reqCaps) {
 		return status.Error(codes.InvalidArgument, "invalid volume accessModes")
 	}
+
+	if _, err := zfssarest.FilesystemProperties(req.GetParameters()); err != nil {
+		return err
+	}
+
+	protocol, err := filesystemProtocol(req.GetParameters())
+	if err != nil {
+		return err
+	}
+	if protocol == protocolNFS {
+		if _, _, err := initialShareNFS(req.GetParameters()); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
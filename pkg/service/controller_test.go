@@ -0,0 +1,366 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/oracle/zfssa-csi-driver/pkg/utils"
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest"
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest/zfssatest"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	testUser     = "csi-user"
+	testPassword = "csi-password"
+	testPool     = "p0"
+	testProject  = "k8s"
+)
+
+func TestMain(m *testing.M) {
+	utils.InitLogs("0", "zfssa-csi-driver", "test", "test-node")
+	os.Exit(m.Run())
+}
+
+// Returns a driver talking to a fake appliance with a pool and a project.
+func newTestDriver(t *testing.T) (*ZFSSADriver, *zfssatest.Server) {
+	t.Helper()
+	zd := new(ZFSSADriver)
+	zd.name = "zfssa-csi-driver"
+	zd.version = "test"
+	zd.config.User = testUser
+	zd.config.NodeName = "test-node"
+	zd.config.Secure = true
+	zd.config.RetryPolicy = zfssarest.DefaultRetryPolicy
+	zd.vCache.vHash = make(map[string]zVolumeInterface)
+	zd.sCache.sHash = make(map[string]*zSnapshot)
+
+	zfssa := addTestAppliance(t, zd)
+	zd.config.Appliance = zfssa.Name()
+	zd.config.CertLocation = zfssa.CertFile()
+	zd.config.CredLocation = zd.appliances[zfssa.Name()].credLocation
+	return zd, zfssa
+}
+
+// Starts a fake appliance with a pool and a project and adds it to the driver.
+func addTestAppliance(t *testing.T, zd *ZFSSADriver) *zfssatest.Server {
+	t.Helper()
+	zfssa := zfssatest.NewServer(testUser, testPassword)
+	t.Cleanup(zfssa.Close)
+	zfssa.AddPool(testPool, 100*Gib)
+	zfssa.AddProject(testPool, testProject)
+	zfssa.AddTargetGroup("iscsi", "tg0", "iqn.1986-03.com.sun:02:test")
+
+	credfile := filepath.Join(t.TempDir(), "zfssa.yaml")
+	creds := "username: " + testUser + "\npassword: " + testPassword + "\n"
+	if err := os.WriteFile(credfile, []byte(creds), 0600); err != nil {
+		t.Fatalf("cannot write the credentials file: %v", err)
+	}
+
+	if err := zd.addAppliance(&applianceConfig{
+		Target:      zfssa.Name(),
+		Credentials: credfile,
+		Certificate: zfssa.CertFile(),
+	}); err != nil {
+		t.Fatalf("addAppliance failed: %v", err)
+	}
+	return zfssa
+}
+
+func testContext() context.Context {
+	return utils.GetNewContext(context.Background())
+}
+
+func mountCapabilities() []*csi.VolumeCapability {
+	return []*csi.VolumeCapability{{
+		AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+		AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+	}}
+}
+
+func blockCapabilities() []*csi.VolumeCapability {
+	return []*csi.VolumeCapability{{
+		AccessType: &csi.VolumeCapability_Block{Block: &csi.VolumeCapability_BlockVolume{}},
+		AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+	}}
+}
+
+func filesystemParameters() map[string]string {
+	return map[string]string{"pool": testPool, "project": testProject}
+}
+
+func TestFilesystemSnapshotCloneFlow(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+	ctx := testContext()
+
+	vol, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-1",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+		VolumeCapabilities: mountCapabilities(),
+		Parameters:         filesystemParameters(),
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume failed: %v", err)
+	}
+	volumeId := vol.GetVolume().GetVolumeId()
+	if vol.GetVolume().GetCapacityBytes() != Gib {
+		t.Errorf("unexpected capacity %d", vol.GetVolume().GetCapacityBytes())
+	}
+
+	// Creating the same volume again is idempotent.
+	again, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-1",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+		VolumeCapabilities: mountCapabilities(),
+		Parameters:         filesystemParameters(),
+	})
+	if err != nil || again.GetVolume().GetVolumeId() != volumeId {
+		t.Fatalf("CreateVolume is not idempotent: %v, %v", again, err)
+	}
+
+	snap, err := zd.CreateSnapshot(ctx, &csi.CreateSnapshotRequest{
+		SourceVolumeId: volumeId,
+		Name:           "snapshot-1",
+	})
+	if err != nil {
+		t.Fatalf("CreateSnapshot failed: %v", err)
+	}
+	snapshotId := snap.GetSnapshot().GetSnapshotId()
+	if !snap.GetSnapshot().GetReadyToUse() || snap.GetSnapshot().GetSourceVolumeId() != volumeId {
+		t.Errorf("unexpected snapshot %v", snap.GetSnapshot())
+	}
+
+	clone, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-2",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+		VolumeCapabilities: mountCapabilities(),
+		Parameters:         filesystemParameters(),
+		VolumeContentSource: &csi.VolumeContentSource{
+			Type: &csi.VolumeContentSource_Snapshot{
+				Snapshot: &csi.VolumeContentSource_SnapshotSource{SnapshotId: snapshotId},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume from a snapshot failed: %v", err)
+	}
+	cloneId := clone.GetVolume().GetVolumeId()
+
+	list, err := zd.ListSnapshots(ctx, &csi.ListSnapshotsRequest{SourceVolumeId: volumeId})
+	if err != nil || len(list.GetEntries()) != 1 {
+		t.Fatalf("ListSnapshots returned %v, %v", list, err)
+	}
+
+	for _, id := range []string{cloneId, volumeId} {
+		if _, err = zd.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: id}); err != nil {
+			t.Fatalf("DeleteVolume(%s) failed: %v", id, err)
+		}
+		if id == cloneId {
+			if _, err = zd.DeleteSnapshot(ctx, &csi.DeleteSnapshotRequest{SnapshotId: snapshotId}); err != nil {
+				t.Fatalf("DeleteSnapshot failed: %v", err)
+			}
+		}
+	}
+
+	for _, href := range []string{"/api/storage/v2/pools/p0/projects/k8s/filesystems/pvc-1",
+		"/api/storage/v2/pools/p0/projects/k8s/filesystems/pvc-2"} {
+		if _, found := zfssa.Lookup(href); found {
+			t.Errorf("%s still present on the appliance", href)
+		}
+	}
+
+	// Deleting a volume that no longer exists succeeds.
+	if _, err = zd.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: volumeId}); err != nil {
+		t.Errorf("DeleteVolume of a deleted volume failed: %v", err)
+	}
+}
+
+func TestCreateBlockVolume(t *testing.T) {
+	zd, _ := newTestDriver(t)
+	ctx := testContext()
+
+	parameters := filesystemParameters()
+	parameters["targetGroup"] = "tg0"
+	parameters["blockSize"] = "8192"
+	vol, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-block",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+		VolumeCapabilities: blockCapabilities(),
+		Parameters:         parameters,
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume failed: %v", err)
+	}
+
+	if _, err = zd.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: vol.GetVolume().GetVolumeId()}); err != nil {
+		t.Fatalf("DeleteVolume failed: %v", err)
+	}
+}
+
+func TestCreateVolumeDataProperties(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+	ctx := testContext()
+
+	parameters := filesystemParameters()
+	parameters["compression"] = "gzip"
+	parameters["recordSize"] = "131072"
+	vol, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-props",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+		VolumeCapabilities: mountCapabilities(),
+		Parameters:         parameters,
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume failed: %v", err)
+	}
+	fs, _ := zfssa.Lookup("/api/storage/v2/pools/p0/projects/k8s/filesystems/pvc-props")
+	if fs["compression"] != "gzip" || fmt.Sprint(fs["recordsize"]) != "131072" {
+		t.Errorf("unexpected properties: compression %v, recordsize %v", fs["compression"], fs["recordsize"])
+	}
+
+	// The properties of the StorageClass of the clone apply to the clone.
+	snap, err := zd.CreateSnapshot(ctx, &csi.CreateSnapshotRequest{
+		SourceVolumeId: vol.GetVolume().GetVolumeId(),
+		Name:           "snapshot-props",
+	})
+	if err != nil {
+		t.Fatalf("CreateSnapshot failed: %v", err)
+	}
+	parameters = filesystemParameters()
+	parameters["compression"] = "lzjb"
+	_, err = zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-props-clone",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+		VolumeCapabilities: mountCapabilities(),
+		Parameters:         parameters,
+		VolumeContentSource: &csi.VolumeContentSource{
+			Type: &csi.VolumeContentSource_Snapshot{
+				Snapshot: &csi.VolumeContentSource_SnapshotSource{SnapshotId: snap.GetSnapshot().GetSnapshotId()},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume from a snapshot failed: %v", err)
+	}
+	fs, _ = zfssa.Lookup("/api/storage/v2/pools/p0/projects/k8s/filesystems/pvc-props-clone")
+	if fs["compression"] != "lzjb" {
+		t.Errorf("unexpected compression of the clone: %v", fs["compression"])
+	}
+
+	parameters = filesystemParameters()
+	parameters["targetGroup"] = "tg0"
+	parameters["writeCache"] = "true"
+	parameters["logBias"] = "throughput"
+	_, err = zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-props-block",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+		VolumeCapabilities: blockCapabilities(),
+		Parameters:         parameters,
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume failed: %v", err)
+	}
+	lun, _ := zfssa.Lookup("/api/storage/v2/pools/p0/projects/k8s/luns/pvc-props-block")
+	if lun["writecache"] != true || lun["logbias"] != "throughput" {
+		t.Errorf("unexpected properties: writecache %v, logbias %v", lun["writecache"], lun["logbias"])
+	}
+
+	// A LUN property is rejected for a file system.
+	parameters = filesystemParameters()
+	parameters["writeCache"] = "true"
+	_, err = zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-props-invalid",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+		VolumeCapabilities: mountCapabilities(),
+		Parameters:         parameters,
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CreateVolume with an invalid property: InvalidArgument expected, got %v", err)
+	}
+}
+
+func TestCreateVolumeInvalidPool(t *testing.T) {
+	zd, _ := newTestDriver(t)
+	ctx := testContext()
+
+	parameters := filesystemParameters()
+	parameters["pool"] = "missing"
+	_, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-1",
+		VolumeCapabilities: mountCapabilities(),
+		Parameters:         parameters,
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+}
+
+func TestCreateVolumeFaults(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+	ctx := testContext()
+
+	tests := []struct {
+		fault zfssatest.Fault
+		want  codes.Code
+	}{
+		{zfssatest.Fault{Method: "POST", Path: "/filesystems", Status: http.StatusBadRequest,
+			Message: "invalid recordsize", Times: 1}, codes.InvalidArgument},
+		{zfssatest.Fault{Method: "POST", Path: "/filesystems", Status: http.StatusForbidden,
+			Message: "permission denied", Times: 1}, codes.PermissionDenied},
+		{zfssatest.Fault{Method: "POST", Path: "/filesystems", Status: http.StatusBadRequest,
+			Message: "pool is out of space", Times: 1}, codes.ResourceExhausted},
+	}
+	for _, tt := range tests {
+		zfssa.AddFault(tt.fault)
+		_, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+			Name:               "pvc-1",
+			CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+			VolumeCapabilities: mountCapabilities(),
+			Parameters:         filesystemParameters(),
+		})
+		if status.Code(err) != tt.want {
+			t.Errorf("expected %v, got %v", tt.want, err)
+		} else if !strings.Contains(err.Error(), tt.fault.Message) {
+			t.Errorf("message of the appliance lost: %v", err)
+		}
+	}
+}
+
+func TestCreateVolumeDeadline(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+
+	req := &csi.CreateVolumeRequest{
+		Name:               "pvc-1",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+		VolumeCapabilities: mountCapabilities(),
+		Parameters:         filesystemParameters(),
+	}
+
+	// The appliance hangs, the request fails once its deadline is reached.
+	zfssa.AddFault(zfssatest.Fault{Method: "POST", Path: "/filesystems", Delay: time.Minute, Times: 1})
+	ctx, cancel := context.WithTimeout(testContext(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := zd.CreateVolume(ctx, req); status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+
+	// The volume has been released, a new attempt goes through.
+	ctx, cancel = context.WithTimeout(testContext(), 10*time.Second)
+	defer cancel()
+	if _, err := zd.CreateVolume(ctx, req); err != nil {
+		t.Fatalf("CreateVolume failed after a deadline: %v", err)
+	}
+}
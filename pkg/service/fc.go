@@ -0,0 +1,179 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/oracle/zfssa-csi-driver/pkg/utils"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// With the parameter "protocol" set to "fc" in a StorageClass, the LUNs are exported
+// through the Fibre Channel target group named by "targetGroup" and published to the
+// Fibre Channel initiator group of the node. There is no session to open on the node:
+// the SCSI hosts of the Fibre Channel ports are rescanned and the device of the LUN is
+// looked up in sysfs, by the WWN of the target ports of the appliance and the number
+// assigned to the LUN:
+//
+//	/sys/class/fc_transport/targetH:B:T/port_name	WWN of the target port
+//	/sys/class/scsi_device/H:B:T:L/device/block/sdX	device of the LUN L
+//
+// When the LUN is seen through several paths and multipath is enabled, the multipath
+// device holding the paths is used.
+
+// Root of the sysfs file system.
+var sysfsRoot = "/sys"
+
+// Time given to the SCSI layer to discover a LUN after a rescan.
+var (
+	fcDiscoveryAttempts = 10
+	fcDiscoveryInterval = time.Second
+)
+
+// Returns the WWN passed in ("0x2101001b32a11639", "wwn.2101001B32A11639" or
+// "21:01:00:1b:32:a1:16:39") in the lower case hexadecimal form used by sysfs.
+func normalizeWWN(wwn string) string {
+	wwn = strings.ToLower(strings.TrimSpace(wwn))
+	wwn = strings.TrimPrefix(wwn, "wwn.")
+	wwn = strings.TrimPrefix(wwn, "0x")
+	return strings.ReplaceAll(wwn, ":", "")
+}
+
+// Returns the WWN passed in the form used by the appliance for the Fibre Channel
+// initiators ("wwn.2101001B32A11639").
+func applianceWWN(wwn string) string {
+	return "wwn." + strings.ToUpper(normalizeWWN(wwn))
+}
+
+// Returns the WWNs of the Fibre Channel ports of the node, in the form used by the
+// appliance. An empty list is returned if the node has no Fibre Channel port.
+func readFCPortNames() ([]string, error) {
+	hosts, err := filepath.Glob(filepath.Join(sysfsRoot, "class", "fc_host", "host*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var wwns []string
+	for _, host := range hosts {
+		data, err := os.ReadFile(filepath.Join(host, "port_name"))
+		if err != nil {
+			return nil, err
+		}
+		wwns = append(wwns, applianceWWN(string(data)))
+	}
+	sort.Strings(wwns)
+	return wwns, nil
+}
+
+// Asks the SCSI hosts of the Fibre Channel ports to scan their targets for new LUNs.
+func rescanFCHosts(ctx context.Context) error {
+	hosts, err := filepath.Glob(filepath.Join(sysfsRoot, "class", "fc_host", "host*"))
+	if err != nil {
+		return err
+	}
+	if len(hosts) == 0 {
+		return errors.New("no Fibre Channel port found on the node")
+	}
+
+	for _, host := range hosts {
+		scan := filepath.Join(sysfsRoot, "class", "scsi_host", filepath.Base(host), "scan")
+		if err := os.WriteFile(scan, []byte("- - -"), 0200); err != nil {
+			utils.GetLogNODE(ctx, 2).Println("SCSI host could not be rescanned", "host", host,
+				"error", err.Error())
+		}
+	}
+	return nil
+}
+
+// Returns the devices of the LUN passed in seen through the target ports passed in.
+func findFCDevices(targets []string, lun int32) ([]string, error) {
+	wanted := make(map[string]bool)
+	for _, target := range targets {
+		wanted[normalizeWWN(target)] = true
+	}
+
+	rports, err := filepath.Glob(filepath.Join(sysfsRoot, "class", "fc_transport", "target*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []string
+	for _, rport := range rports {
+		data, err := os.ReadFile(filepath.Join(rport, "port_name"))
+		if err != nil || !wanted[normalizeWWN(string(data))] {
+			continue
+		}
+		hbt := strings.TrimPrefix(filepath.Base(rport), "target")
+		blocks, err := os.ReadDir(filepath.Join(sysfsRoot, "class", "scsi_device",
+			fmt.Sprintf("%s:%d", hbt, lun), "device", "block"))
+		if err != nil {
+			continue
+		}
+		for _, block := range blocks {
+			devices = append(devices, block.Name())
+		}
+	}
+	sort.Strings(devices)
+	return devices, nil
+}
+
+// Returns the multipath device holding the device passed in, an empty string if the
+// device is not part of a multipath device.
+func multipathHolder(device string) string {
+	holders, err := os.ReadDir(filepath.Join(sysfsRoot, "block", device, "holders"))
+	if err != nil {
+		return ""
+	}
+	for _, holder := range holders {
+		if strings.HasPrefix(holder.Name(), "dm-") {
+			return holder.Name()
+		}
+	}
+	return ""
+}
+
+// Discovers the LUN passed in, exported through the target ports passed in, and returns
+// the path of its device.
+func attachFCVolume(ctx context.Context, targets []string, lun int32) (string, error) {
+
+	if err := rescanFCHosts(ctx); err != nil {
+		return "", status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	for attempt := 1; ; attempt++ {
+		devices, err := findFCDevices(targets, lun)
+		if err != nil {
+			return "", status.Error(codes.Internal, err.Error())
+		}
+		if len(devices) > 0 {
+			device := devices[0]
+			if holder := multipathHolder(device); holder != "" {
+				device = holder
+			}
+			utils.GetLogNODE(ctx, 5).Println("Fibre Channel LUN discovered", "lun", lun,
+				"paths", devices, "device", device)
+			return filepath.Join("/dev", device), nil
+		}
+		if attempt >= fcDiscoveryAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return "", status.FromContextError(ctx.Err()).Err()
+		case <-time.After(fcDiscoveryInterval):
+		}
+	}
+
+	return "", status.Errorf(codes.NotFound, "LUN %d not found behind the targets %v", lun, targets)
+}
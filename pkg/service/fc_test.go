@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Writes a file of the fake sysfs tree of the test.
+func writeSysfsFile(t *testing.T, name, content string) {
+	t.Helper()
+	path := filepath.Join(sysfsRoot, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("cannot create %s: %v", filepath.Dir(path), err)
+	}
+	writeTestFile(t, path, content)
+}
+
+func TestNormalizeWWN(t *testing.T) {
+	for _, wwn := range []string{"0x2101001b32a11639\n", "wwn.2101001B32A11639", "21:01:00:1b:32:a1:16:39"} {
+		if normalizeWWN(wwn) != "2101001b32a11639" {
+			t.Errorf("unexpected normalization of %q: %q", wwn, normalizeWWN(wwn))
+		}
+		if applianceWWN(wwn) != "wwn.2101001B32A11639" {
+			t.Errorf("unexpected appliance form of %q: %q", wwn, applianceWWN(wwn))
+		}
+	}
+}
+
+func TestAttachFCVolume(t *testing.T) {
+	ctx := testContext()
+	sysfsRoot = t.TempDir()
+	attempts := fcDiscoveryAttempts
+	fcDiscoveryAttempts = 2
+	fcDiscoveryInterval = time.Millisecond
+	t.Cleanup(func() {
+		sysfsRoot = "/sys"
+		fcDiscoveryAttempts = attempts
+		fcDiscoveryInterval = time.Second
+	})
+
+	if _, err := attachFCVolume(ctx, []string{"wwn.2100001B32A00001"}, 3); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected FailedPrecondition without Fibre Channel port, got %v", err)
+	}
+
+	// Two ports of the node see the target port of the appliance, a third one sees
+	// another target.
+	for _, host := range []string{"host3", "host4"} {
+		writeSysfsFile(t, "class/fc_host/"+host+"/port_name", "0x2101001b32a11639\n")
+		writeSysfsFile(t, "class/scsi_host/"+host+"/scan", "")
+	}
+	writeSysfsFile(t, "class/fc_transport/target3:0:0/port_name", "0x2100001b32a00001\n")
+	writeSysfsFile(t, "class/fc_transport/target4:0:1/port_name", "0x2100001b32a00001\n")
+	writeSysfsFile(t, "class/fc_transport/target4:0:2/port_name", "0x2100001b32a00002\n")
+	writeSysfsFile(t, "class/scsi_device/4:0:2:3/device/block/sdz/dev", "")
+
+	if _, err := attachFCVolume(ctx, []string{"wwn.2100001B32A00001"}, 3); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound before the LUN is discovered, got %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(sysfsRoot, "class/scsi_host/host3/scan"))
+	if err != nil || string(data) != "- - -" {
+		t.Errorf("SCSI host not rescanned: %q, %v", data, err)
+	}
+
+	writeSysfsFile(t, "class/scsi_device/3:0:0:3/device/block/sdc/dev", "")
+	if device, err := attachFCVolume(ctx, []string{"wwn.2100001B32A00001"}, 3); err != nil || device != "/dev/sdc" {
+		t.Errorf("unexpected device %q, %v", device, err)
+	}
+
+	// With a second path, the multipath device is used.
+	writeSysfsFile(t, "class/scsi_device/4:0:1:3/device/block/sdd/dev", "")
+	writeSysfsFile(t, "block/sdc/holders/dm-2/dev", "")
+	if device, err := attachFCVolume(ctx, []string{"wwn.2100001B32A00001"}, 3); err != nil || device != "/dev/dm-2" {
+		t.Errorf("unexpected multipath device %q, %v", device, err)
+	}
+}
@@ -7,7 +7,6 @@ package service
 
 import (
 	"github.com/oracle/zfssa-csi-driver/pkg/utils"
-	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest"
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/golang/protobuf/ptypes/wrappers"
 	"golang.org/x/net/context"
@@ -64,17 +63,18 @@ func (zd *ZFSSADriver) Probe(ctx context.Context, req *csi.ProbeRequest) (
 
 	utils.GetLogIDTY(ctx, 5).Println("Probe")
 
-	// Check that the appliance is responsive, if it is not, we are on hold
-	user, password, err := zd.getUserLogin(ctx, nil)
-	if err != nil {
-		return nil, grpcStatus.Error(codes.Unauthenticated, "Invalid credentials")
-	}
-	token := zfssarest.LookUpToken(ctx, user, password)
-	_, err = zfssarest.GetServices(ctx, token)
-	if err != nil {
-		return &csi.ProbeResponse{
-			Ready: &wrappers.BoolValue{Value: false},
-		}, grpcStatus.Error(codes.FailedPrecondition, "Failure creating token")
+	// Check that the appliances are responsive, if one is not, we are on hold
+	for _, zfssa := range zd.getApplianceNames() {
+		client, token, err := zd.lookUpToken(ctx, zfssa, nil)
+		if err != nil {
+			return nil, err
+		}
+		_, err = client.GetServices(ctx, token)
+		if err != nil {
+			return &csi.ProbeResponse{
+				Ready: &wrappers.BoolValue{Value: false},
+			}, grpcStatus.Error(codes.FailedPrecondition, "Failure creating token")
+		}
 	}
 
 	return &csi.ProbeResponse{
@@ -0,0 +1,199 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/oracle/zfssa-csi-driver/pkg/utils"
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"os"
+	"strings"
+)
+
+// A LUN is published to a node by setting its initiator group to the group named after
+// the node. The initiators and initiator groups of the nodes no longer have to be defined
+// on the appliance beforehand:
+//
+//   - When it registers, the node plugin reads the IQN of the node from
+//     /etc/iscsi/initiatorname.iscsi and the WWNs of its Fibre Channel ports from
+//     /sys/class/fc_host, and records them in the annotations
+//     "zfssa-csi-driver.oracle.com/iscsi-initiator" and
+//     "zfssa-csi-driver.oracle.com/fc-initiators" (comma separated) of its Node object.
+//   - When a LUN is published to a node for the first time, the controller defines the
+//     initiators of the annotation of the protocol of the LUN (aliased with the node
+//     name) and creates the initiator group of the node or adds the initiators to it.
+//
+// Initiators and initiator groups are never removed by the driver. A node without the
+// annotation (plugin unable to read its initiators) keeps requiring an initiator group
+// defined by the administrator.
+
+const (
+	// Annotation of the Node objects holding the IQN of the node.
+	iscsiInitiatorAnnotation = "zfssa-csi-driver.oracle.com/iscsi-initiator"
+	// Annotation of the Node objects holding the WWNs of the Fibre Channel ports of the node.
+	fcInitiatorAnnotation = "zfssa-csi-driver.oracle.com/fc-initiators"
+	// File of the node defining its IQN.
+	DefaultInitiatorNamePath = "/etc/iscsi/initiatorname.iscsi"
+)
+
+// Location of the file defining the IQN of the node.
+var initiatorNamePath = DefaultInitiatorNamePath
+
+// Annotation holding the initiators of the node for each protocol.
+var initiatorAnnotations = map[string]string{
+	protocolISCSI: iscsiInitiatorAnnotation,
+	protocolFC:    fcInitiatorAnnotation,
+}
+
+// Returns the initiator name (InitiatorName=...) defined in the file passed in.
+func readInitiatorName(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if found && strings.TrimSpace(key) == "InitiatorName" && strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value), nil
+		}
+	}
+	return "", errors.New(fmt.Sprintf("no InitiatorName defined in <%s>", path))
+}
+
+// Records the initiators of the node in the annotations of its Node object. A failure is
+// only logged: the initiator groups of the node then have to be defined on the appliance.
+func (zd *ZFSSADriver) publishInitiators(ctx context.Context) {
+	annotations := make(map[string]string)
+
+	iqn, err := readInitiatorName(initiatorNamePath)
+	if err != nil {
+		utils.GetLogNODE(ctx, 4).Println("IQN of the node unknown", "error", err.Error())
+	} else {
+		annotations[iscsiInitiatorAnnotation] = iqn
+	}
+
+	wwns, err := readFCPortNames()
+	if err != nil {
+		utils.GetLogNODE(ctx, 4).Println("Fibre Channel ports of the node unknown", "error", err.Error())
+	} else if len(wwns) > 0 {
+		annotations[fcInitiatorAnnotation] = strings.Join(wwns, ",")
+	}
+
+	if len(annotations) == 0 {
+		utils.GetLogNODE(ctx, 2).Println("No initiator found on the node")
+		return
+	}
+	if clientset == nil {
+		return
+	}
+	if err := SetNodeAnnotations(ctx, zd.config.NodeName, annotations); err != nil {
+		utils.GetLogNODE(ctx, 2).Println("Initiators of the node could not be published",
+			"node", zd.config.NodeName, "initiators", annotations, "error", err.Error())
+		return
+	}
+	utils.GetLogNODE(ctx, 5).Println("Initiators of the node published", "node", zd.config.NodeName,
+		"initiators", annotations)
+}
+
+// Makes sure the initiator group named after the node exists and contains the initiators
+// of the protocol the node published. Nothing is done if the node did not publish any.
+func ensureNodeInitiatorGroup(ctx context.Context, client *zfssarest.Client, token *zfssarest.Token,
+	protocol, nodeName string) error {
+
+	value, err := GetNodeAnnotation(ctx, nodeName, initiatorAnnotations[protocol])
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "Node (%s) could not be read: %v", nodeName, err)
+	}
+	var initiators []string
+	for _, initiator := range strings.Split(value, ",") {
+		if initiator = strings.TrimSpace(initiator); initiator != "" {
+			initiators = append(initiators, initiator)
+		}
+	}
+	if len(initiators) == 0 {
+		utils.GetLogCTRL(ctx, 5).Println("Initiators of the node unknown, its initiator group must exist",
+			"node", nodeName, "protocol", protocol)
+		return nil
+	}
+
+	for _, initiator := range initiators {
+		if err := ensureInitiator(ctx, client, token, protocol, initiator, nodeName); err != nil {
+			return err
+		}
+	}
+	return ensureInitiatorGroup(ctx, client, token, protocol, nodeName, initiators)
+}
+
+// Defines the initiator passed in if it is not defined.
+func ensureInitiator(ctx context.Context, client *zfssarest.Client, token *zfssarest.Token,
+	protocol, initiator, alias string) error {
+
+	_, err := client.GetInitiator(ctx, token, protocol, initiator)
+	if status.Code(err) != codes.NotFound {
+		return err
+	}
+
+	utils.GetLogCTRL(ctx, 2).Println("Creating initiator", "initiator", initiator, "alias", alias)
+	_, _, err = client.CreateInitiator(ctx, token, protocol, initiator, alias)
+	if status.Code(err) == codes.AlreadyExists {
+		return nil
+	}
+	return err
+}
+
+// Creates the initiator group passed in or adds the initiators missing from it.
+func ensureInitiatorGroup(ctx context.Context, client *zfssarest.Client, token *zfssarest.Token,
+	protocol, name string, initiators []string) error {
+
+	group, err := client.GetInitiatorGroup(ctx, token, protocol, name)
+	if status.Code(err) == codes.NotFound {
+		utils.GetLogCTRL(ctx, 2).Println("Creating initiator group", "group", name, "initiators", initiators)
+		_, _, err = client.CreateInitiatorGroup(ctx, token, protocol, name, initiators)
+		if status.Code(err) != codes.AlreadyExists {
+			return err
+		}
+		// Created concurrently for another volume of the node.
+		group, err = client.GetInitiatorGroup(ctx, token, protocol, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	members := append([]string{}, group.Initiators...)
+	for _, initiator := range initiators {
+		if !containsString(members, initiator) {
+			members = append(members, initiator)
+		}
+	}
+	if len(members) == len(group.Initiators) {
+		return nil
+	}
+
+	utils.GetLogCTRL(ctx, 2).Println("Adding initiators to group", "group", name, "initiators", members)
+	_, _, err = client.ModifyInitiatorGroup(ctx, token, protocol, name, members)
+	return err
+}
+
+// Returns true if the list passed in contains the string passed in.
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
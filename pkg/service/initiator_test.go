@@ -0,0 +1,281 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestReadInitiatorName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "initiatorname.iscsi")
+	writeTestFile(t, path, "## Generated by iscsi-iname\n#InitiatorName=iqn.ignored\nInitiatorName=iqn.1988-12.com.oracle:node1\n")
+	if iqn, err := readInitiatorName(path); err != nil || iqn != "iqn.1988-12.com.oracle:node1" {
+		t.Errorf("unexpected initiator name %q, %v", iqn, err)
+	}
+
+	writeTestFile(t, path, "# no initiator name\n")
+	if _, err := readInitiatorName(path); err == nil {
+		t.Errorf("expected an error for a file without initiator name")
+	}
+}
+
+func TestPublishToNodeInitiatorGroup(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+	ctx := testContext()
+
+	const iqn1 = "iqn.1988-12.com.oracle:node1"
+	const iqn2 = "iqn.1988-12.com.oracle:node2"
+	clientset = fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2",
+			Annotations: map[string]string{iscsiInitiatorAnnotation: iqn2}}})
+	initiatorNamePath = filepath.Join(t.TempDir(), "initiatorname.iscsi")
+	sysfsRoot = t.TempDir()
+	t.Cleanup(func() {
+		clientset = nil
+		initiatorNamePath = DefaultInitiatorNamePath
+		sysfsRoot = "/sys"
+	})
+
+	// The node plugin publishes its initiators when it registers.
+	writeTestFile(t, initiatorNamePath, "InitiatorName="+iqn1+"\n")
+	writeSysfsFile(t, "class/fc_host/host3/port_name", "0x2101001b32a11639\n")
+	writeSysfsFile(t, "class/fc_host/host4/port_name", "0x2101001b32a11640\n")
+	zd.config.NodeName = "node1"
+	if _, err := zd.NodeGetInfo(ctx, &csi.NodeGetInfoRequest{}); err != nil {
+		t.Fatalf("NodeGetInfo failed: %v", err)
+	}
+	if iqn, err := GetNodeAnnotation(ctx, "node1", iscsiInitiatorAnnotation); err != nil || iqn != iqn1 {
+		t.Fatalf("IQN not published: %q, %v", iqn, err)
+	}
+	wwns, err := GetNodeAnnotation(ctx, "node1", fcInitiatorAnnotation)
+	if err != nil || wwns != "wwn.2101001B32A11639,wwn.2101001B32A11640" {
+		t.Fatalf("WWNs not published: %q, %v", wwns, err)
+	}
+
+	client, token, err := zd.lookUpToken(ctx, zfssa.Name(), nil)
+	if err != nil {
+		t.Fatalf("lookUpToken failed: %v", err)
+	}
+	// The group of node2 exists but does not contain its IQN yet.
+	if _, _, err := client.CreateInitiatorGroup(ctx, token, "iscsi", "node2", []string{"iqn.other"}); err != nil {
+		t.Fatalf("CreateInitiatorGroup failed: %v", err)
+	}
+
+	zfssa.AddTargetGroup("fc", "fctg0", "wwn.2100001B32A00001")
+	for _, tt := range []struct {
+		name       string
+		node       string
+		protocol   string
+		initiators []string
+	}{
+		{"pvc-1", "node1", "iscsi", []string{iqn1}},
+		{"pvc-2", "node2", "iscsi", []string{"iqn.other", iqn2}},
+		{"pvc-3", "node1", "fc", []string{"wwn.2101001B32A11639", "wwn.2101001B32A11640"}},
+	} {
+		parameters := filesystemParameters()
+		parameters["targetGroup"] = "tg0"
+		if tt.protocol == "fc" {
+			parameters["protocol"] = "fc"
+			parameters["targetGroup"] = "fctg0"
+		}
+		vol, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+			Name:               tt.name,
+			CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+			VolumeCapabilities: blockCapabilities(),
+			Parameters:         parameters,
+		})
+		if err != nil {
+			t.Fatalf("CreateVolume failed: %v", err)
+		}
+		_, err = zd.ControllerPublishVolume(ctx, &csi.ControllerPublishVolumeRequest{
+			VolumeId:         vol.GetVolume().GetVolumeId(),
+			NodeId:           tt.node,
+			VolumeCapability: blockCapabilities()[0],
+			VolumeContext:    vol.GetVolume().GetVolumeContext(),
+		})
+		if err != nil {
+			t.Fatalf("ControllerPublishVolume to %s failed: %v", tt.node, err)
+		}
+
+		group, err := client.GetInitiatorGroup(ctx, token, tt.protocol, tt.node)
+		if err != nil || !reflect.DeepEqual(group.Initiators, tt.initiators) {
+			t.Errorf("unexpected %s initiator group of %s: %+v, %v", tt.protocol, tt.node, group, err)
+		}
+		if _, err := client.GetInitiator(ctx, token, tt.protocol, tt.initiators[len(tt.initiators)-1]); err != nil {
+			t.Errorf("%s initiator of %s not created: %v", tt.protocol, tt.node, err)
+		}
+		lun, _ := zfssa.Lookup(fmt.Sprintf("/api/storage/v2/pools/%s/projects/%s/luns/%s", testPool, testProject, tt.name))
+		if fmt.Sprint(lun["initiatorgroup"]) != "["+tt.node+"]" {
+			t.Errorf("LUN not published to %s: %v", tt.node, lun["initiatorgroup"])
+		}
+	}
+}
+
+func TestMultiNodeBlockVolume(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+	ctx := testContext()
+
+	clientset = fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1",
+			Annotations: map[string]string{iscsiInitiatorAnnotation: "iqn.1988-12.com.oracle:node1"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2",
+			Annotations: map[string]string{iscsiInitiatorAnnotation: "iqn.1988-12.com.oracle:node2"}}})
+	t.Cleanup(func() { clientset = nil })
+
+	lunHref := fmt.Sprintf("/api/storage/v2/pools/%s/projects/%s/luns/", testPool, testProject)
+	initiatorGroups := func(name string) string {
+		lun, _ := zfssa.Lookup(lunHref + name)
+		return fmt.Sprint(lun["initiatorgroup"])
+	}
+
+	parameters := filesystemParameters()
+	parameters["targetGroup"] = "tg0"
+	for _, tt := range []struct {
+		name string
+		mode csi.VolumeCapability_AccessMode_Mode
+	}{
+		{"pvc-single", csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+		{"pvc-multi", csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+		{"pvc-reader", csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY},
+	} {
+		capability := &csi.VolumeCapability{
+			AccessType: &csi.VolumeCapability_Block{Block: &csi.VolumeCapability_BlockVolume{}},
+			AccessMode: &csi.VolumeCapability_AccessMode{Mode: tt.mode},
+		}
+		vol, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+			Name:               tt.name,
+			CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+			VolumeCapabilities: []*csi.VolumeCapability{capability},
+			Parameters:         parameters,
+		})
+		if err != nil {
+			t.Fatalf("CreateVolume(%s) failed: %v", tt.name, err)
+		}
+		volumeId := vol.GetVolume().GetVolumeId()
+
+		publish := func(node string) error {
+			_, err := zd.ControllerPublishVolume(ctx, &csi.ControllerPublishVolumeRequest{
+				VolumeId:         volumeId,
+				NodeId:           node,
+				VolumeCapability: capability,
+				VolumeContext:    vol.GetVolume().GetVolumeContext(),
+			})
+			return err
+		}
+		unpublish := func(node string) error {
+			_, err := zd.ControllerUnpublishVolume(ctx, &csi.ControllerUnpublishVolumeRequest{
+				VolumeId: volumeId,
+				NodeId:   node,
+			})
+			return err
+		}
+
+		// Publishing twice to the same node is idempotent.
+		for i := 0; i < 2; i++ {
+			if err := publish("node1"); err != nil {
+				t.Fatalf("ControllerPublishVolume(%s) to node1 failed: %v", tt.name, err)
+			}
+		}
+		err = publish("node2")
+		if tt.mode == csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER {
+			if status.Code(err) != codes.FailedPrecondition {
+				t.Errorf("ControllerPublishVolume(%s) to a second node: FailedPrecondition expected, got %v",
+					tt.name, err)
+			}
+			if groups := initiatorGroups(tt.name); groups != "[node1]" {
+				t.Errorf("unexpected initiator groups of %s: %s", tt.name, groups)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ControllerPublishVolume(%s) to node2 failed: %v", tt.name, err)
+		}
+		if groups := initiatorGroups(tt.name); groups != "[node1 node2]" {
+			t.Errorf("unexpected initiator groups of %s: %s", tt.name, groups)
+		}
+
+		// The LUN is masked again once it is unpublished from all the nodes.
+		for _, step := range []struct {
+			node   string
+			groups string
+		}{
+			{"node1", "[node2]"},
+			{"node1", "[node2]"},
+			{"node2", "[" + zfssarest.MaskAll + "]"},
+		} {
+			if err := unpublish(step.node); err != nil {
+				t.Fatalf("ControllerUnpublishVolume(%s) from %s failed: %v", tt.name, step.node, err)
+			}
+			if groups := initiatorGroups(tt.name); groups != step.groups {
+				t.Errorf("unexpected initiator groups of %s after unpublishing from %s: %s",
+					tt.name, step.node, groups)
+			}
+		}
+	}
+}
+
+// A LUN is only shared by several nodes as a block device, a file system on it would be
+// corrupted.
+func TestMultiNodeMountedLUN(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+	ctx := testContext()
+
+	clientset = fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1",
+			Annotations: map[string]string{iscsiInitiatorAnnotation: "iqn.1988-12.com.oracle:node1"}}})
+	t.Cleanup(func() { clientset = nil })
+
+	parameters := filesystemParameters()
+	parameters["targetGroup"] = "tg0"
+	vol, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-lun",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+		VolumeCapabilities: blockCapabilities(),
+		Parameters:         parameters,
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume failed: %v", err)
+	}
+
+	mounted := &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+		AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+	}
+	rsp, err := zd.ValidateVolumeCapabilities(ctx, &csi.ValidateVolumeCapabilitiesRequest{
+		VolumeId:           vol.GetVolume().GetVolumeId(),
+		VolumeCapabilities: []*csi.VolumeCapability{mounted},
+	})
+	if err != nil || rsp.GetConfirmed() != nil {
+		t.Errorf("mounted MULTI_NODE_MULTI_WRITER LUN confirmed: %v, %v", rsp, err)
+	}
+
+	_, err = zd.ControllerPublishVolume(ctx, &csi.ControllerPublishVolumeRequest{
+		VolumeId:         vol.GetVolume().GetVolumeId(),
+		NodeId:           "node1",
+		VolumeCapability: mounted,
+		VolumeContext:    vol.GetVolume().GetVolumeContext(),
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("ControllerPublishVolume of a mounted MULTI_NODE_MULTI_WRITER LUN: InvalidArgument expected, got %v",
+			err)
+	}
+	lun, _ := zfssa.Lookup(fmt.Sprintf("/api/storage/v2/pools/%s/projects/%s/luns/pvc-lun", testPool, testProject))
+	if groups := fmt.Sprint(lun["initiatorgroup"]); groups != "["+zfssarest.MaskAll+"]" {
+		t.Errorf("unexpected initiator groups: %s", groups)
+	}
+}
@@ -13,8 +13,12 @@ import (
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	iscsi_lib "github.com/kubernetes-csi/csi-lib-iscsi/iscsi"
 	"github.com/oracle/zfssa-csi-driver/pkg/utils"
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"k8s.io/kubernetes/pkg/volume/util"
 	"k8s.io/utils/mount"
+	"net"
 	"os"
 	"os/exec"
 	"path"
@@ -32,23 +36,31 @@ const (
 	ISCSI_ERR_NO_OBJS_FOUND                       = 21
 )
 
-func GetISCSIInfo(ctx context.Context, vid *utils.VolumeId, req *csi.NodePublishVolumeRequest, targetIqn string,
-	assignedLunNumber int32) (*iscsiDisk, error) {
+// Port the iSCSI targets of the appliance listen on.
+const iscsiPort = "3260"
 
-	volName := vid.Name
-	tp := req.GetVolumeContext()["targetPortal"]
-	iqn := targetIqn
+// iSCSI target of a target group and the portals it is reached through.
+type iscsiTarget struct {
+	Iqn     string
+	Portals []string
+}
 
-	if tp == "" || iqn == "" {
-		return nil, fmt.Errorf("iSCSI target information is missing (portal=%v), (iqn=%v)", tp, iqn)
+// Returns the information needed to connect the LUN through the targets passed in. The
+// first target is the one the LUN is connected through first, the others add paths to it.
+func GetISCSIInfo(ctx context.Context, vid *utils.VolumeId, req *csi.NodePublishVolumeRequest,
+	targets []iscsiTarget, assignedLunNumber int32) (*iscsiDisk, error) {
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("iSCSI target information is missing")
 	}
+	volName := vid.Name
+	iqn := targets[0].Iqn
+	portals := targets[0].Portals
 
-	portalList := req.GetVolumeContext()["portals"]
-	if portalList == "" {
-		portalList = "[]"
+	if len(portals) == 0 || iqn == "" {
+		return nil, fmt.Errorf("iSCSI target information is missing (portals=%v), (iqn=%v)", portals, iqn)
 	}
 
-	utils.GetLogCTRL(ctx, 5).Println("getISCSIInfo", "portal_list", portalList)
 	secretParams := req.GetVolumeContext()["secret"]
 
 	utils.GetLogCTRL(ctx, 5).Println("getISCSIInfo", "secret_params", secretParams)
@@ -63,21 +75,7 @@ func GetISCSIInfo(ctx context.Context, vid *utils.VolumeId, req *csi.NodePublish
 		return nil, err
 	}
 
-	utils.GetLogCTRL(ctx, 5).Println("portalMounter", "tp", tp)
-	portal := portalMounter(tp)
-	var bkportal []string
-	bkportal = append(bkportal, portal)
-
-	portals := []string{}
-	if err := json.Unmarshal([]byte(portalList), &portals); err != nil {
-		return nil, err
-	}
-
-	for _, portal := range portals {
-		bkportal = append(bkportal, portalMounter(string(portal)))
-	}
-
-	utils.GetLogCTRL(ctx, 5).Println("Built bkportal", "bkportal", bkportal)
+	utils.GetLogCTRL(ctx, 5).Println("getISCSIInfo", "portals", portals)
 	iface := req.GetVolumeContext()["iscsiInterface"]
 	initiatorName := req.GetVolumeContext()["initiatorName"]
 	chapDiscovery := false
@@ -93,7 +91,7 @@ func GetISCSIInfo(ctx context.Context, vid *utils.VolumeId, req *csi.NodePublish
 	utils.GetLogCTRL(ctx, 5).Println("Final values", "iface", iface, "initiatorName", initiatorName)
 	i := iscsiDisk{
 		VolName:         volName,
-		Portals:         bkportal,
+		Portals:         portals,
 		Iqn:             iqn,
 		lun:             assignedLunNumber,
 		Iface:           iface,
@@ -103,6 +101,7 @@ func GetISCSIInfo(ctx context.Context, vid *utils.VolumeId, req *csi.NodePublish
 		sessionSecret:   sessionSecret,
 		discoverySecret: discoverySecret,
 		InitiatorName:   initiatorName,
+		otherTargets:    targets[1:],
 	}
 	return &i, nil
 }
@@ -175,11 +174,11 @@ func GetNodeISCSIInfo(vid *utils.VolumeId, req *csi.NodePublishVolumeRequest, ta
 	return &i, nil
 }
 
-func buildISCSIConnector(iscsiInfo *iscsiDisk) *iscsi_lib.Connector {
+func buildISCSIConnector(iscsiInfo *iscsiDisk, iqn string, portals []string) *iscsi_lib.Connector {
 	c := iscsi_lib.Connector{
 		VolumeName:    iscsiInfo.VolName,
-		TargetIqn:     iscsiInfo.Iqn,
-		TargetPortals: iscsiInfo.Portals,
+		TargetIqn:     iqn,
+		TargetPortals: portals,
 		Lun:           iscsiInfo.lun,
 	}
 
@@ -193,6 +192,16 @@ func buildISCSIConnector(iscsiInfo *iscsiDisk) *iscsi_lib.Connector {
 	return &c
 }
 
+// Returns the connectors of the targets the LUN is reached through, the connector of its
+// first target first.
+func buildISCSIConnectors(iscsiInfo *iscsiDisk) []*iscsi_lib.Connector {
+	connectors := []*iscsi_lib.Connector{buildISCSIConnector(iscsiInfo, iscsiInfo.Iqn, iscsiInfo.Portals)}
+	for _, target := range iscsiInfo.otherTargets {
+		connectors = append(connectors, buildISCSIConnector(iscsiInfo, target.Iqn, target.Portals))
+	}
+	return connectors
+}
+
 func GetISCSIDiskMounter(iscsiInfo *iscsiDisk, readOnly bool, fsType string, mountOptions []string,
 	targetPath string) *iscsiDiskMounter {
 
@@ -204,7 +213,7 @@ func GetISCSIDiskMounter(iscsiInfo *iscsiDisk, readOnly bool, fsType string, mou
 		mounter:      &mount.SafeFormatAndMount{Interface: mount.New("")},
 		targetPath:   targetPath,
 		deviceUtil:   util.NewDeviceHandler(util.NewIOHandler()),
-		connector:    buildISCSIConnector(iscsiInfo),
+		connectors:   buildISCSIConnectors(iscsiInfo),
 	}
 }
 
@@ -218,9 +227,111 @@ func GetISCSIDiskUnmounter(volumeId *utils.VolumeId) *iscsiDiskUnmounter {
 	}
 }
 
+// Returns the targets of the target group whose IQNs are passed in, along with their
+// portals (see getISCSIPortals). On a clustered appliance, the group usually has a target
+// per head, each reached through the interfaces of its head. When the volume context
+// sets the portals, they designate the first target only. A target without portal is
+// skipped, an error is returned if none of them has one.
+func getISCSITargets(ctx context.Context, client *zfssarest.Client, token *zfssarest.Token,
+	volumeContext map[string]string, iqns []string) ([]iscsiTarget, error) {
+
+	if volumeContext["targetPortal"] != "" {
+		iqns = iqns[:1]
+	}
+
+	var targets []iscsiTarget
+	var lastErr error
+	for _, iqn := range iqns {
+		portals, err := getISCSIPortals(ctx, client, token, volumeContext, iqn)
+		if status.Code(err) == codes.FailedPrecondition {
+			utils.GetLogNODE(ctx, 2).Println("iSCSI target skipped", "target", iqn, "error", err.Error())
+			lastErr = err
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, iscsiTarget{Iqn: iqn, Portals: portals})
+	}
+	if len(targets) == 0 {
+		return nil, lastErr
+	}
+	return targets, nil
+}
+
+// Returns the portals of the iSCSI target passed in. When the volume context sets
+// "targetPortal", the portals of the context ("targetPortal" and the JSON list "portals")
+// are returned. Otherwise the portals are the addresses of the network interfaces of the
+// appliance the target is available on (all the interfaces if the target doesn't list
+// any), only the interfaces up are retained.
+func getISCSIPortals(ctx context.Context, client *zfssarest.Client, token *zfssarest.Token,
+	volumeContext map[string]string, targetIqn string) ([]string, error) {
+
+	if tp := volumeContext["targetPortal"]; tp != "" {
+		portalList := volumeContext["portals"]
+		if portalList == "" {
+			portalList = "[]"
+		}
+		portals := []string{}
+		if err := json.Unmarshal([]byte(portalList), &portals); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid list of portals (%s): %v", portalList, err)
+		}
+		bkportal := []string{portalMounter(tp)}
+		for _, portal := range portals {
+			bkportal = append(bkportal, portalMounter(portal))
+		}
+		return bkportal, nil
+	}
+
+	target, err := client.GetTarget(ctx, token, "iscsi", targetIqn)
+	if err != nil {
+		return nil, err
+	}
+
+	var interfaces []zfssarest.NetworkInterface
+	if len(target.Interfaces) == 0 {
+		interfaces, err = client.GetNetworkInterfaces(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		for _, name := range target.Interfaces {
+			iface, err := client.GetNetworkInterface(ctx, token, name)
+			if err != nil {
+				return nil, err
+			}
+			interfaces = append(interfaces, *iface)
+		}
+	}
+
+	var portals []string
+	for _, iface := range interfaces {
+		if !iface.Enable || iface.State != "up" {
+			continue
+		}
+		for _, addr := range append(append([]string{}, iface.V4Addrs...), iface.V6Addrs...) {
+			ip, _, err := net.ParseCIDR(addr)
+			if err != nil {
+				ip = net.ParseIP(addr)
+			}
+			if ip == nil || ip.IsUnspecified() || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+				continue
+			}
+			portals = append(portals, net.JoinHostPort(ip.String(), iscsiPort))
+		}
+	}
+	utils.GetLogNODE(ctx, 5).Println("iSCSI portals discovered", "target", targetIqn, "portals", portals)
+
+	if len(portals) == 0 {
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"no portal found for the iSCSI target %s, set targetPortal in the StorageClass", targetIqn)
+	}
+	return portals, nil
+}
+
 func portalMounter(portal string) string {
 	if !strings.Contains(portal, ":") {
-		portal = portal + ":3260"
+		portal = portal + ":" + iscsiPort
 	}
 	return portal
 }
@@ -295,6 +406,7 @@ type iscsiDisk struct {
 	discoverySecret iscsi_lib.Secrets
 	InitiatorName   string
 	VolName         string
+	otherTargets    []iscsiTarget // Targets of the group other than Iqn
 }
 
 type iscsiDiskMounter struct {
@@ -305,7 +417,7 @@ type iscsiDiskMounter struct {
 	mounter      *mount.SafeFormatAndMount
 	deviceUtil   util.DeviceUtil
 	targetPath   string
-	connector    *iscsi_lib.Connector
+	connectors   []*iscsi_lib.Connector
 }
 
 type iscsiDiskUnmounter struct {
@@ -356,21 +468,30 @@ func (util *ISCSIUtil) ConnectDisk(ctx context.Context, b iscsiDiskMounter) (str
 	utils.GetLogUTIL(ctx, 4).Println("ConnectDisk started")
 	_, err := util.Rescan(ctx)
 	if err != nil {
-		utils.GetLogUTIL(ctx, 4).Println("iSCSI rescan error: %s", err.Error())
+		utils.GetLogUTIL(ctx, 4).Printf("iSCSI rescan error: %s", err.Error())
 		return "", err
 	}
 	utils.GetLogUTIL(ctx, 4).Println("ConnectDisk will connect and get device path")
-	devicePath, err := iscsi_lib.Connect(*b.connector)
+	devicePath, err := iscsi_lib.Connect(*b.connectors[0])
 	if err != nil {
-		utils.GetLogUTIL(ctx, 4).Println("iscsi_lib connect error: %s", err.Error())
+		utils.GetLogUTIL(ctx, 4).Printf("iscsi_lib connect error: %s", err.Error())
 		return "", err
 	}
 
+	// The sessions with the other targets add paths to the device (multipath). A target
+	// that can't be reached only costs its paths.
+	for _, connector := range b.connectors[1:] {
+		if _, err := iscsi_lib.Connect(*connector); err != nil {
+			utils.GetLogUTIL(ctx, 2).Println("iscsi_lib connect error, paths of the target not used",
+				"target", connector.TargetIqn, "error", err.Error())
+		}
+	}
+
 	if devicePath == "" {
 		utils.GetLogUTIL(ctx, 4).Println("iscsi_lib devicePath is empty, cannot continue")
 		return "", fmt.Errorf("connect reported success, but no path returned")
 	}
-	utils.GetLogUTIL(ctx, 4).Println("ConnectDisk devicePath: %s", devicePath)
+	utils.GetLogUTIL(ctx, 4).Printf("ConnectDisk devicePath: %s", devicePath)
 	return devicePath, nil
 }
 
@@ -379,7 +500,7 @@ func (util *ISCSIUtil) AttachDisk(ctx context.Context, b iscsiDiskMounter, devic
 	if len(devicePath) == 0 {
 		localDevicePath, err := util.ConnectDisk(ctx, b)
 		if err != nil {
-			utils.GetLogUTIL(ctx, 3).Println("ConnectDisk failure: %s", err.Error())
+			utils.GetLogUTIL(ctx, 3).Printf("ConnectDisk failure: %s", err.Error())
 			return "", err
 		}
 		devicePath = localDevicePath
@@ -400,10 +521,11 @@ func (util *ISCSIUtil) AttachDisk(ctx context.Context, b iscsiDiskMounter, devic
 	}
 
 	// Persist iscsi disk config to json file for DetachDisk path
-	file := path.Join(mntPath, b.VolName+".json")
-	err = iscsi_lib.PersistConnector(b.connector, file)
-	if err != nil {
-		return "", err
+	for i, connector := range b.connectors {
+		err = iscsi_lib.PersistConnector(connector, connectorFile(mntPath, b.VolName, i))
+		if err != nil {
+			return "", err
+		}
 	}
 
 	options := []string{"bind"}
@@ -415,7 +537,7 @@ func (util *ISCSIUtil) AttachDisk(ctx context.Context, b iscsiDiskMounter, devic
 	}
 	options = append(options, b.mountOptions...)
 
-	utils.GetLogUTIL(ctx, 3).Println("Mounting disk at path: %s", mntPath)
+	utils.GetLogUTIL(ctx, 3).Printf("Mounting disk at path: %s", mntPath)
 	err = b.mounter.Mount(devicePath, mntPath, "", options)
 	if err != nil {
 		utils.GetLogUTIL(ctx, 3).Println("iscsi: failed to mount iscsi volume",
@@ -451,16 +573,37 @@ func (util *ISCSIUtil) DetachDisk(ctx context.Context, c iscsiDiskUnmounter, tar
 	}
 
 	// load iscsi disk config from json file
-	file := path.Join(targetPath, c.iscsiDisk.VolName+".json")
-	connector, err := iscsi_lib.GetConnectorFromFile(file)
+	connector, err := iscsi_lib.GetConnectorFromFile(connectorFile(targetPath, c.iscsiDisk.VolName, 0))
 	if err != nil {
 		return err
 	}
-
 	iscsi_lib.Disconnect(connector.TargetIqn, connector.TargetPortals)
+
+	// Sessions with the other targets of the group
+	for i := 1; ; i++ {
+		file := connectorFile(targetPath, c.iscsiDisk.VolName, i)
+		if _, err := os.Stat(file); err != nil {
+			break
+		}
+		connector, err := iscsi_lib.GetConnectorFromFile(file)
+		if err != nil {
+			return err
+		}
+		iscsi_lib.Disconnect(connector.TargetIqn, connector.TargetPortals)
+	}
+
 	if err := os.RemoveAll(targetPath); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// Returns the file the connector of the target passed in (index in the target group, the
+// first target being 0) is persisted to.
+func connectorFile(dir, volName string, target int) string {
+	if target == 0 {
+		return path.Join(dir, volName+".json")
+	}
+	return path.Join(dir, fmt.Sprintf("%s.%d.json", volName, target))
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/oracle/zfssa-csi-driver/pkg/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestISCSIPortals(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+	ctx := testContext()
+
+	zfssa.AddNetworkInterface("ixgbe0", "up", "192.168.10.4/24", "fe80::1/64")
+	zfssa.AddNetworkInterface("ixgbe1", "up", "192.168.11.4/24", "2001:db8::4/64")
+	zfssa.AddNetworkInterface("ixgbe2", "down", "192.168.12.4/24")
+	zfssa.AddTarget("iqn.1986-03.com.sun:02:tg1", "tg1", "ixgbe0", "ixgbe2")
+	zfssa.AddTarget("iqn.1986-03.com.sun:02:all", "all")
+	zfssa.AddTarget("iqn.1986-03.com.sun:02:down", "down", "ixgbe2")
+
+	client, token, err := zd.lookUpToken(ctx, zfssa.Name(), nil)
+	if err != nil {
+		t.Fatalf("lookUpToken failed: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		context map[string]string
+		target  string
+		portals []string
+		code    codes.Code
+	}{
+		{"interfaces of the target", nil, "iqn.1986-03.com.sun:02:tg1",
+			[]string{"192.168.10.4:3260"}, codes.OK},
+		{"all interfaces", nil, "iqn.1986-03.com.sun:02:all",
+			[]string{"192.168.10.4:3260", "192.168.11.4:3260", "[2001:db8::4]:3260"}, codes.OK},
+		{"storage class portals", map[string]string{"targetPortal": "10.0.0.1", "portals": `["10.0.0.2:3261"]`},
+			"iqn.1986-03.com.sun:02:tg1", []string{"10.0.0.1:3260", "10.0.0.2:3261"}, codes.OK},
+		{"invalid portals", map[string]string{"targetPortal": "10.0.0.1", "portals": "10.0.0.2"},
+			"iqn.1986-03.com.sun:02:tg1", nil, codes.InvalidArgument},
+		{"no interface up", nil, "iqn.1986-03.com.sun:02:down", nil, codes.FailedPrecondition},
+		{"unknown target", nil, "iqn.1986-03.com.sun:02:unknown", nil, codes.NotFound},
+	} {
+		portals, err := getISCSIPortals(ctx, client, token, tt.context, tt.target)
+		if status.Code(err) != tt.code {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.code, err)
+			continue
+		}
+		if !reflect.DeepEqual(portals, tt.portals) {
+			t.Errorf("%s: unexpected portals %v", tt.name, portals)
+		}
+	}
+}
+
+func TestISCSITargets(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+	ctx := testContext()
+
+	zfssa.AddNetworkInterface("ixgbe0", "up", "192.168.10.4/24")
+	zfssa.AddNetworkInterface("ixgbe1", "up", "192.168.11.4/24")
+	zfssa.AddNetworkInterface("ixgbe2", "down", "192.168.12.4/24")
+	zfssa.AddTarget("iqn.1986-03.com.sun:02:head1", "head1", "ixgbe0")
+	zfssa.AddTarget("iqn.1986-03.com.sun:02:head2", "head2", "ixgbe1")
+	zfssa.AddTarget("iqn.1986-03.com.sun:02:down", "down", "ixgbe2")
+
+	client, token, err := zd.lookUpToken(ctx, zfssa.Name(), nil)
+	if err != nil {
+		t.Fatalf("lookUpToken failed: %v", err)
+	}
+
+	group := []string{"iqn.1986-03.com.sun:02:head1", "iqn.1986-03.com.sun:02:down", "iqn.1986-03.com.sun:02:head2"}
+	all, err := getISCSITargets(ctx, client, token, nil, group)
+	expected := []iscsiTarget{
+		{"iqn.1986-03.com.sun:02:head1", []string{"192.168.10.4:3260"}},
+		{"iqn.1986-03.com.sun:02:head2", []string{"192.168.11.4:3260"}},
+	}
+	if err != nil || !reflect.DeepEqual(all, expected) {
+		t.Errorf("unexpected targets %v, %v", all, err)
+	}
+
+	// Portals set by the storage class designate the first target.
+	targets, err := getISCSITargets(ctx, client, token, map[string]string{"targetPortal": "10.0.0.1"}, group)
+	expected = []iscsiTarget{{"iqn.1986-03.com.sun:02:head1", []string{"10.0.0.1:3260"}}}
+	if err != nil || !reflect.DeepEqual(targets, expected) {
+		t.Errorf("unexpected targets with storage class portals %v, %v", targets, err)
+	}
+
+	_, err = getISCSITargets(ctx, client, token, nil, []string{"iqn.1986-03.com.sun:02:down"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("expected FailedPrecondition without portal, got %v", err)
+	}
+
+	// A connector per target, the first one being the connector of the first target.
+	disk, err := GetISCSIInfo(ctx, &utils.VolumeId{Name: "lun0"}, &csi.NodePublishVolumeRequest{}, all, 3)
+	if err != nil {
+		t.Fatalf("GetISCSIInfo failed: %v", err)
+	}
+	connectors := buildISCSIConnectors(disk)
+	if len(connectors) != 2 || connectors[0].TargetIqn != disk.Iqn || connectors[1].TargetIqn != "iqn.1986-03.com.sun:02:head2" ||
+		connectors[1].Lun != 3 || connectors[1].VolumeName != "lun0" {
+		t.Errorf("unexpected connectors %v", connectors)
+	}
+}
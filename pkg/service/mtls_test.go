@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest/zfssatest"
+)
+
+func TestClientCertificateAppliance(t *testing.T) {
+	zd, _ := newTestDriver(t)
+	other := zfssatest.NewServer(testUser, testPassword)
+	t.Cleanup(other.Close)
+	other.AddPool(testPool, 100*Gib)
+
+	certPEM, keyPEM, err := other.IssueClientCertificate(testUser)
+	if err != nil {
+		t.Fatalf("IssueClientCertificate failed: %v", err)
+	}
+	dir := t.TempDir()
+	credfile := filepath.Join(dir, "zfssa.yaml")
+	certfile := filepath.Join(dir, "client.crt")
+	keyfile := filepath.Join(dir, "client.key")
+	writeTestFile(t, credfile, "username: "+testUser+"\n")
+	writeTestFile(t, certfile, string(certPEM))
+	writeTestFile(t, keyfile, string(keyPEM))
+
+	err = zd.addAppliance(&applianceConfig{
+		Target:            other.Name(),
+		Credentials:       credfile,
+		Certificate:       other.CertFile(),
+		ClientCertificate: certfile,
+	})
+	if err == nil {
+		t.Fatalf("appliance accepted with a client certificate but no key")
+	}
+
+	err = zd.addAppliance(&applianceConfig{
+		Target:            other.Name(),
+		Credentials:       credfile,
+		Certificate:       other.CertFile(),
+		ClientCertificate: certfile,
+		ClientKey:         keyfile,
+	})
+	if err != nil {
+		t.Fatalf("addAppliance failed: %v", err)
+	}
+	ctx := testContext()
+	client, token, err := zd.lookUpToken(ctx, other.Name(), nil)
+	if err != nil {
+		t.Fatalf("lookUpToken failed: %v", err)
+	}
+	if _, err = client.GetPool(ctx, token, testPool); err != nil {
+		t.Errorf("request failed with a client certificate: %v", err)
+	}
+}
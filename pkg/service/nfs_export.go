@@ -0,0 +1,493 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"encoding/json"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/oracle/zfssa-csi-driver/pkg/utils"
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Unless the StorageClass sets "shareNFS", the file systems are created with NFS access
+// denied to all the clients and their export is maintained by the controller: when a
+// volume is published to a node, the IP addresses of the node (its internal addresses,
+// the external ones if it has none) are added to the "rw" list of the sharenfs property
+// of the file system ("ro" list if the volume is published read-only), and they are
+// removed when the volume is unpublished from the node:
+//
+//	sec=sys,rw=@10.0.0.11/32:@10.0.0.12/32,ro=@10.0.0.13/32
+//
+// When the file system is published to no node, it is not shared at all ("off"). The
+// parameter "nfsExport" selects the behavior explicitly: "node" for the export
+// maintained per node, "static" for the sharenfs value of "shareNFS" ("on" by default)
+// used as is. The file systems exported per node are stamped with the custom property
+// zcsiNfsExport set to "node", the access of a node is only revoked from them (the
+// unpublish request does not carry the parameters of the volume). The clients granted
+// access per node are recorded in the custom property zcsiNfsNodes, the access of a node
+// is revoked from the clients recorded, whatever the addresses of the node have become
+// (the node may even have been deleted).
+//
+// Rather than a raw sharenfs value, the export policy can be described by the following
+// parameters, validated by the driver:
+//
+//	nfsClients	CIDRs (10.0.0.0/24), IPv4 addresses or netgroups granted access,
+//			separated by commas. The file system is then exported statically.
+//	nfsAccess	"rw" (default) or "ro".
+//	nfsRootSquash	"false" to let the root user of the clients act as root.
+//	nfsAnonUID	UID the anonymous and squashed users are mapped to, -1 to deny them.
+//	nfsSecurity	"sys" (default), "krb5", "krb5i" or "krb5p", several separated by
+//			colons.
+//
+// For instance, nfsClients "10.0.0.0/24,eng", nfsRootSquash "false" and nfsSecurity
+// "krb5" give:
+//
+//	sec=krb5,rw=@10.0.0.0/24:eng,root=@10.0.0.0/24:eng
+//
+// Without nfsClients, the other parameters apply to the export maintained per node.
+
+const (
+	nfsExportKey    = "nfsExport"
+	nfsExportNode   = "node"
+	nfsExportStatic = "static"
+
+	nfsClientsKey    = "nfsClients"
+	nfsAccessKey     = "nfsAccess"
+	nfsRootSquashKey = "nfsRootSquash"
+	nfsAnonUIDKey    = "nfsAnonUID"
+	nfsSecurityKey   = "nfsSecurity"
+)
+
+// Parameters describing the NFS export policy.
+var nfsPolicyKeys = []string{nfsClientsKey, nfsAccessKey, nfsRootSquashKey, nfsAnonUIDKey, nfsSecurityKey}
+
+// Security flavors accepted in nfsSecurity.
+var nfsSecurityFlavors = []string{"sys", "krb5", "krb5i", "krb5p"}
+
+// Netgroup (or host) name accepted in nfsClients.
+var netgroupRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9._-]*$`)
+
+// Returns the NFS export mode requested by the parameters passed in.
+func nfsExportMode(parameters map[string]string) (string, error) {
+	shareNFS, ok := parameters["shareNFS"]
+	if ok && hasNFSPolicy(parameters) {
+		return "", status.Errorf(codes.InvalidArgument, "shareNFS (%s) cannot be set with %v", shareNFS,
+			nfsPolicyKeys)
+	}
+	_, clients := parameters[nfsClientsKey]
+
+	switch mode := parameters[nfsExportKey]; mode {
+	case "":
+		if ok || clients {
+			return nfsExportStatic, nil
+		}
+		return nfsExportNode, nil
+	case nfsExportStatic:
+		return mode, nil
+	case nfsExportNode:
+		if ok {
+			return "", status.Errorf(codes.InvalidArgument, "shareNFS cannot be set with %s %s",
+				nfsExportKey, nfsExportNode)
+		}
+		if clients {
+			return "", status.Errorf(codes.InvalidArgument, "%s cannot be set with %s %s",
+				nfsClientsKey, nfsExportKey, nfsExportNode)
+		}
+		return mode, nil
+	default:
+		return "", status.Errorf(codes.InvalidArgument, "invalid %s (%s)", nfsExportKey, mode)
+	}
+}
+
+// Returns the export mode of a file system and the sharenfs property it is created with,
+// as requested by the parameters passed in. The sharenfs property returned is empty if
+// the parameters do not define it.
+func initialShareNFS(parameters map[string]string) (string, string, error) {
+	mode, err := nfsExportMode(parameters)
+	if err != nil {
+		return "", "", err
+	}
+	policy, err := parseNFSPolicy(parameters)
+	if err != nil {
+		return "", "", err
+	}
+
+	switch {
+	case mode == nfsExportNode:
+		// No access until the volume is published to a node.
+		return mode, "off", nil
+	case !hasNFSPolicy(parameters):
+		return mode, parameters["shareNFS"], nil
+	case len(policy.clients) == 0 && !policy.rootSquash:
+		return "", "", status.Errorf(codes.InvalidArgument, "%s false requires %s", nfsRootSquashKey,
+			nfsClientsKey)
+	default:
+		return mode, policy.shareNFS(), nil
+	}
+}
+
+// Content of the sharenfs property of a file system.
+type nfsExports struct {
+	options []string // Options other than the access lists
+	rw      []string // Clients granted read-write access
+	ro      []string // Clients granted read-only access
+	root    []string // Clients whose root user is not mapped to the anonymous user
+}
+
+// Parses the sharenfs property passed in.
+func parseShareNFS(shareNFS string) *nfsExports {
+	exports := new(nfsExports)
+	for _, option := range strings.Split(shareNFS, ",") {
+		option = strings.TrimSpace(option)
+		name, list, found := strings.Cut(option, "=")
+		switch {
+		case option == "", option == "on", option == "off":
+		case found && name == "rw":
+			exports.rw = append(exports.rw, splitAccessList(list)...)
+		case found && name == "ro":
+			exports.ro = append(exports.ro, splitAccessList(list)...)
+		case found && name == "root":
+			exports.root = append(exports.root, splitAccessList(list)...)
+		default:
+			exports.options = append(exports.options, option)
+		}
+	}
+	return exports
+}
+
+func splitAccessList(list string) []string {
+	var clients []string
+	for _, client := range strings.Split(list, ":") {
+		if client != "" {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+// Returns the sharenfs property. The file system is not shared if no client has access.
+func (e *nfsExports) String() string {
+	if len(e.rw) == 0 && len(e.ro) == 0 {
+		return "off"
+	}
+	options := append([]string{}, e.options...)
+	if len(e.rw) > 0 {
+		options = append(options, "rw="+strings.Join(e.rw, ":"))
+	}
+	if len(e.ro) > 0 {
+		options = append(options, "ro="+strings.Join(e.ro, ":"))
+	}
+	if len(e.root) > 0 {
+		options = append(options, "root="+strings.Join(e.root, ":"))
+	}
+	return strings.Join(options, ",")
+}
+
+// Grants access to the clients passed in, root access if root is true.
+func (e *nfsExports) grant(clients []string, readOnly, root bool) {
+	e.revoke(clients)
+	if readOnly {
+		e.ro = append(e.ro, clients...)
+	} else {
+		e.rw = append(e.rw, clients...)
+	}
+	if root {
+		e.root = append(e.root, clients...)
+	}
+}
+
+// Revokes the access of the clients passed in. Returns true if one of them had access.
+func (e *nfsExports) revoke(clients []string) bool {
+	revoked := false
+	remove := func(list []string) []string {
+		kept := list[:0]
+		for _, client := range list {
+			if containsString(clients, client) {
+				revoked = true
+			} else {
+				kept = append(kept, client)
+			}
+		}
+		return kept
+	}
+	e.rw = remove(e.rw)
+	e.ro = remove(e.ro)
+	e.root = remove(e.root)
+	return revoked
+}
+
+// Returns the NFS clients (@address/32) designating the node passed in. The IPv6
+// addresses are ignored, the colon separating the entries of the access lists.
+func nfsClientsOfNode(ctx context.Context, nodeName string) ([]string, error) {
+	addresses, err := GetNodeAddresses(ctx, nodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	var clients []string
+	for _, address := range addresses {
+		if ip := net.ParseIP(address).To4(); ip != nil {
+			clients = append(clients, "@"+ip.String()+"/32")
+		}
+	}
+	if len(clients) == 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "Node (%s) has no IPv4 address", nodeName)
+	}
+	return clients, nil
+}
+
+// Returns true if the volume is published read-only.
+func isReadOnlyPublish(req *csi.ControllerPublishVolumeRequest) bool {
+	switch req.GetVolumeCapability().GetAccessMode().GetMode() {
+	case csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY,
+		csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY:
+		return true
+	}
+	return req.GetReadonly()
+}
+
+// Grants the node passed in access to the file system, as defined by the policy passed in.
+func (fs *zFilesystem) exportToNode(ctx context.Context, token *zfssarest.Token, nodeName string,
+	readOnly bool, policy *nfsPolicy) error {
+
+	clients, err := nfsClientsOfNode(ctx, nodeName)
+	if err != nil {
+		return err
+	}
+
+	fsinfo, _, err := fs.client.GetFilesystem(ctx, token, fs.id.Pool, fs.id.Project, fs.id.Name)
+	if err != nil {
+		return err
+	}
+	nodes, err := parseNFSNodes(fsinfo.NfsNodes)
+	if err != nil {
+		return err
+	}
+	exports := parseShareNFS(fsinfo.ShareNFS)
+	if len(exports.options) == 0 {
+		exports.options = policy.options()
+	}
+	// The addresses of the node may have changed since it was last granted access.
+	exports.revoke(nodes[nodeName])
+	exports.grant(clients, readOnly || policy.readOnly, !policy.rootSquash)
+	nodes[nodeName] = clients
+
+	return fs.setNFSExport(ctx, token, fsinfo, exports.String(), nodes)
+}
+
+// Revokes the access of the node passed in to the file system.
+func (fs *zFilesystem) unexportFromNode(ctx context.Context, token *zfssarest.Token, nodeName string) error {
+
+	fsinfo, _, err := fs.client.GetFilesystem(ctx, token, fs.id.Pool, fs.id.Project, fs.id.Name)
+	if err != nil {
+		return err
+	}
+	if fsinfo.NfsExport != nfsExportNode {
+		// Not exported per node, the access of the node is left as configured.
+		return nil
+	}
+	nodes, err := parseNFSNodes(fsinfo.NfsNodes)
+	if err != nil {
+		return err
+	}
+	exports := parseShareNFS(fsinfo.ShareNFS)
+
+	clients, recorded := nodes[nodeName]
+	switch {
+	case recorded:
+	case len(nodes) > 0 || (len(exports.rw) == 0 && len(exports.ro) == 0):
+		// The node has no access to the file system.
+		return nil
+	default:
+		// File system exported before the clients were recorded per node, the clients
+		// of the node are derived from its current addresses.
+		clients, err = nfsClientsOfNode(ctx, nodeName)
+		if err != nil {
+			return status.Errorf(codes.Unavailable, "access of node %s to %s could not be revoked: %v",
+				nodeName, fs.id.String(), err)
+		}
+	}
+	exports.revoke(clients)
+	delete(nodes, nodeName)
+
+	return fs.setNFSExport(ctx, token, fsinfo, exports.String(), nodes)
+}
+
+// Returns the clients granted access per node, as recorded in the property passed in.
+func parseNFSNodes(property string) (map[string][]string, error) {
+	nodes := make(map[string][]string)
+	if property == "" {
+		return nodes, nil
+	}
+	if err := json.Unmarshal([]byte(property), &nodes); err != nil {
+		return nil, status.Errorf(codes.Internal, "invalid %s property (%s): %v", zfssarest.NfsNodes,
+			property, err)
+	}
+	return nodes, nil
+}
+
+// Makes sure the export mode of a file system exported per node can be recorded on the
+// appliance. Without it, the access of the nodes could not be revoked.
+func ensureNFSExportSchema(ctx context.Context, client *zfssarest.Client, token *zfssarest.Token) error {
+	if err := ensureOwnerSchema(ctx, client, token); err != nil {
+		return status.Errorf(codes.Internal, "property %s could not be added to the schema of %s: %v",
+			zfssarest.NfsExport, token.Name, err)
+	}
+	return nil
+}
+
+// Updates the sharenfs property of the file system and the clients recorded per node if
+// they changed.
+func (fs *zFilesystem) setNFSExport(ctx context.Context, token *zfssarest.Token, fsinfo *zfssarest.Filesystem,
+	shareNFS string, nodes map[string][]string) error {
+
+	property := ""
+	if len(nodes) > 0 {
+		data, err := json.Marshal(nodes)
+		if err != nil {
+			return status.Errorf(codes.Internal, "clients of the nodes could not be recorded: %v", err)
+		}
+		property = string(data)
+	}
+	if shareNFS == fsinfo.ShareNFS && property == fsinfo.NfsNodes {
+		return nil
+	}
+
+	utils.GetLogCTRL(ctx, 5).Println("Updating NFS export", "volume", fs.id.String(), "sharenfs", shareNFS,
+		"nodes", property)
+	_, _, err := fs.client.ModifyFilesystem(ctx, token, fs.href, &map[string]interface{}{
+		"sharenfs":                     shareNFS,
+		"custom:" + zfssarest.NfsNodes: property,
+	})
+	return err
+}
+
+// NFS export policy described by the parameters of a StorageClass.
+type nfsPolicy struct {
+	clients    []string // Access list entries of the clients granted access
+	readOnly   bool     // Access granted read-only
+	rootSquash bool     // Root user of the clients mapped to the anonymous user
+	anonUID    string   // UID of the anonymous user, default of the appliance if empty
+	security   string   // Security flavors
+}
+
+// Returns true if one of the parameters passed in describes the NFS export policy.
+func hasNFSPolicy(parameters map[string]string) bool {
+	for _, key := range nfsPolicyKeys {
+		if _, ok := parameters[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Parses and validates the NFS export policy described by the parameters passed in.
+func parseNFSPolicy(parameters map[string]string) (*nfsPolicy, error) {
+	policy := &nfsPolicy{rootSquash: true, security: "sys"}
+
+	separator := func(r rune) bool { return r == ',' || unicode.IsSpace(r) }
+	for _, client := range strings.FieldsFunc(parameters[nfsClientsKey], separator) {
+		entry, err := nfsAccessEntry(client)
+		if err != nil {
+			return nil, err
+		}
+		if !containsString(policy.clients, entry) {
+			policy.clients = append(policy.clients, entry)
+		}
+	}
+
+	switch access := parameters[nfsAccessKey]; access {
+	case "", "rw":
+	case "ro":
+		policy.readOnly = true
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid %s (%s), rw or ro expected", nfsAccessKey,
+			access)
+	}
+
+	if value, ok := parameters[nfsRootSquashKey]; ok {
+		rootSquash, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid %s (%s)", nfsRootSquashKey, value)
+		}
+		policy.rootSquash = rootSquash
+	}
+
+	if value := parameters[nfsAnonUIDKey]; value != "" {
+		uid, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || uid < -1 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid %s (%s)", nfsAnonUIDKey, value)
+		}
+		policy.anonUID = strconv.FormatInt(uid, 10)
+	}
+
+	if value := parameters[nfsSecurityKey]; value != "" {
+		for _, flavor := range strings.Split(value, ":") {
+			if !containsString(nfsSecurityFlavors, flavor) {
+				return nil, status.Errorf(codes.InvalidArgument, "invalid %s (%s), %v expected",
+					nfsSecurityKey, value, nfsSecurityFlavors)
+			}
+		}
+		policy.security = value
+	}
+
+	return policy, nil
+}
+
+// Returns the access list entry of the client passed in: @address/prefix for an IPv4
+// address or network, the name itself for a netgroup.
+func nfsAccessEntry(client string) (string, error) {
+	if ip := net.ParseIP(client); ip != nil {
+		if ip.To4() == nil {
+			return "", status.Errorf(codes.InvalidArgument, "IPv6 address (%s) not supported in %s", client,
+				nfsClientsKey)
+		}
+		return "@" + ip.String() + "/32", nil
+	}
+	if ip, network, err := net.ParseCIDR(client); err == nil {
+		if ip.To4() == nil {
+			return "", status.Errorf(codes.InvalidArgument, "IPv6 network (%s) not supported in %s", client,
+				nfsClientsKey)
+		}
+		return "@" + network.String(), nil
+	}
+	if netgroupRegexp.MatchString(client) {
+		return client, nil
+	}
+	return "", status.Errorf(codes.InvalidArgument, "invalid client (%s) in %s", client, nfsClientsKey)
+}
+
+// Returns the options of the sharenfs property other than the access lists.
+func (p *nfsPolicy) options() []string {
+	options := []string{"sec=" + p.security}
+	if p.anonUID != "" {
+		options = append(options, "anon="+p.anonUID)
+	}
+	return options
+}
+
+// Returns the sharenfs property of a file system exported statically.
+func (p *nfsPolicy) shareNFS() string {
+	exports := &nfsExports{options: p.options()}
+	if len(p.clients) == 0 {
+		// Access granted to all the clients.
+		access := "rw"
+		if p.readOnly {
+			access = "ro"
+		}
+		return strings.Join(append(exports.options, access), ",")
+	}
+	exports.grant(p.clients, p.readOnly, !p.rootSquash)
+	return exports.String()
+}
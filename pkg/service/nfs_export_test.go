@@ -0,0 +1,301 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestShareNFS(t *testing.T) {
+	exports := parseShareNFS("sec=sys,rw=@10.0.0.1/32:@10.0.0.2/32,ro=@10.0.0.3/32")
+	exports.grant([]string{"@10.0.0.2/32"}, true, false)
+	exports.grant([]string{"@10.0.0.4/32"}, false, true)
+	if got := exports.String(); got !=
+		"sec=sys,rw=@10.0.0.1/32:@10.0.0.4/32,ro=@10.0.0.3/32:@10.0.0.2/32,root=@10.0.0.4/32" {
+		t.Errorf("unexpected sharenfs after grant: %s", got)
+	}
+	if exports.revoke([]string{"@10.0.0.9/32"}) {
+		t.Errorf("revoke of a client without access reported a change")
+	}
+	exports.revoke([]string{"@10.0.0.1/32", "@10.0.0.4/32", "@10.0.0.3/32", "@10.0.0.2/32"})
+	if got := exports.String(); got != "off" {
+		t.Errorf("unexpected sharenfs without client: %s", got)
+	}
+
+	for _, tt := range []struct {
+		parameters map[string]string
+		mode       string
+		code       codes.Code
+	}{
+		{map[string]string{}, nfsExportNode, codes.OK},
+		{map[string]string{"shareNFS": "on"}, nfsExportStatic, codes.OK},
+		{map[string]string{nfsExportKey: "static"}, nfsExportStatic, codes.OK},
+		{map[string]string{nfsClientsKey: "10.0.0.0/24"}, nfsExportStatic, codes.OK},
+		{map[string]string{nfsSecurityKey: "krb5"}, nfsExportNode, codes.OK},
+		{map[string]string{nfsExportKey: "node", nfsClientsKey: "10.0.0.0/24"}, "", codes.InvalidArgument},
+		{map[string]string{"shareNFS": "on", nfsAccessKey: "ro"}, "", codes.InvalidArgument},
+		{map[string]string{nfsExportKey: "node", "shareNFS": "on"}, "", codes.InvalidArgument},
+		{map[string]string{nfsExportKey: "all"}, "", codes.InvalidArgument},
+	} {
+		mode, err := nfsExportMode(tt.parameters)
+		if mode != tt.mode || status.Code(err) != tt.code {
+			t.Errorf("%v: unexpected mode %q, %v", tt.parameters, mode, err)
+		}
+	}
+}
+
+func TestNFSPolicy(t *testing.T) {
+	for _, tt := range []struct {
+		parameters map[string]string
+		shareNFS   string
+	}{
+		{map[string]string{nfsClientsKey: "10.0.0.0/24, eng,192.0.2.7"},
+			"sec=sys,rw=@10.0.0.0/24:eng:@192.0.2.7/32"},
+		{map[string]string{nfsClientsKey: "10.0.0.5/24", nfsAccessKey: "ro", nfsRootSquashKey: "false",
+			nfsSecurityKey: "krb5:krb5p"}, "sec=krb5:krb5p,ro=@10.0.0.0/24,root=@10.0.0.0/24"},
+		{map[string]string{nfsExportKey: "static", nfsAnonUIDKey: "-1", nfsAccessKey: "ro"},
+			"sec=sys,anon=-1,ro"},
+		{map[string]string{nfsSecurityKey: "krb5i"}, "off"},
+		{map[string]string{nfsExportKey: "static"}, ""},
+	} {
+		_, shareNFS, err := initialShareNFS(tt.parameters)
+		if err != nil || shareNFS != tt.shareNFS {
+			t.Errorf("%v: unexpected sharenfs %q, %v", tt.parameters, shareNFS, err)
+		}
+	}
+
+	for _, parameters := range []map[string]string{
+		{nfsClientsKey: "10.0.0.0/33"},
+		{nfsClientsKey: "fd00::/64"},
+		{nfsClientsKey: "eng;rm"},
+		{nfsAccessKey: "rwx"},
+		{nfsRootSquashKey: "maybe"},
+		{nfsAnonUIDKey: "nobody"},
+		{nfsAnonUIDKey: "-2"},
+		{nfsSecurityKey: "krb5:dh"},
+		{nfsExportKey: "static", nfsRootSquashKey: "false"},
+	} {
+		_, _, err := initialShareNFS(parameters)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%v: InvalidArgument expected, got %v", parameters, err)
+		}
+	}
+}
+
+func TestNodeExport(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+	ctx := testContext()
+
+	node := func(name string, addresses ...corev1.NodeAddress) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.NodeStatus{Addresses: addresses}}
+	}
+	clientset = fake.NewSimpleClientset(
+		node("node1", corev1.NodeAddress{Type: corev1.NodeHostName, Address: "node1"},
+			corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "10.0.0.11"}),
+		node("node2", corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: "fd00::12"},
+			corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: "192.0.2.12"}))
+	t.Cleanup(func() { clientset = nil })
+
+	shareNFS := func(name string) string {
+		fs, _ := zfssa.Lookup("/api/storage/v2/pools/" + testPool + "/projects/" + testProject + "/filesystems/" + name)
+		return toStringValue(fs["sharenfs"])
+	}
+	publish := func(vol *csi.Volume, nodeID string, mode csi.VolumeCapability_AccessMode_Mode) {
+		t.Helper()
+		_, err := zd.ControllerPublishVolume(ctx, &csi.ControllerPublishVolumeRequest{
+			VolumeId: vol.GetVolumeId(),
+			NodeId:   nodeID,
+			VolumeCapability: &csi.VolumeCapability{
+				AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+				AccessMode: &csi.VolumeCapability_AccessMode{Mode: mode},
+			},
+			VolumeContext: vol.GetVolumeContext(),
+		})
+		if err != nil {
+			t.Fatalf("ControllerPublishVolume to %s failed: %v", nodeID, err)
+		}
+	}
+	unpublish := func(vol *csi.Volume, nodeID string) {
+		t.Helper()
+		_, err := zd.ControllerUnpublishVolume(ctx, &csi.ControllerUnpublishVolumeRequest{
+			VolumeId: vol.GetVolumeId(),
+			NodeId:   nodeID,
+		})
+		if err != nil {
+			t.Fatalf("ControllerUnpublishVolume from %s failed: %v", nodeID, err)
+		}
+	}
+
+	rsp, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-node",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+		VolumeCapabilities: mountCapabilities(),
+		Parameters:         filesystemParameters(),
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume failed: %v", err)
+	}
+	vol := rsp.GetVolume()
+	if got := shareNFS("pvc-node"); got != "off" {
+		t.Errorf("file system shared before being published: %s", got)
+	}
+	fs, _ := zfssa.Lookup("/api/storage/v2/pools/" + testPool + "/projects/" + testProject + "/filesystems/pvc-node")
+	if fs["custom:zcsiNfsExport"] != nfsExportNode {
+		t.Errorf("export mode not recorded: %v", fs["custom:zcsiNfsExport"])
+	}
+
+	publish(vol, "node1", csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER)
+	publish(vol, "node2", csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY)
+	if got := shareNFS("pvc-node"); got != "sec=sys,rw=@10.0.0.11/32,ro=@192.0.2.12/32" {
+		t.Errorf("unexpected sharenfs once published: %s", got)
+	}
+	unpublish(vol, "node1")
+	if got := shareNFS("pvc-node"); got != "sec=sys,ro=@192.0.2.12/32" {
+		t.Errorf("unexpected sharenfs once unpublished from node1: %s", got)
+	}
+	unpublish(vol, "node2")
+	if got := shareNFS("pvc-node"); got != "off" {
+		t.Errorf("unexpected sharenfs once unpublished: %s", got)
+	}
+
+	// The policy parameters apply to the export per node.
+	parameters := filesystemParameters()
+	parameters[nfsSecurityKey] = "krb5"
+	parameters[nfsRootSquashKey] = "false"
+	rsp, err = zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-policy",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+		VolumeCapabilities: mountCapabilities(),
+		Parameters:         parameters,
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume failed: %v", err)
+	}
+	publish(rsp.GetVolume(), "node1", csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER)
+	if got := shareNFS("pvc-policy"); got != "sec=krb5,rw=@10.0.0.11/32,root=@10.0.0.11/32" {
+		t.Errorf("unexpected sharenfs once published: %s", got)
+	}
+	unpublish(rsp.GetVolume(), "node1")
+	if got := shareNFS("pvc-policy"); got != "off" {
+		t.Errorf("unexpected sharenfs once unpublished: %s", got)
+	}
+
+	// A file system shared through shareNFS is left as is.
+	parameters = filesystemParameters()
+	parameters["shareNFS"] = "on"
+	rsp, err = zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-static",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+		VolumeCapabilities: mountCapabilities(),
+		Parameters:         parameters,
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume failed: %v", err)
+	}
+	publish(rsp.GetVolume(), "node1", csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER)
+	unpublish(rsp.GetVolume(), "node1")
+	if got := shareNFS("pvc-static"); got != "on" {
+		t.Errorf("sharenfs of a static export modified: %s", got)
+	}
+
+	parameters = filesystemParameters()
+	parameters[nfsClientsKey] = "10.0.0.0/24"
+	parameters[nfsAccessKey] = "ro"
+	_, err = zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-clients",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+		VolumeCapabilities: mountCapabilities(),
+		Parameters:         parameters,
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume failed: %v", err)
+	}
+	if got := shareNFS("pvc-clients"); got != "sec=sys,ro=@10.0.0.0/24" {
+		t.Errorf("unexpected sharenfs of the policy: %s", got)
+	}
+
+	// The access of a node listed in a static export is not revoked.
+	for name, parameter := range map[string][2]string{
+		"pvc-static-node":  {"shareNFS", "sec=sys,rw=@10.0.0.11/32:@192.0.2.12/32"},
+		"pvc-clients-node": {nfsClientsKey, "10.0.0.11,192.0.2.12"},
+	} {
+		parameters = filesystemParameters()
+		parameters[parameter[0]] = parameter[1]
+		rsp, err = zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+			Name:               name,
+			CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+			VolumeCapabilities: mountCapabilities(),
+			Parameters:         parameters,
+		})
+		if err != nil {
+			t.Fatalf("CreateVolume failed: %v", err)
+		}
+		expected := shareNFS(name)
+		publish(rsp.GetVolume(), "node1", csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER)
+		unpublish(rsp.GetVolume(), "node1")
+		if got := shareNFS(name); got != expected {
+			t.Errorf("sharenfs of the static export %s modified: %s, %s expected", name, got, expected)
+		}
+	}
+
+	// The clients granted access are revoked even if the address of the node changed or
+	// if the node was deleted in the meantime.
+	publish(vol, "node1", csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER)
+	publish(vol, "node2", csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER)
+	nodes := clientset.CoreV1().Nodes()
+	_, err = nodes.Update(ctx, node("node1", corev1.NodeAddress{Type: corev1.NodeInternalIP,
+		Address: "10.0.0.21"}), metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("Update of node1 failed: %v", err)
+	}
+	if err := nodes.Delete(ctx, "node2", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Delete of node2 failed: %v", err)
+	}
+	unpublish(vol, "node1")
+	if got := shareNFS("pvc-node"); got != "sec=sys,rw=@192.0.2.12/32" {
+		t.Errorf("unexpected sharenfs once unpublished from node1 with a new address: %s", got)
+	}
+	unpublish(vol, "node2")
+	if got := shareNFS("pvc-node"); got != "off" {
+		t.Errorf("unexpected sharenfs once unpublished from the deleted node2: %s", got)
+	}
+	fs, _ = zfssa.Lookup("/api/storage/v2/pools/" + testPool + "/projects/" + testProject + "/filesystems/pvc-node")
+	if fs["custom:zcsiNfsNodes"] != "" {
+		t.Errorf("clients of the nodes still recorded: %v", fs["custom:zcsiNfsNodes"])
+	}
+
+	// Without the clients recorded, the access of a deleted node can't be revoked and the
+	// unpublish request fails to be retried.
+	client, token, err := zd.lookUpToken(ctx, zfssa.Name(), nil)
+	if err != nil {
+		t.Fatalf("lookUpToken failed: %v", err)
+	}
+	_, _, err = client.ModifyFilesystem(ctx, token, toStringValue(fs["href"]),
+		&map[string]interface{}{"sharenfs": "sec=sys,rw=@192.0.2.12/32"})
+	if err != nil {
+		t.Fatalf("ModifyFilesystem failed: %v", err)
+	}
+	_, err = zd.ControllerUnpublishVolume(ctx, &csi.ControllerUnpublishVolumeRequest{
+		VolumeId: vol.GetVolumeId(),
+		NodeId:   "node2",
+	})
+	if err == nil || status.Code(err) == codes.NotFound {
+		t.Errorf("unpublish from a deleted node not recorded succeeded: %v", err)
+	}
+}
+
+func toStringValue(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
@@ -6,21 +6,25 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-csi/csi-lib-utils/protosanitizer"
 	"github.com/oracle/zfssa-csi-driver/pkg/utils"
-	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"os"
+	"syscall"
 )
 
 var (
 	// nodeCaps represents the capability of node service.
 	nodeCaps = []csi.NodeServiceCapability_RPC_Type{
 		csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME,
-		//		csi.NodeServiceCapability_RPC_EXPAND_VOLUME,
+		csi.NodeServiceCapability_RPC_EXPAND_VOLUME,
+		csi.NodeServiceCapability_RPC_GET_VOLUME_STATS,
+		csi.NodeServiceCapability_RPC_VOLUME_CONDITION,
 		csi.NodeServiceCapability_RPC_UNKNOWN,
 	}
 )
@@ -124,7 +128,7 @@ func (zd *ZFSSADriver) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnsta
 func (zd *ZFSSADriver) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolumeRequest) (
 	*csi.NodePublishVolumeResponse, error) {
 
-	utils.GetLogNODE(ctx, 5).Println("NodePublishVolume", "request", req)
+	utils.GetLogNODE(ctx, 5).Println("NodePublishVolume", "request", protosanitizer.StripSecrets(req))
 
 	VolumeID := req.GetVolumeId()
 	if len(VolumeID) == 0 {
@@ -161,11 +165,10 @@ func (zd *ZFSSADriver) NodePublishVolume(ctx context.Context, req *csi.NodePubli
 	}
 
 	// The account to be used for this operation is determined.
-	user, password, err := zd.getUserLogin(ctx, req.Secrets)
+	client, token, err := zd.lookUpToken(ctx, zVolumeId.Zfssa, req.Secrets)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+		return nil, err
 	}
-	token := zfssarest.LookUpToken(ctx, user, password)
 
 	var mountOptions []string
 	if req.GetReadonly() {
@@ -174,13 +177,13 @@ func (zd *ZFSSADriver) NodePublishVolume(ctx context.Context, req *csi.NodePubli
 
 	if req.GetVolumeCapability().GetBlock() != nil {
 		mountOptions = append(mountOptions, "bind")
-		return zd.nodePublishBlockVolume(ctx, token, req, zVolumeId, mountOptions)
+		return zd.nodePublishBlockVolume(ctx, client, token, req, zVolumeId, mountOptions)
 	}
 
 	switch mode := volCap.GetAccessType().(type) {
 	case *csi.VolumeCapability_Block:
 		mountOptions = append(mountOptions, "bind")
-		return zd.nodePublishBlockVolume(ctx, token, req, zVolumeId, mountOptions)
+		return zd.nodePublishBlockVolume(ctx, client, token, req, zVolumeId, mountOptions)
 	case *csi.VolumeCapability_Mount:
 		return zd.nodePublishFileSystem(ctx, token, req, zVolumeId, mountOptions, mode)
 	default:
@@ -213,11 +216,10 @@ func (zd *ZFSSADriver) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnp
 		return nil, err
 	}
 
-	user, password, err := zd.getUserLogin(ctx, nil)
+	_, token, err := zd.lookUpToken(ctx, zVolumeId.Zfssa, nil)
 	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+		return nil, err
 	}
-	token := zfssarest.LookUpToken(ctx, user, password)
 	if zVolumeId.IsBlock() {
 		return zd.nodeUnpublishBlockVolume(ctx, token, req, zVolumeId)
 	} else {
@@ -230,7 +232,46 @@ func (zd *ZFSSADriver) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetV
 
 	utils.GetLogNODE(ctx, 5).Println("NodeGetVolumeStats", "request", req)
 
-	return nil, status.Error(codes.Unimplemented, "")
+	volumeID := req.GetVolumeId()
+	if len(volumeID) == 0 {
+		utils.GetLogNODE(ctx, 2).Println("VolumeID not provided, will return")
+		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
+	}
+
+	volumePath := req.GetVolumePath()
+	if len(volumePath) == 0 {
+		utils.GetLogNODE(ctx, 2).Println("Volume path not provided, will return")
+		return nil, status.Error(codes.InvalidArgument, "Volume path not provided")
+	}
+
+	if _, err := utils.VolumeIdFromString(volumeID); err != nil {
+		return nil, status.Errorf(codes.NotFound, "Volume (%s) not found", volumeID)
+	}
+
+	info, err := os.Stat(volumePath)
+	switch {
+	case errors.Is(err, syscall.ESTALE):
+		utils.GetLogNODE(ctx, 2).Println("Stale file handle", "volume_id", volumeID, "path", volumePath)
+		return abnormalVolumeStats("stale NFS file handle"), nil
+	case os.IsNotExist(err):
+		return nil, status.Errorf(codes.NotFound, "volume path %s not found", volumePath)
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if !info.IsDir() {
+		return blockStats(volumePath), nil
+	}
+
+	rsp, err := filesystemStats(volumePath)
+	if errors.Is(err, syscall.ESTALE) {
+		utils.GetLogNODE(ctx, 2).Println("Stale file handle", "volume_id", volumeID, "path", volumePath)
+		return abnormalVolumeStats("stale NFS file handle"), nil
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "statistics of %s unavailable: %v", volumePath, err)
+	}
+	return rsp, nil
 }
 
 func (zd *ZFSSADriver) NodeExpandVolume(ctx context.Context, req *csi.NodeExpandVolumeRequest) (
@@ -238,7 +279,28 @@ func (zd *ZFSSADriver) NodeExpandVolume(ctx context.Context, req *csi.NodeExpand
 
 	utils.GetLogNODE(ctx, 5).Println("NodeExpandVolume", "request", req)
 
-	return nil, status.Error(codes.Unimplemented, "")
+	volumeID := req.GetVolumeId()
+	if len(volumeID) == 0 {
+		utils.GetLogNODE(ctx, 2).Println("VolumeID not provided, will return")
+		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
+	}
+
+	if len(req.GetVolumePath()) == 0 {
+		utils.GetLogNODE(ctx, 2).Println("Volume path not provided, will return")
+		return nil, status.Error(codes.InvalidArgument, "Volume path not provided")
+	}
+
+	vid, err := utils.VolumeIdFromString(volumeID)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "Volume (%s) not found", volumeID)
+	}
+
+	// The file systems are expanded by the appliance only.
+	if vid.Type != utils.BlockVolume {
+		return &csi.NodeExpandVolumeResponse{}, nil
+	}
+
+	return zd.nodeExpandBlockVolume(ctx, req)
 }
 
 func (zd *ZFSSADriver) NodeGetCapabilities(ctx context.Context, req *csi.NodeGetCapabilitiesRequest) (
@@ -266,6 +328,8 @@ func (zd *ZFSSADriver) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoRequ
 
 	utils.GetLogNODE(ctx, 2).Println("NodeGetInfo", "request", req)
 
+	zd.publishInitiators(ctx)
+
 	return &csi.NodeGetInfoResponse{
 		NodeId: zd.config.NodeName,
 	}, nil
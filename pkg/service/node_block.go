@@ -68,14 +68,14 @@ func (zd *ZFSSADriver) NodeUnstageBlockVolume(ctx context.Context, req *csi.Node
 
 // nodePublishBlockVolume is the worker for block volumes only, it is going to get the
 // block device mounted to the target path so it can be moved to the container requesting it
-func (zd *ZFSSADriver) nodePublishBlockVolume(ctx context.Context, token *zfssarest.Token,
-	req *csi.NodePublishVolumeRequest, vid *utils.VolumeId, mountOptions []string) (
+func (zd *ZFSSADriver) nodePublishBlockVolume(ctx context.Context, client *zfssarest.Client,
+	token *zfssarest.Token, req *csi.NodePublishVolumeRequest, vid *utils.VolumeId, mountOptions []string) (
 	*csi.NodePublishVolumeResponse, error) {
 
 	target := req.GetTargetPath()
 
 	utils.GetLogNODE(ctx, 5).Println("nodePublishBlockVolume", req)
-	devicePath, err := attachBlockVolume(ctx, token, req, vid)
+	devicePath, err := attachBlockVolume(ctx, client, token, req, vid)
 	if err != nil {
 		return nil, err
 	}
@@ -126,30 +126,49 @@ func (zd *ZFSSADriver) nodePublishBlockVolume(ctx context.Context, token *zfssar
 	return &csi.NodePublishVolumeResponse{}, nil
 }
 
-// attachBlockVolume rescans the iSCSI session and attempts to attach the disk.
+// attachBlockVolume rescans the iSCSI session (or the Fibre Channel ports) and attempts
+// to attach the disk.
 // This may actually belong in ControllerPublish (and was there for a while), but
 // if it goes in the controller, then we have to have a way to remote the request
 // to the proper node since the controller may not co-exist with the node where
 // the device is actually needed for the work to be done
-func attachBlockVolume(ctx context.Context, token *zfssarest.Token, req *csi.NodePublishVolumeRequest,
-	vid *utils.VolumeId) (string, error) {
+func attachBlockVolume(ctx context.Context, client *zfssarest.Client, token *zfssarest.Token,
+	req *csi.NodePublishVolumeRequest, vid *utils.VolumeId) (string, error) {
 
 	lun := vid.Name
 	pool := vid.Pool
 	project := vid.Project
 
-	lunInfo, _, err := zfssarest.GetLun(nil, token, pool, project, lun)
+	lunInfo, _, err := client.GetLun(ctx, token, pool, project, lun)
+	if err != nil {
+		return "", err
+	}
+
+	protocol, err := blockProtocol(req.GetVolumeContext())
 	if err != nil {
 		return "", err
 	}
 
 	targetGroup := lunInfo.TargetGroup
-	targetInfo, err := zfssarest.GetTargetGroup(nil, token, "iscsi", targetGroup)
+	targetInfo, err := client.GetTargetGroup(ctx, token, protocol, targetGroup)
 	if err != nil {
 		return "", err
 	}
 
-	iscsiInfo, err := GetISCSIInfo(ctx, vid, req, targetInfo.Targets[0], lunInfo.AssignedNumber[0])
+	if len(targetInfo.Targets) == 0 {
+		return "", status.Errorf(codes.FailedPrecondition, "target group %s has no target", targetGroup)
+	}
+
+	if protocol == protocolFC {
+		return attachFCVolume(ctx, targetInfo.Targets, lunInfo.AssignedNumber[0])
+	}
+
+	targets, err := getISCSITargets(ctx, client, token, req.GetVolumeContext(), targetInfo.Targets)
+	if err != nil {
+		return "", err
+	}
+
+	iscsiInfo, err := GetISCSIInfo(ctx, vid, req, targets, lunInfo.AssignedNumber[0])
 	if err != nil {
 		return "", status.Error(codes.Internal, err.Error())
 	}
@@ -159,20 +178,21 @@ func attachBlockVolume(ctx context.Context, token *zfssarest.Token, req *csi.Nod
 	mountOptions := req.GetVolumeCapability().GetMount().GetMountFlags()
 	diskMounter := GetISCSIDiskMounter(iscsiInfo, false, fsType, mountOptions, "")
 
-	utils.GetLogNODE(ctx, 5).Println("iSCSI Connector", "TargetPortals", diskMounter.connector.TargetPortals,
-		"Lun", diskMounter.connector.Lun, "TargetIqn", diskMounter.connector.TargetIqn,
-		"VolumeName", diskMounter.connector.VolumeName)
+	for _, connector := range diskMounter.connectors {
+		utils.GetLogNODE(ctx, 5).Println("iSCSI Connector", "TargetPortals", connector.TargetPortals,
+			"Lun", connector.Lun, "TargetIqn", connector.TargetIqn, "VolumeName", connector.VolumeName)
+	}
 
 	util := &ISCSIUtil{}
 
 	utils.GetLogNODE(ctx, 5).Println("attachBlockVolume: connecting disk", "diskMounter", diskMounter)
 	devicePath, err := util.ConnectDisk(ctx, *diskMounter)
 	if err != nil {
-		utils.GetLogNODE(ctx, 5).Println("attachBlockVolume: failed connecting the disk: %s", err.Error())
+		utils.GetLogNODE(ctx, 5).Printf("attachBlockVolume: failed connecting the disk: %s", err.Error())
 		return "", status.Error(codes.Internal, err.Error())
 	}
 
-	utils.GetLogNODE(ctx, 5).Println("attachBlockVolume: attached at: %s", devicePath)
+	utils.GetLogNODE(ctx, 5).Printf("attachBlockVolume: attached at: %s", devicePath)
 	return devicePath, nil
 }
 
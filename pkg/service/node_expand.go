@@ -0,0 +1,240 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"fmt"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/oracle/zfssa-csi-driver/pkg/utils"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Once a LUN has been expanded by the controller, the node has to make the kernel see the
+// new size of the device and, when the LUN holds a file system, grow the file system:
+//
+//   - The device is found from the volume path: the device node itself (raw block volume)
+//     or the file system mounted on it. For a multipath device, its paths are used.
+//   - The iSCSI sessions are rescanned, then each SCSI device of the LUN is rescanned
+//     (/sys/block/sdX/device/rescan) and the multipath map is resized.
+//   - The size of the device (/sys/block/X/size) is read until it reaches the size
+//     required.
+//   - An ext2/ext3/ext4 file system is grown with resize2fs, an XFS file system with
+//     xfs_growfs.
+
+// Time given to the SCSI layer to report the new size of a device after a rescan.
+var (
+	expandAttempts = 10
+	expandInterval = time.Second
+)
+
+// Runs a command of the node and returns its combined output.
+var runCommand = func(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).CombinedOutput()
+}
+
+// Rescans the iSCSI sessions of the node.
+var rescanISCSISessions = func(ctx context.Context) error {
+	_, err := (&ISCSIUtil{}).Rescan(ctx)
+	return err
+}
+
+// Returns the major and minor numbers of a Linux device number.
+func splitDeviceNumber(dev uint64) (uint64, uint64) {
+	major := (dev>>8)&0xfff | (dev>>32)&^0xfff
+	minor := dev&0xff | (dev>>12)&^0xff
+	return major, minor
+}
+
+// Returns the name of the block device of the volume path passed in and whether the path
+// is a mounted file system (true) or the device node of a raw block volume (false).
+func blockDeviceOf(path string) (string, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", false, err
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", false, fmt.Errorf("device of %s unknown", path)
+	}
+
+	isFilesystem := info.IsDir()
+	dev := uint64(st.Rdev)
+	if isFilesystem {
+		dev = uint64(st.Dev)
+	}
+	major, minor := splitDeviceNumber(dev)
+
+	link, err := os.Readlink(filepath.Join(sysfsRoot, "dev", "block", fmt.Sprintf("%d:%d", major, minor)))
+	if err != nil {
+		return "", false, fmt.Errorf("%s is not on a block device: %v", path, err)
+	}
+	return filepath.Base(link), isFilesystem, nil
+}
+
+// Returns the paths (SCSI devices) of a multipath device, the device itself if it is not
+// a multipath device.
+func devicePaths(device string) []string {
+	slaves, err := os.ReadDir(filepath.Join(sysfsRoot, "block", device, "slaves"))
+	if err != nil || len(slaves) == 0 {
+		return []string{device}
+	}
+	paths := make([]string, 0, len(slaves))
+	for _, slave := range slaves {
+		paths = append(paths, slave.Name())
+	}
+	return paths
+}
+
+// Returns the size in bytes of the block device passed in.
+func blockDeviceSize(device string) (int64, error) {
+	data, err := os.ReadFile(filepath.Join(sysfsRoot, "block", device, "size"))
+	if err != nil {
+		return 0, err
+	}
+	sectors, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return sectors * 512, nil
+}
+
+// Makes the kernel read the size of the device passed in again. The failures are only
+// logged, the size of the device is checked afterwards.
+func rescanBlockDevice(ctx context.Context, device string) {
+	log2 := utils.GetLogNODE(ctx, 2)
+
+	sessions, _ := filepath.Glob(filepath.Join(sysfsRoot, "class", "iscsi_session", "session*"))
+	if len(sessions) > 0 {
+		if err := rescanISCSISessions(ctx); err != nil {
+			log2.Println("iSCSI sessions could not be rescanned", "error", err.Error())
+		}
+	}
+
+	paths := devicePaths(device)
+	for _, path := range paths {
+		rescan := filepath.Join(sysfsRoot, "block", path, "device", "rescan")
+		if err := os.WriteFile(rescan, []byte("1"), 0200); err != nil {
+			log2.Println("SCSI device could not be rescanned", "device", path, "error", err.Error())
+		}
+	}
+
+	if len(paths) == 1 && paths[0] == device {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(sysfsRoot, "block", device, "dm", "name"))
+	if err != nil {
+		log2.Println("Name of the multipath device unknown", "device", device, "error", err.Error())
+		return
+	}
+	name := strings.TrimSpace(string(data))
+	if output, err := runCommand("multipathd", "resize", "map", name); err != nil {
+		log2.Println("Multipath device could not be resized", "device", name, "error", err.Error(),
+			"output", string(output))
+	}
+}
+
+// Waits until the size of the device passed in is at least the size passed in and
+// returns it.
+func waitBlockDeviceSize(ctx context.Context, device string, required int64) (int64, error) {
+	for attempt := 1; ; attempt++ {
+		size, err := blockDeviceSize(device)
+		if err != nil {
+			return 0, status.Errorf(codes.Internal, "size of device %s unknown: %v", device, err)
+		}
+		if size >= required {
+			return size, nil
+		}
+		if attempt >= expandAttempts {
+			return 0, status.Errorf(codes.Internal, "device %s is %d bytes, %d bytes expected",
+				device, size, required)
+		}
+		select {
+		case <-ctx.Done():
+			return 0, status.FromContextError(ctx.Err()).Err()
+		case <-time.After(expandInterval):
+		}
+	}
+}
+
+// Grows the file system of the type passed in, held by the device and mounted at the path
+// passed in, to the size of the device.
+func growFilesystem(ctx context.Context, fsType, device, mountPath string) error {
+	var output []byte
+	var err error
+	switch fsType {
+	case "ext2", "ext3", "ext4":
+		output, err = runCommand("resize2fs", filepath.Join("/dev", device))
+	case "xfs":
+		output, err = runCommand("xfs_growfs", mountPath)
+	default:
+		return status.Errorf(codes.InvalidArgument, "file system %q cannot be grown", fsType)
+	}
+	if err != nil {
+		return status.Errorf(codes.Internal, "file system on %s could not be grown: %v (%s)",
+			device, err, strings.TrimSpace(string(output)))
+	}
+	utils.GetLogNODE(ctx, 5).Println("File system grown", "device", device, "type", fsType)
+	return nil
+}
+
+// Returns the type of the file system mounted at the path passed in.
+func (zd *ZFSSADriver) mountedFsType(path string) (string, error) {
+	mountPoints, err := zd.NodeMounter.List()
+	if err != nil {
+		return "", err
+	}
+	for _, mountPoint := range mountPoints {
+		if mountPoint.Path == path {
+			return mountPoint.Type, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not mounted", path)
+}
+
+// Brings the size of the device of a LUN published at the volume path of the request to
+// the size of the LUN, and grows its file system if it holds one.
+func (zd *ZFSSADriver) nodeExpandBlockVolume(ctx context.Context, req *csi.NodeExpandVolumeRequest) (
+	*csi.NodeExpandVolumeResponse, error) {
+
+	volumePath := req.GetVolumePath()
+	device, isFilesystem, err := blockDeviceOf(volumePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, status.Errorf(codes.NotFound, "volume path %s not found", volumePath)
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	utils.GetLogNODE(ctx, 5).Println("Expanding block device", "device", device, "path", volumePath)
+
+	rescanBlockDevice(ctx, device)
+	size, err := waitBlockDeviceSize(ctx, device, req.GetCapacityRange().GetRequiredBytes())
+	if err != nil {
+		return nil, err
+	}
+
+	if isFilesystem && req.GetVolumeCapability().GetBlock() == nil {
+		fsType := req.GetVolumeCapability().GetMount().GetFsType()
+		if fsType == "" {
+			if fsType, err = zd.mountedFsType(volumePath); err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+		}
+		if err := growFilesystem(ctx, fsType, device, volumePath); err != nil {
+			return nil, err
+		}
+	}
+
+	return &csi.NodeExpandVolumeResponse{CapacityBytes: size}, nil
+}
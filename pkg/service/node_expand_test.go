@@ -0,0 +1,199 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/oracle/zfssa-csi-driver/pkg/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/utils/mount"
+)
+
+func TestSplitDeviceNumber(t *testing.T) {
+	for _, c := range []struct {
+		dev          uint64
+		major, minor uint64
+	}{
+		{0x0801, 8, 1},
+		{0xfd00, 253, 0},
+		{0x10110370, 259, 0x10170},
+	} {
+		major, minor := splitDeviceNumber(c.dev)
+		if major != c.major || minor != c.minor {
+			t.Errorf("unexpected device number of %#x: %d:%d", c.dev, major, minor)
+		}
+	}
+}
+
+// Sets up a fake sysfs in which the file system of the directory passed in is on the
+// device passed in, and records the commands run.
+func setupExpandTest(t *testing.T, dir, device string) *[]string {
+	t.Helper()
+	sysfsRoot = t.TempDir()
+	attempts, run := expandAttempts, runCommand
+	expandAttempts = 2
+	expandInterval = time.Millisecond
+	commands := new([]string)
+	runCommand = func(name string, args ...string) ([]byte, error) {
+		*commands = append(*commands, strings.Join(append([]string{name}, args...), " "))
+		return nil, nil
+	}
+	t.Cleanup(func() {
+		sysfsRoot = "/sys"
+		expandAttempts = attempts
+		expandInterval = time.Second
+		runCommand = run
+	})
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatalf("cannot stat %s: %v", dir, err)
+	}
+	major, minor := splitDeviceNumber(uint64(info.Sys().(*syscall.Stat_t).Dev))
+	link := filepath.Join(sysfsRoot, "dev", "block", fmt.Sprintf("%d:%d", major, minor))
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		t.Fatalf("cannot create %s: %v", filepath.Dir(link), err)
+	}
+	if err := os.Symlink("../../devices/virtual/block/"+device, link); err != nil {
+		t.Fatalf("cannot create %s: %v", link, err)
+	}
+	return commands
+}
+
+func TestNodeExpandBlockVolume(t *testing.T) {
+	zd, _ := newTestDriver(t)
+	ctx := testContext()
+
+	volumePath := t.TempDir()
+	commands := setupExpandTest(t, volumePath, "sdz")
+	writeSysfsFile(t, "block/sdz/size", "4194304\n")
+	writeSysfsFile(t, "block/sdz/device/rescan", "")
+	zd.NodeMounter = &fakeMounter{mount.NewFakeMounter([]mount.MountPoint{
+		{Device: "/dev/sdz", Path: volumePath, Type: "ext4"},
+	})}
+
+	volumeId := utils.NewVolumeId(utils.BlockVolume, "zfssa", testPool, testProject, "pvc-lun").String()
+	req := &csi.NodeExpandVolumeRequest{
+		VolumeId:         volumeId,
+		VolumePath:       volumePath,
+		CapacityRange:    &csi.CapacityRange{RequiredBytes: 2 * Gib},
+		VolumeCapability: &csi.VolumeCapability{AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}}},
+	}
+	rsp, err := zd.NodeExpandVolume(ctx, req)
+	if err != nil {
+		t.Fatalf("NodeExpandVolume failed: %v", err)
+	}
+	if rsp.GetCapacityBytes() != 2*Gib {
+		t.Errorf("unexpected capacity %d", rsp.GetCapacityBytes())
+	}
+	if data, _ := os.ReadFile(filepath.Join(sysfsRoot, "block/sdz/device/rescan")); string(data) != "1" {
+		t.Errorf("device not rescanned: %q", data)
+	}
+	if !reflect.DeepEqual(*commands, []string{"resize2fs /dev/sdz"}) {
+		t.Errorf("unexpected commands %v", *commands)
+	}
+
+	// The type of the file system passed in is used.
+	*commands = nil
+	req.VolumeCapability = &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{FsType: "xfs"}},
+	}
+	if _, err = zd.NodeExpandVolume(ctx, req); err != nil {
+		t.Fatalf("NodeExpandVolume failed: %v", err)
+	}
+	if !reflect.DeepEqual(*commands, []string{"xfs_growfs " + volumePath}) {
+		t.Errorf("unexpected commands %v", *commands)
+	}
+
+	// The device does not reach the size required.
+	req.CapacityRange = &csi.CapacityRange{RequiredBytes: 3 * Gib}
+	if _, err = zd.NodeExpandVolume(ctx, req); status.Code(err) != codes.Internal {
+		t.Errorf("NodeExpandVolume of a device too small: Internal expected, got %v", err)
+	}
+
+	// The file systems are expanded by the appliance.
+	req.VolumeId = utils.NewVolumeId(utils.MountVolume, "zfssa", testPool, testProject, "pvc-fs").String()
+	if _, err = zd.NodeExpandVolume(ctx, req); err != nil {
+		t.Errorf("NodeExpandVolume of a file system failed: %v", err)
+	}
+}
+
+func TestNodeExpandMultipathVolume(t *testing.T) {
+	zd, _ := newTestDriver(t)
+	ctx := testContext()
+
+	volumePath := t.TempDir()
+	commands := setupExpandTest(t, volumePath, "dm-2")
+	writeSysfsFile(t, "block/dm-2/size", "6291456\n")
+	writeSysfsFile(t, "block/dm-2/dm/name", "mpatha\n")
+	for _, path := range []string{"sdb", "sdc"} {
+		writeSysfsFile(t, "block/dm-2/slaves/"+path, "")
+		writeSysfsFile(t, "block/"+path+"/device/rescan", "")
+	}
+
+	rsp, err := zd.NodeExpandVolume(ctx, &csi.NodeExpandVolumeRequest{
+		VolumeId:         utils.NewVolumeId(utils.BlockVolume, "zfssa", testPool, testProject, "pvc-lun").String(),
+		VolumePath:       volumePath,
+		CapacityRange:    &csi.CapacityRange{RequiredBytes: 3 * Gib},
+		VolumeCapability: &csi.VolumeCapability{AccessType: &csi.VolumeCapability_Block{Block: &csi.VolumeCapability_BlockVolume{}}},
+	})
+	if err != nil {
+		t.Fatalf("NodeExpandVolume failed: %v", err)
+	}
+	if rsp.GetCapacityBytes() != 3*Gib {
+		t.Errorf("unexpected capacity %d", rsp.GetCapacityBytes())
+	}
+	for _, path := range []string{"sdb", "sdc"} {
+		if data, _ := os.ReadFile(filepath.Join(sysfsRoot, "block", path, "device/rescan")); string(data) != "1" {
+			t.Errorf("path %s not rescanned: %q", path, data)
+		}
+	}
+	if !reflect.DeepEqual(*commands, []string{"multipathd resize map mpatha"}) {
+		t.Errorf("unexpected commands %v", *commands)
+	}
+}
+
+func TestControllerExpandBlockVolume(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+	ctx := testContext()
+
+	parameters := filesystemParameters()
+	parameters["targetGroup"] = "tg0"
+	vol, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-lun",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+		VolumeCapabilities: blockCapabilities(),
+		Parameters:         parameters,
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume failed: %v", err)
+	}
+
+	rsp, err := zd.ControllerExpandVolume(ctx, &csi.ControllerExpandVolumeRequest{
+		VolumeId:      vol.GetVolume().GetVolumeId(),
+		CapacityRange: &csi.CapacityRange{RequiredBytes: 2 * Gib},
+	})
+	if err != nil {
+		t.Fatalf("ControllerExpandVolume failed: %v", err)
+	}
+	if rsp.GetCapacityBytes() != 2*Gib || !rsp.GetNodeExpansionRequired() {
+		t.Errorf("unexpected response %v", rsp)
+	}
+	lun, _ := zfssa.Lookup("/api/storage/v2/pools/p0/projects/k8s/luns/pvc-lun")
+	if fmt.Sprint(lun["volsize"]) != fmt.Sprint(2*Gib) {
+		t.Errorf("unexpected volsize %v", lun["volsize"])
+	}
+}
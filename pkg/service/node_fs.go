@@ -48,9 +48,19 @@ func (zd *ZFSSADriver) nodePublishFileSystem(ctx context.Context, token *zfssare
 	}
 
 	source := fmt.Sprintf("%s:%s", s, ep)
-	utils.GetLogNODE(ctx, 5).Println("nodePublishFileSystem", "mount_point", source)
+	fsType := "nfs"
+	var sensitiveOptions []string
+	if req.GetVolumeContext()["protocol"] == protocolSMB {
+		source = fmt.Sprintf("//%s/%s", s, req.GetVolumeContext()[smbShareKey])
+		fsType = "cifs"
+		sensitiveOptions, err = smbCredentials(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+	utils.GetLogNODE(ctx, 5).Println("nodePublishFileSystem", "mount_point", source, "fstype", fsType)
 
-	err = zd.NodeMounter.Mount(source, targetPath, "nfs", mountOptions)
+	err = zd.NodeMounter.MountSensitive(source, targetPath, fsType, mountOptions, sensitiveOptions)
 	if err != nil {
 		if os.IsPermission(err) {
 			return nil, status.Error(codes.PermissionDenied, err.Error())
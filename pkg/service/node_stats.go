@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"syscall"
+)
+
+// The statistics of a volume are read on the node where it is published:
+//
+//   - For a mounted volume (NFS or SMB share, LUN holding a file system), the bytes and
+//     inodes of the file system are returned by statfs.
+//   - For a raw block volume, the size of its device is returned.
+//
+// A volume whose NFS file handle is stale or whose device is gone is reported with an
+// abnormal condition.
+
+// Returns the statistics of the file system mounted at the path passed in.
+var statfs = syscall.Statfs
+
+// Returns a response reporting the volume as abnormal, with the message passed in.
+func abnormalVolumeStats(message string) *csi.NodeGetVolumeStatsResponse {
+	return &csi.NodeGetVolumeStatsResponse{
+		VolumeCondition: &csi.VolumeCondition{Abnormal: true, Message: message},
+	}
+}
+
+// Returns the usage of the bytes and inodes of the file system mounted at the path
+// passed in.
+func filesystemStats(path string) (*csi.NodeGetVolumeStatsResponse, error) {
+	var fs syscall.Statfs_t
+	if err := statfs(path, &fs); err != nil {
+		return nil, err
+	}
+	blockSize := int64(fs.Bsize)
+	return &csi.NodeGetVolumeStatsResponse{
+		Usage: []*csi.VolumeUsage{
+			{
+				Unit:      csi.VolumeUsage_BYTES,
+				Total:     int64(fs.Blocks) * blockSize,
+				Available: int64(fs.Bavail) * blockSize,
+				Used:      int64(fs.Blocks-fs.Bfree) * blockSize,
+			},
+			{
+				Unit:      csi.VolumeUsage_INODES,
+				Total:     int64(fs.Files),
+				Available: int64(fs.Ffree),
+				Used:      int64(fs.Files - fs.Ffree),
+			},
+		},
+		VolumeCondition: &csi.VolumeCondition{Abnormal: false, Message: "volume is healthy"},
+	}, nil
+}
+
+// Returns the size of the device of the raw block volume published at the path passed in.
+func blockStats(path string) *csi.NodeGetVolumeStatsResponse {
+	device, _, err := blockDeviceOf(path)
+	if err != nil {
+		return abnormalVolumeStats("device of the volume not found: " + err.Error())
+	}
+	size, err := blockDeviceSize(device)
+	if err != nil {
+		return abnormalVolumeStats("device " + device + " not found: " + err.Error())
+	}
+	return &csi.NodeGetVolumeStatsResponse{
+		Usage: []*csi.VolumeUsage{
+			{Unit: csi.VolumeUsage_BYTES, Total: size},
+		},
+		VolumeCondition: &csi.VolumeCondition{Abnormal: false, Message: "volume is healthy"},
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/oracle/zfssa-csi-driver/pkg/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNodeGetFilesystemVolumeStats(t *testing.T) {
+	zd, _ := newTestDriver(t)
+	ctx := testContext()
+
+	req := &csi.NodeGetVolumeStatsRequest{
+		VolumeId:   utils.NewVolumeId(utils.MountVolume, "zfssa", testPool, testProject, "pvc-fs").String(),
+		VolumePath: t.TempDir(),
+	}
+	rsp, err := zd.NodeGetVolumeStats(ctx, req)
+	if err != nil {
+		t.Fatalf("NodeGetVolumeStats failed: %v", err)
+	}
+	if len(rsp.GetUsage()) != 2 || rsp.GetVolumeCondition().GetAbnormal() {
+		t.Fatalf("unexpected statistics %v", rsp)
+	}
+	for _, usage := range rsp.GetUsage() {
+		if usage.GetTotal() <= 0 || usage.GetUsed()+usage.GetAvailable() > usage.GetTotal() {
+			t.Errorf("unexpected usage %v", usage)
+		}
+	}
+
+	// A stale NFS file handle is reported as an abnormal condition.
+	statfs = func(path string, buf *syscall.Statfs_t) error { return syscall.ESTALE }
+	t.Cleanup(func() { statfs = syscall.Statfs })
+	rsp, err = zd.NodeGetVolumeStats(ctx, req)
+	if err != nil || !rsp.GetVolumeCondition().GetAbnormal() {
+		t.Errorf("NodeGetVolumeStats of a stale volume returned %v, %v", rsp, err)
+	}
+
+	req.VolumePath = filepath.Join(req.VolumePath, "missing")
+	if _, err = zd.NodeGetVolumeStats(ctx, req); status.Code(err) != codes.NotFound {
+		t.Errorf("NodeGetVolumeStats of a missing path: NotFound expected, got %v", err)
+	}
+}
+
+func TestNodeGetBlockVolumeStats(t *testing.T) {
+	zd, _ := newTestDriver(t)
+	ctx := testContext()
+	sysfsRoot = t.TempDir()
+	t.Cleanup(func() { sysfsRoot = "/sys" })
+
+	// The device number of a regular file is 0:0.
+	volumePath := filepath.Join(t.TempDir(), "pvc-lun")
+	writeTestFile(t, volumePath, "")
+	req := &csi.NodeGetVolumeStatsRequest{
+		VolumeId:   utils.NewVolumeId(utils.BlockVolume, "zfssa", testPool, testProject, "pvc-lun").String(),
+		VolumePath: volumePath,
+	}
+
+	rsp, err := zd.NodeGetVolumeStats(ctx, req)
+	if err != nil || !rsp.GetVolumeCondition().GetAbnormal() {
+		t.Errorf("NodeGetVolumeStats of a missing device returned %v, %v", rsp, err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(sysfsRoot, "dev", "block"), 0755); err != nil {
+		t.Fatalf("cannot create sysfs: %v", err)
+	}
+	if err := os.Symlink("../../devices/virtual/block/sdq", filepath.Join(sysfsRoot, "dev", "block", "0:0")); err != nil {
+		t.Fatalf("cannot create sysfs link: %v", err)
+	}
+	writeSysfsFile(t, "block/sdq/size", "2097152\n")
+
+	rsp, err = zd.NodeGetVolumeStats(ctx, req)
+	if err != nil {
+		t.Fatalf("NodeGetVolumeStats failed: %v", err)
+	}
+	if len(rsp.GetUsage()) != 1 || rsp.GetUsage()[0].GetTotal() != Gib || rsp.GetVolumeCondition().GetAbnormal() {
+		t.Errorf("unexpected statistics %v", rsp)
+	}
+}
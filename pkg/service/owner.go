@@ -0,0 +1,149 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/oracle/zfssa-csi-driver/pkg/utils"
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sync"
+	"time"
+)
+
+// The file systems and LUNs created by the driver are stamped with custom properties of
+// the schema of the appliance recording their owner: the cluster (ZFSSA_CLUSTER_ID, the
+// UID of the kube-system namespace by default), the PV, the namespace and name of the
+// PVC (passed by the external provisioner when it runs with --extra-create-metadata)
+// and the driver. The properties are added to the schema of the appliances when the
+// driver starts or, if an appliance can't be reached then, before the first share is
+// created on it.
+//
+// A share stamped with the ID of another cluster is not deleted. As long as the ID of the
+// cluster is unknown, no share stamped with an owner is deleted. The shares without
+// owner (created before or outside of the driver) are not protected.
+
+const (
+	pvNameKey  = "csi.storage.k8s.io/pv/name"
+	pvcNameKey = "csi.storage.k8s.io/pvc/name"
+)
+
+// Owner of the shares created by the driver, without the PV and PVC. The ownership is not
+// recorded if the ID of the cluster is unknown.
+var driverOwner zfssarest.Owner
+
+// Appliances (names) whose schema has the owner properties.
+var ownerSchemas sync.Map
+
+// Time given to the API server to return the UID of the kube-system namespace.
+const clusterUIDTimeout = 30 * time.Second
+
+// Determines the owner of the shares created by the driver. Called when the driver
+// starts, the UID of the cluster is requested with a context of its own.
+func (zd *ZFSSADriver) initOwner() {
+	ctx, cancel := context.WithTimeout(utils.GetNewContext(context.Background()), clusterUIDTimeout)
+	defer cancel()
+
+	clusterId := zd.config.ClusterId
+	if clusterId == "" {
+		uid, err := GetClusterUID(ctx)
+		if err != nil {
+			utils.GetLogCSID(ctx, 2).Println("Cluster ID unknown, the owner of the shares is not recorded and no owned share is deleted",
+				"error", err.Error())
+			return
+		}
+		clusterId = uid
+	}
+
+	driverOwner = zfssarest.Owner{
+		ClusterId: clusterId,
+		CreatedBy: zd.name + "/" + zd.version,
+	}
+	utils.GetLogCSID(ctx, 5).Println("Owner of the shares", "cluster", clusterId)
+}
+
+// Adds the owner properties to the schema of the appliances. A failure is only logged,
+// it is retried before the first share is created on the appliance.
+func (zd *ZFSSADriver) initOwnerSchemas(ctx context.Context) {
+	if driverOwner.ClusterId == "" {
+		return
+	}
+
+	for _, name := range zd.getApplianceNames() {
+		client, token, err := zd.lookUpToken(ctx, name, nil)
+		if err == nil {
+			err = ensureOwnerSchema(ctx, client, token)
+		}
+		if err != nil {
+			utils.GetLogCSID(ctx, 2).Println("Owner properties could not be added to the schema",
+				"appliance", name, "error", err.Error())
+		}
+	}
+}
+
+// Makes sure the schema of the appliance has the owner properties and the properties
+// recording the NFS export of the file systems.
+func ensureOwnerSchema(ctx context.Context, client *zfssarest.Client, token *zfssarest.Token) error {
+	if _, ok := ownerSchemas.Load(token.Name); ok {
+		return nil
+	}
+
+	for _, property := range append(zfssarest.OwnerSchema, zfssarest.NfsExportSchema...) {
+		_, err := client.GetProperty(ctx, token, property.Property)
+		if status.Code(err) == codes.NotFound {
+			utils.GetLogCTRL(ctx, 2).Println("Adding property to the schema", "appliance", token.Name,
+				"property", property.Property)
+			_, err = client.CreateProperty(ctx, token, property)
+			if status.Code(err) == codes.AlreadyExists {
+				err = nil
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	ownerSchemas.Store(token.Name, true)
+	return nil
+}
+
+// Returns the owner to stamp on the share created by the request passed in, nil if the
+// ownership is not recorded.
+func shareOwner(ctx context.Context, client *zfssarest.Client, token *zfssarest.Token,
+	req *csi.CreateVolumeRequest) *zfssarest.Owner {
+
+	if driverOwner.ClusterId == "" {
+		return nil
+	}
+	if err := ensureOwnerSchema(ctx, client, token); err != nil {
+		utils.GetLogCTRL(ctx, 2).Println("Owner properties missing from the schema, owner not recorded",
+			"appliance", token.Name, "volume", req.GetName(), "error", err.Error())
+		return nil
+	}
+
+	owner := driverOwner
+	owner.PvName = req.GetParameters()[pvNameKey]
+	owner.PvcNamespace = req.GetParameters()[pvcNamespaceKey]
+	owner.PvcName = req.GetParameters()[pvcNameKey]
+	return &owner
+}
+
+// Returns an error if the share passed in is owned by another cluster, or may be if the
+// ID of the cluster is unknown.
+func checkOwner(vid *utils.VolumeId, owner *zfssarest.Owner) error {
+	if owner.ClusterId == "" || owner.ClusterId == driverOwner.ClusterId {
+		return nil
+	}
+	if driverOwner.ClusterId == "" {
+		return status.Errorf(codes.FailedPrecondition,
+			"Volume (%s) is owned by cluster (%s) and the ID of this cluster is unknown (see ZFSSA_CLUSTER_ID)",
+			vid.String(), owner.ClusterId)
+	}
+	return status.Errorf(codes.FailedPrecondition, "Volume (%s) is owned by cluster (%s)",
+		vid.String(), owner.ClusterId)
+}
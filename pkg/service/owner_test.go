@@ -0,0 +1,176 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+// The owner is determined through a clientset throttled by a rate limiter, as the one of
+// the driver running in a cluster is.
+func TestInitOwnerFromCluster(t *testing.T) {
+	zd, _ := newTestDriver(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/kube-system" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&corev1.Namespace{
+			TypeMeta:   metav1.TypeMeta{Kind: "Namespace", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: types.UID("cluster-c")},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	cs, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL, QPS: 5, Burst: 10})
+	if err != nil {
+		t.Fatalf("NewForConfig failed: %v", err)
+	}
+	clientset = cs
+	t.Cleanup(func() {
+		clientset = nil
+		driverOwner = zfssarest.Owner{}
+	})
+
+	zd.initOwner()
+	if driverOwner.ClusterId != "cluster-c" {
+		t.Errorf("unexpected owner %+v", driverOwner)
+	}
+}
+
+func TestShareOwner(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+	ctx := testContext()
+
+	clientset = fake.NewSimpleClientset(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: types.UID("cluster-a")}})
+	t.Cleanup(func() {
+		clientset = nil
+		driverOwner = zfssarest.Owner{}
+		ownerSchemas.Delete(zfssa.Name())
+	})
+	zd.initOwner()
+	zd.initOwnerSchemas(ctx)
+	for _, property := range zfssarest.OwnerSchema {
+		if _, found := zfssa.Lookup("/api/storage/v2/schema/" + property.Property); !found {
+			t.Errorf("property %s missing from the schema", property.Property)
+		}
+	}
+
+	parameters := filesystemParameters()
+	parameters[pvNameKey] = "pvc-owned"
+	parameters[pvcNamespaceKey] = "team-a"
+	parameters[pvcNameKey] = "data"
+	vol, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-owned",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+		VolumeCapabilities: mountCapabilities(),
+		Parameters:         parameters,
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume failed: %v", err)
+	}
+	fs, _ := zfssa.Lookup("/api/storage/v2/pools/p0/projects/k8s/filesystems/pvc-owned")
+	for property, expected := range map[string]string{
+		"custom:zcsiClusterId":    "cluster-a",
+		"custom:zcsiPvName":       "pvc-owned",
+		"custom:zcsiPvcNamespace": "team-a",
+		"custom:zcsiPvcName":      "data",
+		"custom:zcsiCreatedBy":    "zfssa-csi-driver/test",
+	} {
+		if fs[property] != expected {
+			t.Errorf("unexpected %s: %v", property, fs[property])
+		}
+	}
+
+	// A share of another cluster is not deleted.
+	client, token, err := zd.lookUpToken(ctx, zfssa.Name(), nil)
+	if err != nil {
+		t.Fatalf("lookUpToken failed: %v", err)
+	}
+	vid, _, _, err := client.CreateLUN(ctx, token, "lun-other", Gib,
+		&map[string]string{"pool": testPool, "project": testProject, "targetGroup": "tg0"},
+		&zfssarest.Owner{ClusterId: "cluster-b"})
+	if err != nil {
+		t.Fatalf("CreateLUN failed: %v", err)
+	}
+	_, err = zd.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: vid.String()})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("DeleteVolume of a share of another cluster: FailedPrecondition expected, got %v", err)
+	}
+	if _, found := zfssa.Lookup("/api/storage/v2/pools/p0/projects/k8s/luns/lun-other"); !found {
+		t.Errorf("share of another cluster deleted")
+	}
+
+	if _, err = zd.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: vol.GetVolume().GetVolumeId()}); err != nil {
+		t.Errorf("DeleteVolume failed: %v", err)
+	}
+}
+
+// Without the ID of the cluster, the shares of any cluster are protected.
+func TestUnknownClusterOwner(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+	ctx := testContext()
+
+	// The kube-system namespace can't be read.
+	clientset = fake.NewSimpleClientset()
+	t.Cleanup(func() {
+		clientset = nil
+		driverOwner = zfssarest.Owner{}
+	})
+	zd.initOwner()
+	if driverOwner.ClusterId != "" {
+		t.Fatalf("unexpected owner %+v", driverOwner)
+	}
+
+	client, token, err := zd.lookUpToken(ctx, zfssa.Name(), nil)
+	if err != nil {
+		t.Fatalf("lookUpToken failed: %v", err)
+	}
+	vid, _, _, err := client.CreateLUN(ctx, token, "lun-other", Gib,
+		&map[string]string{"pool": testPool, "project": testProject, "targetGroup": "tg0"},
+		&zfssarest.Owner{ClusterId: "cluster-b"})
+	if err != nil {
+		t.Fatalf("CreateLUN failed: %v", err)
+	}
+	_, err = zd.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: vid.String()})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("DeleteVolume of an owned share: FailedPrecondition expected, got %v", err)
+	}
+	if _, found := zfssa.Lookup("/api/storage/v2/pools/p0/projects/k8s/luns/lun-other"); !found {
+		t.Errorf("owned share deleted while the ID of the cluster is unknown")
+	}
+
+	// The shares created meanwhile have no owner and can be deleted.
+	vol, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-unowned",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+		VolumeCapabilities: mountCapabilities(),
+		Parameters:         filesystemParameters(),
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume failed: %v", err)
+	}
+	if _, err = zd.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: vol.GetVolume().GetVolumeId()}); err != nil {
+		t.Errorf("DeleteVolume failed: %v", err)
+	}
+}
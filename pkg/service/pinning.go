@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest"
+	"os"
+	"strings"
+)
+
+// The certificate of an appliance can be pinned instead of being verified against a
+// certificate authority (see zfssarest.Pinning). For the default appliance, pinning is
+// configured with the following environment variables:
+//
+//	ZFSSA_CERT_FINGERPRINT	SHA-256 fingerprint of the certificate of the appliance.
+//	ZFSSA_CERT_PUBKEY		SHA-256 hash of the public key of the certificate.
+//	ZFSSA_CERT_TOFU		"true" to pin the certificate presented by the first connection.
+//	ZFSSA_CERT_PIN_FILE	File recording the fingerprint pinned on first use.
+//
+// and for the other appliances with the corresponding fields of the appliances file:
+//
+//	appliances:
+//	  - target: 10.0.0.12
+//	    credentials: /mnt/zfssa3/zfssa.yaml
+//	    fingerprint: "5E:2A:...:C4"
+//	  - target: 10.0.0.13
+//	    credentials: /mnt/zfssa4/zfssa.yaml
+//	    trustOnFirstUse: true
+//	    pinFile: /var/lib/zfssa-csi/10.0.0.13.pin
+//
+// When pinning is configured, the certificate file of the appliance is not used. With
+// trust on first use and a pin file, the fingerprint recorded in the file (if any) is
+// pinned, otherwise the fingerprint pinned by the first connection is written to it.
+
+// Pinning configuration of an appliance.
+type pinConfig struct {
+	Fingerprint     string `yaml:"fingerprint"`
+	PublicKey       string `yaml:"publicKey"`
+	TrustOnFirstUse bool   `yaml:"trustOnFirstUse"`
+	PinFile         string `yaml:"pinFile"`
+}
+
+// Returns true if the certificate of the appliance is pinned.
+func (p *pinConfig) enabled() bool {
+	return p != nil && (p.Fingerprint != "" || p.PublicKey != "" || p.TrustOnFirstUse)
+}
+
+// Gets the pinning configuration of the default appliance from the environment.
+func getPinConfig() (pinConfig, error) {
+	pin := pinConfig{
+		Fingerprint: strings.TrimSpace(getEnvFallback("ZFSSA_CERT_FINGERPRINT", "")),
+		PublicKey:   strings.TrimSpace(getEnvFallback("ZFSSA_CERT_PUBKEY", "")),
+		PinFile:     strings.TrimSpace(getEnvFallback("ZFSSA_CERT_PIN_FILE", "")),
+	}
+	switch strings.ToLower(strings.TrimSpace(getEnvFallback("ZFSSA_CERT_TOFU", "False"))) {
+	case "true":
+		pin.TrustOnFirstUse = true
+	case "false":
+		pin.TrustOnFirstUse = false
+	default:
+		return pin, errors.New("ZFSSA_CERT_TOFU value is invalid")
+	}
+	return pin, nil
+}
+
+// Pins the certificate of the appliance in its REST client.
+func (p *pinConfig) apply(client *zfssarest.Client) error {
+
+	pinning := zfssarest.Pinning{
+		Fingerprint:     p.Fingerprint,
+		PublicKey:       p.PublicKey,
+		TrustOnFirstUse: p.TrustOnFirstUse,
+	}
+
+	if p.TrustOnFirstUse && p.PinFile != "" {
+		if pinning.Fingerprint == "" && pinning.PublicKey == "" {
+			data, err := os.ReadFile(p.PinFile)
+			switch {
+			case err == nil:
+				pinning.Fingerprint = strings.TrimSpace(string(data))
+			case !os.IsNotExist(err):
+				return errors.New(fmt.Sprintf("the pin file <%s> could not be read: <%s>", p.PinFile, err))
+			}
+		}
+		pinFile := p.PinFile
+		pinning.Record = func(fingerprint string) error {
+			return os.WriteFile(pinFile, []byte(fingerprint+"\n"), 0600)
+		}
+	}
+
+	return client.SetPinning(nil, pinning)
+}
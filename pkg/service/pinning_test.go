@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest/zfssatest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPinnedAppliance(t *testing.T) {
+	zd, _ := newTestDriver(t)
+	other := zfssatest.NewServer(testUser, testPassword)
+	t.Cleanup(other.Close)
+	other.AddPool(testPool, 100*Gib)
+
+	dir := t.TempDir()
+	pinfile := filepath.Join(dir, "other.pin")
+	zd.config.AppliancesLocation = filepath.Join(dir, "appliances.yaml")
+	writeTestFile(t, zd.config.AppliancesLocation, "appliances:\n"+
+		"  - target: "+other.Name()+"\n"+
+		"    credentials: "+zd.config.CredLocation+"\n"+
+		"    trustOnFirstUse: true\n"+
+		"    pinFile: "+pinfile+"\n")
+
+	probe := func() error {
+		ctx := testContext()
+		client, token, err := zd.lookUpToken(ctx, other.Name(), nil)
+		if err != nil {
+			return err
+		}
+		_, err = client.GetPool(ctx, token, testPool)
+		return err
+	}
+
+	// The fingerprint is recorded by the first connection.
+	zd.appliances = nil
+	if err := zd.initAppliances(); err != nil {
+		t.Fatalf("initAppliances failed: %v", err)
+	}
+	if err := probe(); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	fingerprint, err := os.ReadFile(pinfile)
+	if err != nil || len(strings.Split(strings.TrimSpace(string(fingerprint)), ":")) != 32 {
+		t.Fatalf("fingerprint not recorded: %q, %v", fingerprint, err)
+	}
+
+	// The recorded fingerprint is pinned when the driver restarts.
+	zd.appliances = nil
+	if err := zd.initAppliances(); err != nil {
+		t.Fatalf("initAppliances failed: %v", err)
+	}
+	if err := probe(); err != nil {
+		t.Fatalf("request failed with the recorded fingerprint: %v", err)
+	}
+
+	writeTestFile(t, pinfile, strings.Repeat("00:", 31)+"00\n")
+	zd.appliances = nil
+	if err := zd.initAppliances(); err != nil {
+		t.Fatalf("initAppliances failed: %v", err)
+	}
+	if err := probe(); status.Code(err) != codes.Unavailable {
+		t.Errorf("expected Unavailable with another fingerprint, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/oracle/zfssa-csi-driver/pkg/utils"
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// By default the volumes of a StorageClass are created in the project named by its
+// "project" parameter, which must exist. With the parameter "projectMode" set to
+// "namespace", each namespace gets its own project instead, named after the namespace
+// of the PVC and optionally prefixed by the parameter "projectPrefix":
+//
+//	parameters:
+//	  pool: pool0
+//	  projectMode: namespace
+//	  projectPrefix: k8s-
+//
+// The project is created on demand when the first volume of the namespace is created.
+// Its properties are inherited from the pool, the project can be modified afterwards
+// (quota, reservation...). The namespace of the PVC is passed by the external
+// provisioner when it runs with --extra-create-metadata.
+
+const (
+	projectModeStatic    = "static"
+	projectModeNamespace = "namespace"
+	pvcNamespaceKey      = "csi.storage.k8s.io/pvc/namespace"
+)
+
+// Sets the "project" parameter of the request if the project is derived from the
+// namespace of the PVC. Returns true if it is: the project is then created, once the
+// request is validated, by ensureProject.
+func resolveProject(req *csi.CreateVolumeRequest) (bool, error) {
+
+	parameters := req.GetParameters()
+	switch parameters["projectMode"] {
+	case "", projectModeStatic:
+		return false, nil
+	case projectModeNamespace:
+	default:
+		return false, status.Errorf(codes.InvalidArgument, "invalid projectMode (%s)", parameters["projectMode"])
+	}
+
+	namespace := parameters[pvcNamespaceKey]
+	if len(namespace) == 0 {
+		return false, status.Errorf(codes.InvalidArgument,
+			"projectMode %s requires the namespace of the PVC (%s), is --extra-create-metadata set?",
+			projectModeNamespace, pvcNamespaceKey)
+	}
+	project := parameters["projectPrefix"] + namespace
+	if !utils.IsResourceNameValid(project) {
+		return false, status.Errorf(codes.InvalidArgument, "project name is invalid (%s)", project)
+	}
+
+	req.Parameters["project"] = project
+	return true, nil
+}
+
+// Creates the project passed in if it doesn't exist.
+func ensureProject(ctx context.Context, client *zfssarest.Client, token *zfssarest.Token,
+	pool, project string) error {
+
+	_, err := client.GetProject(ctx, token, pool, project)
+	if status.Code(err) != codes.NotFound {
+		return err
+	}
+
+	utils.GetLogCTRL(ctx, 2).Println("Creating project", "pool", pool, "project", project)
+	_, _, err = client.CreateProject(ctx, token, pool, project, nil)
+	if status.Code(err) == codes.AlreadyExists {
+		// Created concurrently for another volume of the namespace.
+		return nil
+	}
+	return err
+}
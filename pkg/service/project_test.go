@@ -0,0 +1,105 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNamespaceProject(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+	ctx := testContext()
+
+	parameters := func(namespace string) map[string]string {
+		return map[string]string{
+			"pool":          testPool,
+			"projectMode":   projectModeNamespace,
+			"projectPrefix": "k8s-",
+			pvcNamespaceKey: namespace,
+		}
+	}
+
+	// The project of the namespace is created by the first volume and reused by the next.
+	for _, name := range []string{"pvc-1", "pvc-2"} {
+		vol, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+			Name:               name,
+			CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+			VolumeCapabilities: mountCapabilities(),
+			Parameters:         parameters("team-a"),
+		})
+		if err != nil {
+			t.Fatalf("CreateVolume failed: %v", err)
+		}
+		if !strings.HasSuffix(vol.GetVolume().GetVolumeId(), "/"+testPool+"/k8s-team-a/"+name) {
+			t.Errorf("volume not created in the project of the namespace: %s", vol.GetVolume().GetVolumeId())
+		}
+	}
+	if _, ok := zfssa.Lookup("/api/storage/v2/pools/" + testPool + "/projects/k8s-team-a/filesystems/pvc-2"); !ok {
+		t.Errorf("filesystem not found in the project of the namespace")
+	}
+
+	for _, tt := range []struct {
+		name       string
+		parameters map[string]string
+	}{
+		{"no namespace", parameters("")},
+		{"invalid mode", map[string]string{"pool": testPool, "project": testProject, "projectMode": "pvc"}},
+		{"invalid project", parameters("team/b")},
+	} {
+		_, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+			Name:               "pvc-invalid",
+			CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+			VolumeCapabilities: mountCapabilities(),
+			Parameters:         tt.parameters,
+		})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: expected InvalidArgument, got %v", tt.name, err)
+		}
+	}
+
+	// The project is not created for a request that fails the validation.
+	projectCreations := func() int {
+		n := 0
+		for _, r := range zfssa.Requests() {
+			if r.Method == "POST" && strings.HasSuffix(r.Path, "/pools/"+testPool+"/projects") {
+				n++
+			}
+		}
+		return n
+	}
+	created := projectCreations()
+	for _, tt := range []struct {
+		name         string
+		capabilities []*csi.VolumeCapability
+		parameters   map[string]string
+	}{
+		{"no capabilities", nil, parameters("team-b")},
+		{"invalid protocol", mountCapabilities(), func() map[string]string {
+			p := parameters("team-b")
+			p["protocol"] = "iscsi"
+			return p
+		}()},
+		{"no target group", blockCapabilities(), parameters("team-b")},
+	} {
+		_, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+			Name:               "pvc-invalid",
+			CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+			VolumeCapabilities: tt.capabilities,
+			Parameters:         tt.parameters,
+		})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: expected InvalidArgument, got %v", tt.name, err)
+		}
+	}
+	if projectCreations() != created {
+		t.Errorf("project created for an invalid request")
+	}
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/oracle/zfssa-csi-driver/pkg/utils"
+	"golang.org/x/net/context"
+	"os"
+	"time"
+)
+
+// The credentials file and the certificate of an appliance are usually mounted from
+// Kubernetes secrets and may be updated while the driver runs. Their content is cached
+// in memory and the files are polled: when the content of a file changes, it is reloaded
+// and swapped atomically with the previous one.
+//
+//   - A change of credentials invalidates the sessions opened with the previous account.
+//   - A change of certificate replaces the TLS configuration of the REST client and
+//     invalidates all the sessions opened on the appliance.
+//   - A change of client certificate or key invalidates all the sessions as well.
+//
+// A file that cannot be read or is invalid does not stop the driver: the error is logged
+// and the previous credentials or certificate remain in use until the file is fixed.
+
+// Interval between two checks of the files of the appliances.
+const reloadPeriod = 10 * time.Second
+
+// Polls the credentials and certificates of the appliances until done is closed.
+func (zd *ZFSSADriver) watchAppliances(done <-chan struct{}) {
+	ticker := time.NewTicker(reloadPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			zd.reloadAppliances(nil)
+		}
+	}
+}
+
+// Reloads the credentials and certificates of the appliances that changed.
+func (zd *ZFSSADriver) reloadAppliances(ctx context.Context) {
+	for _, name := range zd.getApplianceNames() {
+		zd.appliances[name].reload(ctx)
+	}
+}
+
+// Reloads the credentials and the certificate of the appliance if they changed. A
+// failure is logged once, the recovery as well.
+func (za *zAppliance) reload(ctx context.Context) {
+	err := errors.Join(za.loadCredentials(ctx), za.loadCertificate(ctx), za.loadClientCertificate(ctx))
+
+	za.reloadMtx.Lock()
+	defer za.reloadMtx.Unlock()
+	switch {
+	case err != nil && err.Error() != za.reloadErr:
+		utils.GetLogCSID(ctx, 2).Println("Reload of the appliance files failed, previous ones kept",
+			"appliance", za.name, "error", err)
+		za.reloadErr = err.Error()
+	case err == nil && za.reloadErr != "":
+		utils.GetLogCSID(ctx, 2).Println("Reload of the appliance files succeeded", "appliance", za.name)
+		za.reloadErr = ""
+	}
+}
+
+// Loads the credentials file if its content changed since the last load. The sessions
+// of the previous account are invalidated.
+func (za *zAppliance) loadCredentials(ctx context.Context) error {
+	za.reloadMtx.Lock()
+	defer za.reloadMtx.Unlock()
+
+	data, err := os.ReadFile(za.credLocation)
+	if err != nil {
+		return errors.New(fmt.Sprintf("the ZFSSA credentials file <%s> could not be read: <%s>",
+			za.credLocation, err))
+	}
+	hash := sha256.Sum256(data)
+	if hash == za.credHash && za.creds.Load() != nil {
+		return nil
+	}
+
+	creds, err := parseCredentials(za.credLocation, data)
+	if err != nil {
+		return err
+	}
+	previous := za.creds.Swap(creds)
+	za.credHash = hash
+
+	if previous != nil && *previous != *creds {
+		utils.GetLogCSID(ctx, 2).Println("Credentials reloaded", "appliance", za.name,
+			"user", creds.Username)
+		za.client.InvalidateSessions(ctx, previous.Username)
+	}
+	return nil
+}
+
+// Loads the certificate if its content changed since the last load.
+func (za *zAppliance) loadCertificate(ctx context.Context) error {
+	if !za.secure {
+		return nil
+	}
+
+	za.reloadMtx.Lock()
+	defer za.reloadMtx.Unlock()
+
+	data, err := os.ReadFile(za.certLocation)
+	if err != nil {
+		return errors.New(fmt.Sprintf("the certificate <%s> could not be read: <%s>",
+			za.certLocation, err))
+	}
+	hash := sha256.Sum256(data)
+	if hash == za.certHash {
+		return nil
+	}
+
+	if err := za.client.SetCertificate(ctx, data); err != nil {
+		return errors.New(fmt.Sprintf("the certificate <%s> could not be loaded: <%s>",
+			za.certLocation, err))
+	}
+	za.certHash = hash
+	utils.GetLogCSID(ctx, 2).Println("Certificate reloaded", "appliance", za.name)
+	return nil
+}
+
+// Loads the client certificate and key if their content changed since the last load.
+func (za *zAppliance) loadClientCertificate(ctx context.Context) error {
+	if za.clientCertLocation == "" {
+		return nil
+	}
+
+	za.reloadMtx.Lock()
+	defer za.reloadMtx.Unlock()
+
+	certPEM, err := os.ReadFile(za.clientCertLocation)
+	if err != nil {
+		return errors.New(fmt.Sprintf("the client certificate <%s> could not be read: <%s>",
+			za.clientCertLocation, err))
+	}
+	keyPEM, err := os.ReadFile(za.clientKeyLocation)
+	if err != nil {
+		return errors.New(fmt.Sprintf("the client key <%s> could not be read: <%s>",
+			za.clientKeyLocation, err))
+	}
+	hash := sha256.Sum256(append(append([]byte{}, certPEM...), keyPEM...))
+	if hash == za.clientCertHash {
+		return nil
+	}
+
+	if err := za.client.SetClientCertificate(ctx, certPEM, keyPEM); err != nil {
+		return errors.New(fmt.Sprintf("the client certificate <%s> could not be loaded: <%s>",
+			za.clientCertLocation, err))
+	}
+	za.clientCertHash = hash
+	utils.GetLogCSID(ctx, 2).Println("Client certificate loaded", "appliance", za.name)
+	return nil
+}
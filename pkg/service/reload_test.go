@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"os"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Returns the error of a request sent to the default appliance of the driver.
+func probeAppliance(zd *ZFSSADriver) error {
+	ctx := testContext()
+	client, token, err := zd.lookUpToken(ctx, "", nil)
+	if err != nil {
+		return err
+	}
+	_, err = client.GetPool(ctx, token, testPool)
+	return err
+}
+
+func writeTestFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(name, []byte(content), 0600); err != nil {
+		t.Fatalf("cannot write %s: %v", name, err)
+	}
+}
+
+func TestReloadCredentials(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+	credfile := zd.config.CredLocation
+
+	if err := probeAppliance(zd); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	// An invalid file is reported and the previous credentials are kept.
+	writeTestFile(t, credfile, "username: [\n")
+	zd.reloadAppliances(testContext())
+	if zd.appliances[zfssa.Name()].reloadErr == "" {
+		t.Errorf("invalid credentials file not reported")
+	}
+	if err := probeAppliance(zd); err != nil {
+		t.Fatalf("request failed with the previous credentials: %v", err)
+	}
+
+	// New credentials invalidate the session of the previous account.
+	writeTestFile(t, credfile, "username: "+testUser+"\npassword: wrong\n")
+	zd.reloadAppliances(testContext())
+	if zd.appliances[zfssa.Name()].reloadErr != "" {
+		t.Errorf("unexpected reload error: %s", zd.appliances[zfssa.Name()].reloadErr)
+	}
+	if err := probeAppliance(zd); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated with a wrong password, got %v", err)
+	}
+
+	writeTestFile(t, credfile, "username: "+testUser+"\npassword: "+testPassword+"\n")
+	zd.reloadAppliances(testContext())
+	if err := probeAppliance(zd); err != nil {
+		t.Fatalf("request failed once the credentials were fixed: %v", err)
+	}
+}
+
+func TestReloadCertificate(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+
+	certfile := t.TempDir() + "/zfssa.crt"
+	cert, err := os.ReadFile(zfssa.CertFile())
+	if err != nil {
+		t.Fatalf("cannot read the certificate: %v", err)
+	}
+	writeTestFile(t, certfile, string(cert))
+	zd.appliances = nil
+	if err := zd.addAppliance(&applianceConfig{
+		Target:      zfssa.Name(),
+		Credentials: zd.config.CredLocation,
+		Certificate: certfile,
+	}); err != nil {
+		t.Fatalf("addAppliance failed: %v", err)
+	}
+	if err := probeAppliance(zd); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	// A certificate that cannot be parsed is reported, the previous one is kept.
+	writeTestFile(t, certfile, "garbage")
+	zd.reloadAppliances(testContext())
+	if zd.appliances[zfssa.Name()].reloadErr == "" {
+		t.Errorf("invalid certificate not reported")
+	}
+	sessions := zfssa.Sessions()
+	if err := probeAppliance(zd); err != nil {
+		t.Fatalf("request failed with the previous certificate: %v", err)
+	}
+	if zfssa.Sessions() != sessions {
+		t.Errorf("session renewed although the certificate was not reloaded")
+	}
+
+	// A new certificate invalidates the sessions.
+	writeTestFile(t, certfile, string(cert)+"\n")
+	zd.reloadAppliances(testContext())
+	if zd.appliances[zfssa.Name()].reloadErr != "" {
+		t.Errorf("unexpected reload error: %s", zd.appliances[zfssa.Name()].reloadErr)
+	}
+	if err := probeAppliance(zd); err != nil {
+		t.Fatalf("request failed once the certificate was reloaded: %v", err)
+	}
+	if zfssa.Sessions() != sessions+1 {
+		t.Errorf("session not renewed after a certificate change")
+	}
+}
+
+func TestCloseSessions(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+	if err := probeAppliance(zd); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if zfssa.OpenSessions() != 1 {
+		t.Fatalf("unexpected number of sessions open: %d", zfssa.OpenSessions())
+	}
+	zd.closeSessions(nil)
+	if zfssa.OpenSessions() != 0 {
+		t.Errorf("sessions not closed on shutdown")
+	}
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/oracle/zfssa-csi-driver/pkg/utils"
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest"
+	"golang.org/x/net/context"
+	"strings"
+	"sync"
+)
+
+// By default the volumes and snapshots of a whole appliance are listed. The listing can
+// be restricted to the pools and projects used by Kubernetes. For the default appliance,
+// the scopes are listed, separated by commas, in ZFSSA_LIST_SCOPES:
+//
+//	ZFSSA_LIST_SCOPES=pool0/k8s,pool1
+//
+// and for the other appliances in the "scopes" field of the appliances file:
+//
+//	appliances:
+//	  - target: 10.0.0.12
+//	    credentials: /mnt/zfssa3/zfssa.yaml
+//	    scopes: [pool0/k8s, pool1]
+//
+// A scope is either a pool (all its projects) or a project of a pool. The scopes of an
+// appliance are fetched concurrently and ListVolumes and ListSnapshots only return the
+// volumes and snapshots located in the scopes.
+
+// Maximum number of scopes of an appliance fetched at the same time.
+const maxScopeFetches = 8
+
+// Pool, or project of a pool, the volumes and snapshots are listed from.
+type listScope struct {
+	pool    string
+	project string // Empty for all the projects of the pool
+}
+
+func (s listScope) String() string {
+	if s.project == "" {
+		return s.pool
+	}
+	return s.pool + "/" + s.project
+}
+
+// Returns true if the project passed in belongs to the scope.
+func (s listScope) contains(pool, project string) bool {
+	return s.pool == pool && (s.project == "" || s.project == project)
+}
+
+// Parses a list of scopes ("pool" or "pool/project").
+func parseScopes(values []string) ([]listScope, error) {
+	var scopes []listScope
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		parts := strings.Split(value, "/")
+		if len(parts) > 2 || parts[0] == "" || (len(parts) == 2 && parts[1] == "") {
+			return nil, errors.New(fmt.Sprintf("invalid scope <%s>, expected pool or pool/project", value))
+		}
+		scope := listScope{pool: parts[0]}
+		if len(parts) == 2 {
+			scope.project = parts[1]
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes, nil
+}
+
+// Returns true if the project passed in is listed on the appliance.
+func (za *zAppliance) inScope(pool, project string) bool {
+	if len(za.scopes) == 0 {
+		return true
+	}
+	for _, scope := range za.scopes {
+		if scope.contains(pool, project) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if the volume passed in is listed by the driver.
+func (zd *ZFSSADriver) volumeInScope(vid *utils.VolumeId) bool {
+	appliance, err := zd.getAppliance(vid.Zfssa)
+	return err == nil && appliance.inScope(vid.Pool, vid.Project)
+}
+
+// Returns the projects to list on the appliance. The pool scopes are expanded into
+// the projects of the pool. A nil list designates the whole appliance.
+func (za *zAppliance) listProjects(ctx context.Context, token *zfssarest.Token) ([]listScope, error) {
+	if len(za.scopes) == 0 {
+		return nil, nil
+	}
+
+	projects := make([]listScope, 0, len(za.scopes))
+	seen := make(map[listScope]bool)
+	for _, scope := range za.scopes {
+		if scope.project != "" {
+			if !seen[scope] {
+				seen[scope] = true
+				projects = append(projects, scope)
+			}
+			continue
+		}
+		list, err := za.client.GetProjects(ctx, token, scope.pool)
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range list {
+			p := listScope{pool: scope.pool, project: project.Name}
+			if !seen[p] {
+				seen[p] = true
+				projects = append(projects, p)
+			}
+		}
+	}
+	return projects, nil
+}
+
+// Calls fetch for each project passed in, at most maxScopeFetches at a time, and
+// returns the first error reported.
+func fetchScopes(projects []listScope, fetch func(scope listScope) error) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(projects))
+	slots := make(chan struct{}, maxScopeFetches)
+	for i, project := range projects {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, project listScope) {
+			defer wg.Done()
+			errs[i] = fetch(project)
+			<-slots
+		}(i, project)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
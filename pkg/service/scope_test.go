@@ -0,0 +1,116 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+)
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := parseScopes([]string{" p0/k8s", "p1", ""})
+	if err != nil || len(scopes) != 2 || scopes[0] != (listScope{"p0", "k8s"}) || scopes[1] != (listScope{"p1", ""}) {
+		t.Errorf("unexpected scopes %v, %v", scopes, err)
+	}
+	for _, invalid := range []string{"/k8s", "p0/", "p0/k8s/x"} {
+		if _, err := parseScopes([]string{invalid}); err == nil {
+			t.Errorf("scope %q accepted", invalid)
+		}
+	}
+}
+
+func TestScopedListing(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+	zfssa.AddProject(testPool, "other")
+	ctx := testContext()
+
+	for _, project := range []string{testProject, "other"} {
+		vol, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+			Name:               "pvc-" + project,
+			CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+			VolumeCapabilities: mountCapabilities(),
+			Parameters:         map[string]string{"pool": testPool, "project": project},
+		})
+		if err != nil {
+			t.Fatalf("CreateVolume failed: %v", err)
+		}
+		_, err = zd.CreateSnapshot(ctx, &csi.CreateSnapshotRequest{
+			Name:           "snap-" + project,
+			SourceVolumeId: vol.GetVolume().GetVolumeId(),
+		})
+		if err != nil {
+			t.Fatalf("CreateSnapshot failed: %v", err)
+		}
+	}
+
+	list := func() (volumes, snapshots []string) {
+		// Start from empty caches, as after a restart of the driver.
+		zd.vCache.vHash = make(map[string]zVolumeInterface)
+		zd.sCache.sHash = make(map[string]*zSnapshot)
+		vols, err := zd.ListVolumes(ctx, &csi.ListVolumesRequest{})
+		if err != nil {
+			t.Fatalf("ListVolumes failed: %v", err)
+		}
+		for _, entry := range vols.GetEntries() {
+			volumes = append(volumes, entry.GetVolume().GetVolumeId())
+		}
+		snaps, err := zd.ListSnapshots(ctx, &csi.ListSnapshotsRequest{})
+		if err != nil {
+			t.Fatalf("ListSnapshots failed: %v", err)
+		}
+		for _, entry := range snaps.GetEntries() {
+			snapshots = append(snapshots, entry.GetSnapshot().GetSnapshotId())
+		}
+		sort.Strings(volumes)
+		sort.Strings(snapshots)
+		return volumes, snapshots
+	}
+
+	appliance := zd.appliances[zfssa.Name()]
+	appliance.scopes = []listScope{{pool: testPool, project: testProject}}
+	zfssa.ResetRequests()
+	volumes, snapshots := list()
+	if len(volumes) != 1 || !strings.Contains(volumes[0], "/"+testProject+"/pvc-"+testProject) {
+		t.Errorf("unexpected volumes in the project scope: %v", volumes)
+	}
+	if len(snapshots) != 1 || !strings.Contains(snapshots[0], "snap-"+testProject) {
+		t.Errorf("unexpected snapshots in the project scope: %v", snapshots)
+	}
+	for _, r := range zfssa.Requests() {
+		if r.Path == "/api/storage/v2/filesystems" || r.Path == "/api/storage/v2/luns" ||
+			r.Path == "/api/storage/v2/snapshots" {
+			t.Errorf("whole appliance listed with a scope: %s", r.Path)
+		}
+	}
+
+	for _, r := range zfssa.Requests() {
+		if strings.Contains(r.Path, "/projects/other/") {
+			t.Errorf("project out of the scope listed: %s", r.Path)
+		}
+	}
+
+	appliance.scopes = []listScope{{pool: testPool}}
+	volumes, snapshots = list()
+	if len(volumes) != 2 || len(snapshots) != 2 {
+		t.Errorf("unexpected volumes %v and snapshots %v in the pool scope", volumes, snapshots)
+	}
+
+	// Without scope, the snapshots of the whole appliance are read at once.
+	appliance.scopes = nil
+	zfssa.ResetRequests()
+	volumes, snapshots = list()
+	if len(volumes) != 2 || len(snapshots) != 2 {
+		t.Errorf("unexpected volumes %v and snapshots %v without scope", volumes, snapshots)
+	}
+	for _, r := range zfssa.Requests() {
+		if strings.HasSuffix(r.Path, "/snapshots") && r.Path != "/api/storage/v2/snapshots" {
+			t.Errorf("snapshots of a share listed without scope: %s", r.Path)
+		}
+	}
+}
@@ -28,10 +28,11 @@ import (
 
 const (
 	// Default Log Level
-	DefaultLogLevel   = "3"
-	DefaultCertPath   = "/mnt/certs/zfssa.crt"
-	DefaultCredPath   = "/mnt/zfssa/zfssa.yaml"
-	DefaultConfigPath = "/mnt/config/config.yaml"
+	DefaultLogLevel       = "3"
+	DefaultCertPath       = "/mnt/certs/zfssa.crt"
+	DefaultCredPath       = "/mnt/zfssa/zfssa.yaml"
+	DefaultConfigPath     = "/mnt/config/config.yaml"
+	DefaultAppliancesPath = "/mnt/config/appliances.yaml"
 )
 
 type ZFSSADriver struct {
@@ -40,6 +41,7 @@ type ZFSSADriver struct {
 	version     string
 	endpoint    string
 	config      config
+	appliances  map[string]*zAppliance
 	NodeMounter Mounter
 	vCache      volumeHashTable
 	sCache      snapshotHashTable
@@ -49,23 +51,32 @@ type ZFSSADriver struct {
 }
 
 type config struct {
-	Appliance    string
-	User         string
-	endpoint     string
-	HostIp       string
-	NodeName     string
-	PodIp        string
-	Secure       bool
-	logLevel     string
-	Certificate  []byte
-	CertLocation string
-	CredLocation string
+	Appliance          string
+	User               string
+	endpoint           string
+	HostIp             string
+	NodeName           string
+	PodIp              string
+	Secure             bool
+	logLevel           string
+	Certificate        []byte
+	CertLocation       string
+	CredLocation       string
+	AppliancesLocation string
+	Timeouts           zfssarest.Timeouts
+	RetryPolicy        zfssarest.RetryPolicy
+	SessionTimeout     time.Duration
+	ListScopes         []string
+	Pin                pinConfig
+	ClientCertLocation string
+	ClientKeyLocation  string
+	ClusterId          string
 }
 
 // The structured data in the ZFSSA credentials file
 type ZfssaCredentials struct {
-	Username string `yaml:username`
-	Password string `yaml:password`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
 }
 
 type accessType int
@@ -122,7 +133,7 @@ func NewZFSSADriver(driverName, version string) (*ZFSSADriver, error) {
 
 	utils.InitLogs(zd.config.logLevel, zd.name, version, zd.config.NodeName)
 
-	err = zfssarest.InitREST(zd.config.Appliance, zd.config.CertLocation, zd.config.Secure)
+	err = zd.initAppliances()
 	if err != nil {
 		return nil, err
 	}
@@ -131,6 +142,7 @@ func NewZFSSADriver(driverName, version string) (*ZFSSADriver, error) {
 	if err != nil {
 		return nil, err
 	}
+	zd.initOwner()
 
 	zd.is = newZFSSAIdentityServer(zd)
 	zd.cs = newZFSSAControllerServer(zd)
@@ -148,7 +160,23 @@ func NewZFSSADriver(driverName, version string) (*ZFSSADriver, error) {
 //	CSI_ENDPOINT	Unix socket the CSI driver will be listening on.
 //	ZFSSA_INSECURE	Boolean specifying whether an appliance certificate is not required.
 //	ZFSSA_CERT		Path to the certificate file (defaults to "/mnt/certs/zfssa.crt")
+//	ZFSSA_CERT_*	Pinning of the certificate of the appliance (see pinning.go)
+//	ZFSSA_CLIENT_CERT	Path to the client certificate presented to the appliance (mutual TLS)
+//	ZFSSA_CLIENT_KEY	Path to the private key of the client certificate
 //	ZFSSA_CRED		Path to the credential file (defaults to "/mnt/zfssa/zfssa.yaml")
+//	ZFSSA_APPLIANCES	Path to the file listing additional appliances (defaults to
+//					"/mnt/config/appliances.yaml")
+//	ZFSSA_CREATE_TIMEOUT	Timeout of the requests creating an object on an appliance.
+//	ZFSSA_DELETE_TIMEOUT	Timeout of the requests deleting an object on an appliance.
+//	ZFSSA_LIST_TIMEOUT	Timeout of the requests reading objects on an appliance.
+//	ZFSSA_MODIFY_TIMEOUT	Timeout of the requests modifying an object on an appliance.
+//	ZFSSA_SESSION_TIMEOUT	Session timeout configured on the appliances (defaults to 15m).
+//	ZFSSA_RETRY_ATTEMPTS	Attempts of a request failing for a transient reason.
+//	ZFSSA_RETRY_BASE_DELAY	Delay before the first retry of a request.
+//	ZFSSA_RETRY_MAX_DELAY	Maximum delay between two attempts of a request.
+//	ZFSSA_RETRY_BUDGET	Maximum number of retries in a row, for all the requests.
+//	ZFSSA_LIST_SCOPES	Pools and projects the volumes are listed from (see scope.go).
+//	ZFSSA_CLUSTER_ID	ID of the cluster recorded on the shares (see owner.go).
 //	HOST_IP			IP address of the node.
 //	POD_IP			IP address of the pod.
 //	LOG_LEVEL		Log level to apply.
@@ -170,7 +198,7 @@ func getConfig(zd *ZFSSADriver) error {
 	}
 
 	// Get the user from the credentials file, this can be stored in the config file without a problem
-	zd.config.User, err = zd.GetUsernameFromCred()
+	zd.config.User, err = getUsernameFromCred(credfile)
 	if err != nil {
 		return errors.New(fmt.Sprintf("Cannot get ZFSSA username: %s", err))
 	}
@@ -212,7 +240,12 @@ func getConfig(zd *ZFSSADriver) error {
 		return errors.New("ZFSSA_INSECURE value is invalid")
 	}
 
-	if zd.config.Secure {
+	zd.config.Pin, err = getPinConfig()
+	if err != nil {
+		return err
+	}
+
+	if zd.config.Secure && !zd.config.Pin.enabled() {
 		certfile := strings.TrimSpace(getEnvFallback("ZFSSA_CERT", DefaultCertPath))
 		if len(certfile) == 0 {
 			return errors.New("a certificate is required")
@@ -228,6 +261,44 @@ func getConfig(zd *ZFSSADriver) error {
 		}
 	}
 
+	zd.config.AppliancesLocation = strings.TrimSpace(getEnvFallback("ZFSSA_APPLIANCES", DefaultAppliancesPath))
+
+	if scopes := strings.TrimSpace(getEnvFallback("ZFSSA_LIST_SCOPES", "")); scopes != "" {
+		zd.config.ListScopes = strings.Split(scopes, ",")
+	}
+
+	zd.config.ClusterId = strings.TrimSpace(getEnvFallback("ZFSSA_CLUSTER_ID", ""))
+
+	zd.config.ClientCertLocation = strings.TrimSpace(getEnvFallback("ZFSSA_CLIENT_CERT", ""))
+	zd.config.ClientKeyLocation = strings.TrimSpace(getEnvFallback("ZFSSA_CLIENT_KEY", ""))
+	if (zd.config.ClientCertLocation == "") != (zd.config.ClientKeyLocation == "") {
+		return errors.New("ZFSSA_CLIENT_CERT and ZFSSA_CLIENT_KEY must be set together")
+	}
+
+	zd.config.Timeouts = zfssarest.DefaultTimeouts
+	zd.config.SessionTimeout = zfssarest.DefaultSessionTimeout
+	for key, timeout := range map[string]*time.Duration{
+		"ZFSSA_CREATE_TIMEOUT":  &zd.config.Timeouts.Create,
+		"ZFSSA_DELETE_TIMEOUT":  &zd.config.Timeouts.Delete,
+		"ZFSSA_LIST_TIMEOUT":    &zd.config.Timeouts.List,
+		"ZFSSA_MODIFY_TIMEOUT":  &zd.config.Timeouts.Other,
+		"ZFSSA_SESSION_TIMEOUT": &zd.config.SessionTimeout,
+	} {
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		*timeout, err = time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || *timeout < 0 {
+			return errors.New(fmt.Sprintf("%s value is invalid: <%s>", key, value))
+		}
+	}
+
+	zd.config.RetryPolicy, err = getRetryPolicy()
+	if err != nil {
+		return err
+	}
+
 	zd.config.HostIp = getEnvFallback("HOST_IP", "0.0.0.0")
 	zd.config.PodIp = getEnvFallback("POD_IP", "0.0.0.0")
 	zd.config.logLevel = getEnvFallback("LOG_LEVEL", DefaultLogLevel)
@@ -248,48 +319,87 @@ var sigList = []os.Signal{
 	syscall.SIGQUIT,
 }
 
-// Retrieves just the username from a credential file (zd.config.CredLocation)
-func (zd *ZFSSADriver) GetUsernameFromCred() (string, error) {
-	yamlData, err := ioutil.ReadFile(zd.config.CredLocation)
-	if err != nil {
-		return "", errors.New(fmt.Sprintf("the ZFSSA credentials file <%s> could not be read: <%s>",
-			zd.config.CredLocation, err))
+// Returns the retry policy of the requests sent to the appliances, the default policy
+// modified by the ZFSSA_RETRY_* environment variables.
+func getRetryPolicy() (zfssarest.RetryPolicy, error) {
+	policy := zfssarest.DefaultRetryPolicy
+
+	if value, ok := os.LookupEnv("ZFSSA_RETRY_ATTEMPTS"); ok {
+		attempts, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || attempts < 1 {
+			return policy, errors.New(fmt.Sprintf("ZFSSA_RETRY_ATTEMPTS value is invalid: <%s>", value))
+		}
+		policy.MaxAttempts = attempts
 	}
 
-	var yamlConfig ZfssaCredentials
-	err = yaml.Unmarshal(yamlData, &yamlConfig)
-	if err != nil {
-		return "", errors.New(fmt.Sprintf("the ZFSSA credentials file <%s> could not be parsed: <%s>",
-			zd.config.CredLocation, err))
+	for key, delay := range map[string]*time.Duration{
+		"ZFSSA_RETRY_BASE_DELAY": &policy.BaseDelay,
+		"ZFSSA_RETRY_MAX_DELAY":  &policy.MaxDelay,
+	} {
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		var err error
+		*delay, err = time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || *delay < 0 {
+			return policy, errors.New(fmt.Sprintf("%s value is invalid: <%s>", key, value))
+		}
+	}
+	if policy.MaxDelay < policy.BaseDelay {
+		return policy, errors.New(fmt.Sprintf("ZFSSA_RETRY_MAX_DELAY (%s) is shorter than ZFSSA_RETRY_BASE_DELAY (%s)",
+			policy.MaxDelay, policy.BaseDelay))
 	}
 
-	if !isUsernameValid(yamlConfig.Username) {
-		return "", errors.New(fmt.Sprintf("ZFSSA username is invalid: <%s>", yamlConfig.Username))
+	if value, ok := os.LookupEnv("ZFSSA_RETRY_BUDGET"); ok {
+		budget, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || budget < 0 {
+			return policy, errors.New(fmt.Sprintf("ZFSSA_RETRY_BUDGET value is invalid: <%s>", value))
+		}
+		policy.Budget = budget
 	}
 
-	return yamlConfig.Username, nil
+	return policy, nil
 }
 
 // Retrieves just the username from a credential file
-func (zd *ZFSSADriver) GetPasswordFromCred() (string, error) {
-	yamlData, err := ioutil.ReadFile(zd.config.CredLocation)
+func getUsernameFromCred(credLocation string) (string, error) {
+	creds, err := readCredentials(credLocation)
 	if err != nil {
-		return "", errors.New(fmt.Sprintf("the ZFSSA credentials file <%s> could not be read: <%s>",
-			zd.config.CredLocation, err))
+		return "", err
 	}
+	return creds.Username, nil
+}
+
+// Retrieves the username and password from a credential file
+func readCredentials(credLocation string) (*ZfssaCredentials, error) {
+	yamlData, err := ioutil.ReadFile(credLocation)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("the ZFSSA credentials file <%s> could not be read: <%s>",
+			credLocation, err))
+	}
+	return parseCredentials(credLocation, yamlData)
+}
 
+// Parses the content of a credential file
+func parseCredentials(credLocation string, yamlData []byte) (*ZfssaCredentials, error) {
 	var yamlConfig ZfssaCredentials
-	err = yaml.Unmarshal(yamlData, &yamlConfig)
+	err := yaml.Unmarshal(yamlData, &yamlConfig)
 	if err != nil {
-		return "", errors.New(fmt.Sprintf("the ZFSSA credentials file <%s> could not be parsed: <%s>",
-			zd.config.CredLocation, err))
+		return nil, errors.New(fmt.Sprintf("the ZFSSA credentials file <%s> could not be parsed: <%s>",
+			credLocation, err))
 	}
 
-	return yamlConfig.Password, nil
+	if !isUsernameValid(yamlConfig.Username) {
+		return nil, errors.New(fmt.Sprintf("ZFSSA username is invalid: <%s>", yamlConfig.Username))
+	}
+
+	return &yamlConfig, nil
 }
 
 func (zd *ZFSSADriver) Run() {
 	// Refresh current information
+	zd.initOwnerSchemas(nil)
 	_ = zd.updateVolumeList(nil)
 	_ = zd.updateSnapshotList(nil)
 
@@ -299,9 +409,15 @@ func (zd *ZFSSADriver) Run() {
 	sigChannel := make(chan os.Signal, 1)
 	signal.Notify(sigChannel, sigList...)
 
+	// Watch the credentials and certificates of the appliances
+	done := make(chan struct{})
+	go zd.watchAppliances(done)
+
 	s.Start(zd.config.endpoint, *zd.is, *zd.cs, *zd.ns)
 	s.Wait(sigChannel)
 	s.Stop()
+	close(done)
+	zd.closeSessions(nil)
 	_ = os.RemoveAll(zd.config.endpoint)
 }
 
@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// With the parameter "protocol" set to "smb" in a StorageClass, the file systems are
+// shared over SMB instead of NFS: they are created with sharenfs set to "off" and
+// sharesmb set to the name of the volume ("name=pvc-..."), recorded in the volume
+// context as "smbShare". The nodes mount them with the cifs file system:
+//
+//	//<nfsServer>/<smbShare>
+//
+// The credentials of the SMB user are taken from the node publish secrets of the
+// StorageClass, under the keys "smbUsername", "smbPassword" and, optionally,
+// "smbDomain" ("username" and "password" being the credentials of the appliance).
+
+// Protocols the file systems are shared with, selected by the parameter "protocol".
+const (
+	protocolNFS = "nfs"
+	protocolSMB = "smb"
+)
+
+const (
+	// Volume context key of the name of the SMB share of the file system.
+	smbShareKey = "smbShare"
+	// Node publish secrets holding the SMB credentials.
+	smbUsernameKey = "smbUsername"
+	smbPasswordKey = "smbPassword"
+	smbDomainKey   = "smbDomain"
+)
+
+// Returns the protocol the file system is shared with, NFS if the parameters passed in
+// do not specify it.
+func filesystemProtocol(parameters map[string]string) (string, error) {
+	switch protocol := strings.ToLower(parameters["protocol"]); protocol {
+	case "", protocolNFS:
+		return protocolNFS, nil
+	case protocolSMB:
+		for _, key := range append([]string{"shareNFS", nfsExportKey}, nfsPolicyKeys...) {
+			if _, ok := parameters[key]; ok {
+				return "", status.Errorf(codes.InvalidArgument, "%s cannot be set with protocol %s", key,
+					protocolSMB)
+			}
+		}
+		return protocolSMB, nil
+	default:
+		return "", status.Errorf(codes.InvalidArgument, "unsupported protocol (%s)", parameters["protocol"])
+	}
+}
+
+// Returns the mount options carrying the SMB credentials of the node publish request
+// passed in.
+func smbCredentials(req *csi.NodePublishVolumeRequest) ([]string, error) {
+	secrets := req.GetSecrets()
+	username, password := secrets[smbUsernameKey], secrets[smbPasswordKey]
+	if username == "" || password == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%s and %s missing from the node publish secrets",
+			smbUsernameKey, smbPasswordKey)
+	}
+
+	options := []string{"username=" + username, "password=" + password}
+	if domain := secrets[smbDomainKey]; domain != "" {
+		options = append(options, "domain="+domain)
+	}
+	for _, option := range options {
+		// The comma separates the mount options.
+		if strings.Contains(option, ",") {
+			name, _, _ := strings.Cut(option, "=")
+			return nil, status.Errorf(codes.InvalidArgument, "SMB %s containing a comma not supported", name)
+		}
+	}
+	return options, nil
+}
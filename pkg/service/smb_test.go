@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package service
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/utils/mount"
+)
+
+// Mounter recording the mounts instead of performing them.
+type fakeMounter struct {
+	*mount.FakeMounter
+}
+
+func (m *fakeMounter) GetDeviceName(mountPath string) (string, int, error) {
+	return mount.GetDeviceNameFromMount(m, mountPath)
+}
+
+func (m *fakeMounter) MakeFile(pathname string) error {
+	return nil
+}
+
+func (m *fakeMounter) ExistsPath(pathname string) (bool, error) {
+	return true, nil
+}
+
+func TestFilesystemProtocol(t *testing.T) {
+	for _, tt := range []struct {
+		parameters map[string]string
+		protocol   string
+		code       codes.Code
+	}{
+		{map[string]string{}, protocolNFS, codes.OK},
+		{map[string]string{"protocol": "SMB"}, protocolSMB, codes.OK},
+		{map[string]string{"protocol": "smb", "shareNFS": "on"}, "", codes.InvalidArgument},
+		{map[string]string{"protocol": "smb", nfsClientsKey: "10.0.0.0/24"}, "", codes.InvalidArgument},
+		{map[string]string{"protocol": "iscsi"}, "", codes.InvalidArgument},
+	} {
+		protocol, err := filesystemProtocol(tt.parameters)
+		if protocol != tt.protocol || status.Code(err) != tt.code {
+			t.Errorf("%v: unexpected protocol %q, %v", tt.parameters, protocol, err)
+		}
+	}
+}
+
+func TestSMBVolume(t *testing.T) {
+	zd, zfssa := newTestDriver(t)
+	ctx := testContext()
+	mounter := &fakeMounter{mount.NewFakeMounter(nil)}
+	zd.NodeMounter = mounter
+
+	parameters := filesystemParameters()
+	parameters["protocol"] = "smb"
+	parameters["nfsServer"] = "192.0.2.10"
+	rsp, err := zd.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-smb",
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: Gib},
+		VolumeCapabilities: mountCapabilities(),
+		Parameters:         parameters,
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume failed: %v", err)
+	}
+	fs, _ := zfssa.Lookup("/api/storage/v2/pools/" + testPool + "/projects/" + testProject + "/filesystems/pvc-smb")
+	if fs["sharesmb"] != "name=pvc-smb" || fs["sharenfs"] != "off" {
+		t.Errorf("unexpected shares: sharesmb %v, sharenfs %v", fs["sharesmb"], fs["sharenfs"])
+	}
+
+	vol := rsp.GetVolume()
+	publish := func(secrets map[string]string) error {
+		_, err := zd.NodePublishVolume(ctx, &csi.NodePublishVolumeRequest{
+			VolumeId:          vol.GetVolumeId(),
+			StagingTargetPath: filepath.Join(t.TempDir(), "staging"),
+			TargetPath:        filepath.Join(t.TempDir(), "target"),
+			VolumeCapability:  mountCapabilities()[0],
+			VolumeContext:     vol.GetVolumeContext(),
+			Secrets:           secrets,
+		})
+		return err
+	}
+
+	if err := publish(map[string]string{smbUsernameKey: "k8s"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("NodePublishVolume without SMB password: InvalidArgument expected, got %v", err)
+	}
+	err = publish(map[string]string{smbUsernameKey: "k8s", smbPasswordKey: "secret", smbDomainKey: "EXAMPLE"})
+	if err != nil {
+		t.Fatalf("NodePublishVolume failed: %v", err)
+	}
+	if len(mounter.MountPoints) != 1 {
+		t.Fatalf("unexpected mounts: %v", mounter.MountPoints)
+	}
+	mp := mounter.MountPoints[0]
+	if mp.Device != "//192.0.2.10/pvc-smb" || mp.Type != "cifs" ||
+		!reflect.DeepEqual(mp.Opts, []string{"username=k8s", "password=secret", "domain=EXAMPLE"}) {
+		t.Errorf("unexpected mount: %s %s %v", mp.Device, mp.Type, mp.Opts)
+	}
+}
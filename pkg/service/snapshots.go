@@ -13,7 +13,6 @@ import (
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
-	"net/http"
 	"sync/atomic"
 )
 
@@ -45,16 +44,16 @@ func (zsnap *zSnapshot) create(ctx context.Context, token *zfssarest.Token) (
 
 	utils.GetLogCTRL(ctx, 5).Println("zsnap.create")
 
-	snapinfo, httpStatus, err := zfssarest.CreateSnapshot(ctx, token, zsnap.zvol.getHref(), zsnap.id.Name)
+	snapinfo, _, err := zsnap.zvol.getClient().CreateSnapshot(ctx, token, zsnap.zvol.getHref(), zsnap.id.Name)
 	if err != nil {
-		if httpStatus != http.StatusConflict {
+		if status.Code(err) != codes.AlreadyExists {
 			zsnap.state = stateDeleted
 			return nil, err
 		}
 		// The creation failed because the source file system already has a snapshot
 		// with the same name.
 		if zsnap.getState() == stateCreated {
-			snapinfo, _, err := zfssarest.GetSnapshot(ctx, token, zsnap.zvol.getHref(), zsnap.id.Name)
+			snapinfo, _, err := zsnap.zvol.getClient().GetSnapshot(ctx, token, zsnap.zvol.getHref(), zsnap.id.Name)
 			if err != nil {
 				return nil, err
 			}
@@ -88,9 +87,9 @@ func (zsnap *zSnapshot) delete(ctx context.Context, token *zfssarest.Token) (
 	utils.GetLogCTRL(ctx, 5).Println("zsnap.delete")
 
 	// Update the snapshot information.
-	httpStatus, err := zsnap.refreshDetails(ctx, token)
+	err := zsnap.refreshDetails(ctx, token)
 	if err != nil {
-		if httpStatus != http.StatusNotFound {
+		if status.Code(err) != codes.NotFound {
 			return nil, err
 		}
 		zsnap.state = stateDeleted
@@ -101,8 +100,8 @@ func (zsnap *zSnapshot) delete(ctx context.Context, token *zfssarest.Token) (
 		return nil, status.Errorf(codes.FailedPrecondition, "Snapshot has (%d) dependents", zsnap.numClones)
 	}
 
-	_, httpStatus, err = zfssarest.DeleteSnapshot(ctx, token, zsnap.href)
-	if err != nil && httpStatus != http.StatusNotFound {
+	_, _, err = zsnap.zvol.getClient().DeleteSnapshot(ctx, token, zsnap.href)
+	if err != nil && status.Code(err) != codes.NotFound {
 		return nil, err
 	}
 
@@ -111,34 +110,34 @@ func (zsnap *zSnapshot) delete(ctx context.Context, token *zfssarest.Token) (
 }
 
 
-func (zsnap *zSnapshot) getDetails(ctx context.Context, token *zfssarest.Token) (int, error) {
+func (zsnap *zSnapshot) getDetails(ctx context.Context, token *zfssarest.Token) error {
 
 	utils.GetLogCTRL(ctx, 5).Println("zsnap.getDetails")
 
-	snapinfo, httpStatus, err := zfssarest.GetSnapshot(ctx, token, zsnap.zvol.getHref(), zsnap.id.Name)
+	snapinfo, _, err := zsnap.zvol.getClient().GetSnapshot(ctx, token, zsnap.zvol.getHref(), zsnap.id.Name)
 	if err != nil {
-		return httpStatus, err
+		return err
 	}
 	zsnap.timeStamp, err = utils.DateToUnix(snapinfo.Creation)
 	if err != nil {
-		return httpStatus, err
+		return err
 	}
 	zsnap.numClones = snapinfo.NumClones
 	zsnap.spaceData = snapinfo.SpaceData
 	zsnap.spaceUnique = snapinfo.SpaceUnique
 	zsnap.href = snapinfo.Href
 	zsnap.state = stateCreated
-	return httpStatus, nil
+	return nil
 }
 
-func (zsnap *zSnapshot) refreshDetails(ctx context.Context, token *zfssarest.Token) (int, error) {
-	snapinfo, httpStatus, err := zfssarest.GetSnapshot(ctx, token, zsnap.zvol.getHref(), zsnap.id.Name)
+func (zsnap *zSnapshot) refreshDetails(ctx context.Context, token *zfssarest.Token) error {
+	snapinfo, _, err := zsnap.zvol.getClient().GetSnapshot(ctx, token, zsnap.zvol.getHref(), zsnap.id.Name)
 	if err == nil {
 		zsnap.numClones = snapinfo.NumClones
 		zsnap.spaceData = snapinfo.SpaceData
 		zsnap.spaceUnique = snapinfo.SpaceUnique
 	}
-	return httpStatus, err
+	return err
 }
 
 // Populate the snapshot structure with the information provided
@@ -196,6 +195,10 @@ func zfssaSnapshotList2csiSnapshotList(ctx context.Context, zfssa string,
 	entries := make([]*csi.ListSnapshotsResponse_Entry, 0, len(snapList))
 
 	for _, snapInfo := range snapList {
+		// The snapshots taken to clone volumes are not reported.
+		if isCloneSnapshot(snapInfo.Name) {
+			continue
+		}
 		sid, err := utils.SnapshotIdStringFromHref(zfssa, snapInfo.Href)
 		if err != nil {
 			continue
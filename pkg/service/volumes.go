@@ -12,7 +12,6 @@ import (
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
-	"net/http"
 	"sync"
 )
 
@@ -93,8 +92,8 @@ type zVolumeInterface interface {
 	cloneSnapshot(ctx context.Context, token *zfssarest.Token,
 		req *csi.CreateVolumeRequest, zsnap *zSnapshot) (*csi.CreateVolumeResponse, error)
 	cloneVolume(ctx context.Context, token *zfssarest.Token,
-		req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error)
-	getDetails(ctx context.Context, token *zfssarest.Token) (int, error)
+		req *csi.CreateVolumeRequest, zsrc zVolumeInterface) (*csi.CreateVolumeResponse, error)
+	getDetails(ctx context.Context, token *zfssarest.Token) error
 	setInfo(volInfo interface{})
 	getSnapshotsList(context.Context, *zfssarest.Token) ([]*csi.ListSnapshotsResponse_Entry, error)
 	hold(ctx context.Context) volumeState
@@ -106,6 +105,7 @@ type zVolumeInterface interface {
 	getHref() string
 	getVolumeID() *utils.VolumeId
 	getCapacity() int64
+	getClient() *zfssarest.Client
 	isBlock() bool
 }
 
@@ -121,21 +121,26 @@ type zVolumeInterface interface {
 //   - A structure representing the volume already exists in the cache and is NOT in
 //     the stateCreated state. This means the CO probably lost state and submitted multiple
 //     simultaneous requests for this volume. In this case an error is returned.
-func (zd *ZFSSADriver) newVolume(ctx context.Context, pool, project, name string,
+func (zd *ZFSSADriver) newVolume(ctx context.Context, zfssa, pool, project, name string,
 	block bool) (zVolumeInterface, error) {
 
+	appliance, err := zd.getAppliance(zfssa)
+	if err != nil {
+		return nil, err
+	}
+
 	var vid *utils.VolumeId
 	var zvolNew zVolumeInterface
 	if block {
-		vid = utils.NewVolumeId(utils.BlockVolume, zd.config.Appliance, pool, project, name)
-		zvolNew = newLUN(vid)
+		vid = utils.NewVolumeId(utils.BlockVolume, appliance.name, pool, project, name)
+		zvolNew = newLUN(appliance.client, vid)
 	} else {
-		vid = utils.NewVolumeId(utils.MountVolume, zd.config.Appliance, pool, project, name)
-		zvolNew = newFilesystem(vid)
+		vid = utils.NewVolumeId(utils.MountVolume, appliance.name, pool, project, name)
+		zvolNew = newFilesystem(appliance.client, vid)
 	}
 
 	zd.vCache.Lock(ctx)
-	zvol := zd.vCache.lookup(ctx, name)
+	zvol := zd.vCache.lookup(ctx, volumeKey(vid))
 	if zvol != nil {
 		// Volume already known.
 		utils.GetLogCTRL(ctx, 5).Println("zd.newVolume", "request")
@@ -148,7 +153,7 @@ func (zd *ZFSSADriver) newVolume(ctx context.Context, pool, project, name string
 		return zvol, nil
 	}
 
-	zd.vCache.add(ctx, name, zvolNew)
+	zd.vCache.add(ctx, volumeKey(vid), zvolNew)
 	zvolNew.hold(ctx)
 	zvolNew.lock(ctx)
 	zd.vCache.Unlock(ctx)
@@ -172,7 +177,7 @@ func (zd *ZFSSADriver) lookupVolume(ctx context.Context, token *zfssarest.Token,
 	// Check first in the list of volumes if the volume is already known.
 	zd.vCache.RLock(ctx)
 
-	zvol := zd.vCache.lookup(ctx, vid.Name)
+	zvol := zd.vCache.lookup(ctx, volumeKey(vid))
 	if zvol != nil {
 		zvol.hold(ctx)
 		zd.vCache.RUnlock(ctx)
@@ -187,17 +192,17 @@ func (zd *ZFSSADriver) lookupVolume(ctx context.Context, token *zfssarest.Token,
 
 	// Create a context for the new volume. The new context will act as a place holder
 	// for the name passed in.
-	zvol, err = zd.newVolume(ctx, vid.Pool, vid.Project, vid.Name, vid.Type == utils.BlockVolume)
+	zvol, err = zd.newVolume(ctx, vid.Zfssa, vid.Pool, vid.Project, vid.Name, vid.Type == utils.BlockVolume)
 	if err != nil {
 		return nil, err
 	}
 
 	switch zvol.getState() {
 	case stateCreating: // We check with the appliance.
-		httpStatus, err := zvol.getDetails(ctx, token)
+		err := zvol.getDetails(ctx, token)
 		if err != nil {
 			zd.releaseVolume(ctx, zvol)
-			if httpStatus == http.StatusNotFound {
+			if status.Code(err) == codes.NotFound {
 				return nil, status.Errorf(codes.NotFound, "Volume (%s) not found", volumeId)
 			}
 			return nil, err
@@ -220,7 +225,7 @@ func (zd *ZFSSADriver) releaseVolume(ctx context.Context, zvol zVolumeInterface)
 		zd.vCache.Lock(ctx)
 		refCount, state = zvol.unlock(ctx)
 		if refCount == 0 && state != stateCreated {
-			zd.vCache.delete(ctx, zvol.getName())
+			zd.vCache.delete(ctx, volumeKey(zvol.getVolumeID()))
 		}
 		zd.vCache.Unlock(ctx)
 	} else {
@@ -255,12 +260,12 @@ func (zd *ZFSSADriver) newSnapshot(ctx context.Context, token *zfssarest.Token,
 
 	zd.sCache.Lock(ctx)
 
-	zsnap := zd.sCache.lookup(ctx, name)
+	zsnap := zd.sCache.lookup(ctx, snapshotKey(sid))
 	if zsnap == nil {
 		// Snapshot doesn't exist or is unknown.
 		zsnap := newSnapshot(sid, zvol)
 		_ = zsnap.hold(ctx)
-		zd.sCache.add(ctx, name, zsnap)
+		zd.sCache.add(ctx, snapshotKey(sid), zsnap)
 		zd.sCache.Unlock(ctx)
 		zsnap.lock(ctx)
 		return zsnap, nil
@@ -317,7 +322,7 @@ func (zd *ZFSSADriver) lookupSnapshot(ctx context.Context, token *zfssarest.Toke
 	}
 
 	zd.sCache.RLock(ctx)
-	zsnap = zd.sCache.lookup(ctx, sid.Name)
+	zsnap = zd.sCache.lookup(ctx, snapshotKey(sid))
 	if zsnap != nil {
 		if zsnap.getSourceVolume() != zvol {
 			// This is a serious problem. It means the volume source found using
@@ -347,7 +352,7 @@ func (zd *ZFSSADriver) lookupSnapshot(ctx context.Context, token *zfssarest.Toke
 
 	switch zsnap.getState() {
 	case stateCreating: // We check with the appliance.
-		_, err = zsnap.getDetails(ctx, token)
+		err = zsnap.getDetails(ctx, token)
 		if err != nil {
 			zd.releaseSnapshot(ctx, zsnap)
 			return nil, err
@@ -375,7 +380,7 @@ func (zd *ZFSSADriver) releaseSnapshot(ctx context.Context, zsnap *zSnapshot) {
 		zd.sCache.Lock(ctx)
 		refCount, state = zsnap.unlock(ctx)
 		if refCount == 0 && state != stateCreated {
-			zd.sCache.delete(ctx, zsnap.getName())
+			zd.sCache.delete(ctx, snapshotKey(zsnap.id))
 		}
 		zd.sCache.Unlock(ctx)
 	} else {
@@ -395,8 +400,11 @@ func (zd *ZFSSADriver) getVolumesList(ctx context.Context) ([]*csi.ListVolumesRe
 	}
 
 	zd.vCache.RLock(ctx)
-	entries := make([]*csi.ListVolumesResponse_Entry, len(zd.sCache.sHash))
+	entries := make([]*csi.ListVolumesResponse_Entry, 0, len(zd.vCache.vHash))
 	for _, zvol := range zd.vCache.vHash {
+		if !zd.volumeInScope(zvol.getVolumeID()) {
+			continue
+		}
 		entry := new(csi.ListVolumesResponse_Entry)
 		entry.Volume = &csi.Volume{
 			VolumeId:      zvol.getVolumeID().String(),
@@ -409,42 +417,68 @@ func (zd *ZFSSADriver) getVolumesList(ctx context.Context) ([]*csi.ListVolumesRe
 	return entries, nil
 }
 
-// Retrieves the list of LUNs and filesystems from the appliance and updates
-// the local list.
+// Retrieves the list of LUNs and filesystems from the appliances and updates
+// the local list. The appliances are queried concurrently.
 func (zd *ZFSSADriver) updateVolumeList(ctx context.Context) error {
+	return zd.updateAppliances(ctx, zd.updateApplianceVolumeList)
+}
 
-	fsChan := make(chan error)
-	lunChan := make(chan error)
-	go zd.updateFilesystemList(ctx, fsChan)
-	go zd.updateLunList(ctx, lunChan)
-	errfs := <-fsChan
-	errlun := <-lunChan
+// Retrieves the list of LUNs and filesystems of the scopes of an appliance (see
+// scope.go) and updates the local list.
+func (zd *ZFSSADriver) updateApplianceVolumeList(ctx context.Context, zfssa string) error {
 
-	if errfs != nil {
-		return errfs
-	} else if errlun != nil {
-		return errlun
+	appliance, err := zd.getAppliance(zfssa)
+	if err != nil {
+		return err
+	}
+	_, token, err := zd.lookUpToken(ctx, zfssa, nil)
+	if err != nil {
+		return err
+	}
+	projects, err := appliance.listProjects(ctx, token)
+	if err != nil {
+		utils.GetLogCTRL(ctx, 2).Println("zd.updateVolumeList failed to list the projects",
+			"appliance", zfssa, "error", err.Error())
+		return err
+	}
+	if projects == nil {
+		// The whole appliance
+		projects = []listScope{{}}
 	}
 
-	return nil
+	return fetchScopes(projects, func(scope listScope) error {
+		fsChan := make(chan error)
+		lunChan := make(chan error)
+		go zd.updateFilesystemList(ctx, zfssa, scope, fsChan)
+		go zd.updateLunList(ctx, zfssa, scope, lunChan)
+		errfs := <-fsChan
+		errlun := <-lunChan
+
+		if errfs != nil {
+			return errfs
+		}
+		return errlun
+	})
 }
 
-// Asks the appliance for the list of filesystems and updates the local list of volumes.
-func (zd *ZFSSADriver) updateFilesystemList(ctx context.Context, out chan<- error) {
+// Asks the appliance for the list of filesystems of a project (all of them if the scope
+// is empty) and updates the local list of volumes.
+func (zd *ZFSSADriver) updateFilesystemList(ctx context.Context, zfssa string, scope listScope,
+	out chan<- error) {
 
-	utils.GetLogCTRL(ctx, 5).Println("zd.updateFilesystemList")
+	utils.GetLogCTRL(ctx, 5).Println("zd.updateFilesystemList", "appliance", zfssa, "scope", scope)
 
-	user, password, err := zd.getUserLogin(ctx, nil)
+	client, token, err := zd.lookUpToken(ctx, zfssa, nil)
 	if err != nil {
 		out <- err
+		return
 	}
-	token := zfssarest.LookUpToken(ctx, user, password)
-	fsList, err := zfssarest.GetFilesystems(ctx, token, "", "")
+	fsList, err := client.GetFilesystems(ctx, token, scope.pool, scope.project)
 	if err != nil {
 		utils.GetLogCTRL(ctx, 2).Println("zd.updateFilesystemList failed", "error", err.Error())
 	} else {
 		for _, fsInfo := range fsList {
-			zvol, err := zd.newVolume(ctx, fsInfo.Pool, fsInfo.Project, fsInfo.Name, false)
+			zvol, err := zd.newVolume(ctx, zfssa, fsInfo.Pool, fsInfo.Project, fsInfo.Name, false)
 			if err != nil {
 				continue
 			}
@@ -455,23 +489,24 @@ func (zd *ZFSSADriver) updateFilesystemList(ctx context.Context, out chan<- erro
 	out <- err
 }
 
-// Asks the appliance for the list of LUNs and updates the local list of volumes.
-func (zd *ZFSSADriver) updateLunList(ctx context.Context, out chan<- error) {
+// Asks the appliance for the list of LUNs of a project (all of them if the scope is
+// empty) and updates the local list of volumes.
+func (zd *ZFSSADriver) updateLunList(ctx context.Context, zfssa string, scope listScope, out chan<- error) {
 
-	utils.GetLogCTRL(ctx, 5).Println("zd.updateLunList")
+	utils.GetLogCTRL(ctx, 5).Println("zd.updateLunList", "appliance", zfssa, "scope", scope)
 
-	user, password, err := zd.getUserLogin(ctx, nil)
+	client, token, err := zd.lookUpToken(ctx, zfssa, nil)
 	if err != nil {
 		out <- err
+		return
 	}
-	token := zfssarest.LookUpToken(ctx, user, password)
 
-	lunList, err := zfssarest.GetLuns(ctx, token, "", "")
+	lunList, err := client.GetLuns(ctx, token, scope.pool, scope.project)
 	if err != nil {
 		utils.GetLogCTRL(ctx, 2).Println("zd.updateLunList failed", "error", err.Error())
 	} else {
 		for _, lunInfo := range lunList {
-			zvol, err := zd.newVolume(ctx, lunInfo.Pool, lunInfo.Project, lunInfo.Name, true)
+			zvol, err := zd.newVolume(ctx, zfssa, lunInfo.Pool, lunInfo.Project, lunInfo.Name, true)
 			if err != nil {
 				continue
 			}
@@ -496,6 +531,9 @@ func (zd *ZFSSADriver) getSnapshotList(ctx context.Context) ([]*csi.ListSnapshot
 	zd.sCache.RLock(ctx)
 	entries := make([]*csi.ListSnapshotsResponse_Entry, 0, len(zd.sCache.sHash))
 	for _, zsnap := range zd.sCache.sHash {
+		if !zd.volumeInScope(zsnap.id.VolumeId) {
+			continue
+		}
 		entry := new(csi.ListSnapshotsResponse_Entry)
 		entry.Snapshot = &csi.Snapshot{
 			SizeBytes:      zsnap.getSize(),
@@ -511,25 +549,100 @@ func (zd *ZFSSADriver) getSnapshotList(ctx context.Context) ([]*csi.ListSnapshot
 	return entries, nil
 }
 
-// Requests the list of snapshots from the appliance and updates the local list. Only
-// snapshots that can be identified as filesytem snapshots or lun snapshots are kept.
+// Requests the list of snapshots from the appliances and updates the local list. The
+// appliances are queried concurrently.
 func (zd *ZFSSADriver) updateSnapshotList(ctx context.Context) error {
+	return zd.updateAppliances(ctx, zd.updateApplianceSnapshotList)
+}
 
-	utils.GetLogCTRL(ctx, 5).Println("zd.updateSnapshotList")
+// Requests the list of snapshots from an appliance and updates the local list. Only
+// snapshots that can be identified as filesytem snapshots or lun snapshots and that
+// belong to the scopes of the appliance are kept. Without scope, all the snapshots of
+// the appliance are read at once. With scopes, the appliance having no collection of the
+// share snapshots of a project, the snapshots of each share of the projects of the
+// scopes are read, the projects being queried concurrently.
+func (zd *ZFSSADriver) updateApplianceSnapshotList(ctx context.Context, zfssa string) error {
 
-	user, password, err := zd.getUserLogin(ctx, nil)
+	utils.GetLogCTRL(ctx, 5).Println("zd.updateSnapshotList", "appliance", zfssa)
+
+	appliance, err := zd.getAppliance(zfssa)
+	if err != nil {
+		return err
+	}
+	client, token, err := zd.lookUpToken(ctx, zfssa, nil)
 	if err != nil {
 		utils.GetLogCTRL(ctx, 2).Println("Authentication error", "error", err.Error())
 		return err
 	}
-
-	token := zfssarest.LookUpToken(ctx, user, password)
-	snapList, err := zfssarest.GetSnapshots(ctx, token, "")
+	projects, err := appliance.listProjects(ctx, token)
 	if err != nil {
-		utils.GetLogCTRL(ctx, 2).Println("zd.updateSnapshotList failed", "error", err.Error())
+		utils.GetLogCTRL(ctx, 2).Println("zd.updateSnapshotList failed to list the projects",
+			"appliance", zfssa, "error", err.Error())
 		return err
 	}
 
+	if projects == nil {
+		snapList, err := client.GetSnapshots(ctx, token, "")
+		if err != nil {
+			utils.GetLogCTRL(ctx, 2).Println("zd.updateSnapshotList failed", "error", err.Error())
+			return err
+		}
+		zd.addSnapshotList(ctx, appliance, token, snapList)
+		return nil
+	}
+
+	return fetchScopes(projects, func(scope listScope) error {
+		snapList, err := projectSnapshots(ctx, client, token, scope)
+		if err != nil {
+			utils.GetLogCTRL(ctx, 2).Println("zd.updateSnapshotList failed", "scope", scope,
+				"error", err.Error())
+			return err
+		}
+		zd.addSnapshotList(ctx, appliance, token, snapList)
+		return nil
+	})
+}
+
+// Returns the snapshots of the file systems and LUNs of the project passed in.
+func projectSnapshots(ctx context.Context, client *zfssarest.Client, token *zfssarest.Token,
+	scope listScope) ([]zfssarest.Snapshot, error) {
+
+	fsList, err := client.GetFilesystems(ctx, token, scope.pool, scope.project)
+	if err != nil {
+		return nil, err
+	}
+	lunList, err := client.GetLuns(ctx, token, scope.pool, scope.project)
+	if err != nil {
+		return nil, err
+	}
+
+	hrefs := make([]string, 0, len(fsList)+len(lunList))
+	for _, fsInfo := range fsList {
+		hrefs = append(hrefs, fsInfo.Href)
+	}
+	for _, lunInfo := range lunList {
+		hrefs = append(hrefs, lunInfo.Href)
+	}
+
+	var snapList []zfssarest.Snapshot
+	for _, href := range hrefs {
+		list, err := client.GetSnapshots(ctx, token, href)
+		if status.Code(err) == codes.NotFound {
+			// Share deleted since the projects were listed.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		snapList = append(snapList, list...)
+	}
+	return snapList, nil
+}
+
+// Adds the snapshots passed in to the local list.
+func (zd *ZFSSADriver) addSnapshotList(ctx context.Context, appliance *zAppliance, token *zfssarest.Token,
+	snapList []zfssarest.Snapshot) {
+
 	for _, snapInfo := range snapList {
 		utils.GetLogCTRL(ctx, 5).Println("zd.updateSnapshotList", "snapshotInfo", snapInfo)
 		sid, err := utils.SnapshotIdFromHref(token.Name, snapInfo.Href)
@@ -537,6 +650,9 @@ func (zd *ZFSSADriver) updateSnapshotList(ctx context.Context) error {
 			utils.GetLogCTRL(ctx, 2).Println("zd.updateSnapshotList snapshotIdFromHref", "err", err)
 			continue
 		}
+		if !appliance.inScope(sid.VolumeId.Pool, sid.VolumeId.Project) || isCloneSnapshot(snapInfo.Name) {
+			continue
+		}
 		utils.GetLogCTRL(ctx, 2).Println("zd.updateSnapshotList newSnapshot")
 		zsnap, err := zd.newSnapshot(ctx, token, snapInfo.Name, sid.VolumeId.String())
 		if err != nil {
@@ -551,8 +667,6 @@ func (zd *ZFSSADriver) updateSnapshotList(ctx context.Context) error {
 		}
 		zd.releaseSnapshot(ctx, zsnap)
 	}
-
-	return nil
 }
 
 func compareCapacityRange(req *csi.CapacityRange, capacity int64) bool {
@@ -589,6 +703,17 @@ func compareCapabilities(req []*csi.VolumeCapability, cur []csi.VolumeCapability
 	return foundAll
 }
 
+// Key of a volume in the volume cache. Volumes of different appliances may have
+// the same name.
+func volumeKey(vid *utils.VolumeId) string {
+	return vid.Zfssa + "/" + vid.Name
+}
+
+// Key of a snapshot in the snapshot cache.
+func snapshotKey(sid *utils.SnapshotId) string {
+	return sid.VolumeId.Zfssa + "/" + sid.Name
+}
+
 type volumeHashTable struct {
 	vMutex sync.RWMutex
 	vHash  map[string]zVolumeInterface
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	grpcStatus "google.golang.org/grpc/status"
+)
+
+// A request failing on the appliance is answered with an HTTP status and, most of the
+// time, a fault:
+//
+//	{"fault": {"message": "...", "code": 400, "name": "ERR_INVALID_ARG"}}
+//
+// The failure is translated into a gRPC status error carrying the message of the fault
+// so that the callers (and ultimately the sidecars) can tell permanent failures from
+// those worth retrying:
+//
+//	400             InvalidArgument
+//	401             Unauthenticated
+//	403             PermissionDenied
+//	404             NotFound
+//	409             AlreadyExists
+//	413, 507        ResourceExhausted
+//	501             Unimplemented
+//	502, 503, 504   Unavailable
+//	other           Unknown
+//
+// Whatever the HTTP status, a fault reporting a lack of space is translated into
+// ResourceExhausted.
+
+// Fragments of the fault messages (lower case) of the appliance reporting a lack of space.
+var outOfSpaceMessages = []string{
+	"out of space",
+	"insufficient space",
+	"not enough space",
+	"no space",
+	"quota exceeded",
+	"exceeds quota",
+}
+
+// Returns the gRPC code matching the HTTP status and the fault returned by the appliance.
+func faultCode(httpStatus int, fault *faultInfo) codes.Code {
+
+	if fault != nil && isOutOfSpaceFault(fault) {
+		return codes.ResourceExhausted
+	}
+
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusRequestEntityTooLarge, http.StatusInsufficientStorage:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return codes.Unavailable
+	}
+	return codes.Unknown
+}
+
+// Returns true if the fault reports a lack of space in the pool or a quota reached.
+func isOutOfSpaceFault(fault *faultInfo) bool {
+	if fault.Code == http.StatusInsufficientStorage || strings.Contains(fault.Name, "ENOSPC") ||
+		strings.Contains(fault.Name, "INSUFFICIENT_STORAGE") {
+		return true
+	}
+	message := strings.ToLower(fault.Message)
+	for _, fragment := range outOfSpaceMessages {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// Translates the failed response of the appliance (HTTP status and body) into a gRPC
+// status error. The message of the fault (or the body if it is not a fault) is kept.
+func faultError(httpStatus int, body []byte) error {
+
+	var fault *faultInfo
+	message := strings.TrimSpace(string(body))
+	failure := &faultResponse{}
+	if err := json.Unmarshal(body, failure); err == nil && len(failure.Fault.Message) > 0 {
+		fault = &failure.Fault
+		message = fault.Message
+	}
+
+	code := faultCode(httpStatus, fault)
+	switch code {
+	case codes.NotFound:
+		return grpcStatus.Errorf(code, "Resource not found on target appliance: %s", message)
+	case codes.Unknown:
+		return grpcStatus.Errorf(code, "Unknown Error Occurred on target appliance: %s", message)
+	}
+	return grpcStatus.Errorf(code, "Target appliance failed the request (%d): %s", httpStatus, message)
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest/zfssatest"
+	"google.golang.org/grpc/codes"
+	grpcStatus "google.golang.org/grpc/status"
+)
+
+func TestFaultError(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   codes.Code
+	}{
+		{400, `{"fault": {"message": "invalid input argument", "code": 400, "name": "ERR_INVALID_ARG"}}`,
+			codes.InvalidArgument},
+		{403, `{"fault": {"message": "permission denied", "code": 403, "name": "ERR_DENIED"}}`,
+			codes.PermissionDenied},
+		{404, `{"fault": {"message": "no such share", "code": 404, "name": "ERR_NOT_FOUND"}}`,
+			codes.NotFound},
+		{409, `{"fault": {"message": "share already exists", "code": 409, "name": "ERR_OBJECT_EXISTS"}}`,
+			codes.AlreadyExists},
+		{400, `{"fault": {"message": "Pool is out of space", "code": 400, "name": "ERR_INVALID_ARG"}}`,
+			codes.ResourceExhausted},
+		{500, `{"fault": {"message": "quota exceeded for project", "code": 500, "name": "ERR_INTERNAL"}}`,
+			codes.ResourceExhausted},
+		{507, `insufficient storage`, codes.ResourceExhausted},
+		{503, `{"fault": {"message": "appliance busy", "code": 503, "name": "ERR_BUSY"}}`,
+			codes.Unavailable},
+		{500, `<html>internal error</html>`, codes.Unknown},
+	}
+	for _, tt := range tests {
+		err := faultError(tt.status, []byte(tt.body))
+		if grpcStatus.Code(err) != tt.want {
+			t.Errorf("faultError(%d, %s) = %v, want %v", tt.status, tt.body, err, tt.want)
+		}
+	}
+
+	// The message of the appliance is preserved.
+	err := faultError(400, []byte(`{"fault": {"message": "invalid recordsize", "code": 400}}`))
+	if !strings.Contains(grpcStatus.Convert(err).Message(), "invalid recordsize") {
+		t.Errorf("message of the fault lost: %v", err)
+	}
+}
+
+func TestFaultCodes(t *testing.T) {
+	zfssa, c, token := newTestAppliance(t)
+	c.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+	ctx := context.Background()
+	parameters := map[string]string{"pool": testPool, "project": testProject}
+
+	if _, _, err := c.CreateFilesystem(ctx, token, "vol1", 1<<30, &parameters, nil); err != nil {
+		t.Fatalf("CreateFilesystem failed: %v", err)
+	}
+	if _, _, err := c.CreateFilesystem(ctx, token, "vol1", 1<<30, &parameters, nil); grpcStatus.Code(err) != codes.AlreadyExists {
+		t.Errorf("expected AlreadyExists, got %v", err)
+	}
+
+	zfssa.AddFault(zfssatest.Fault{Method: "POST", Path: "/filesystems", Status: http.StatusBadRequest,
+		Message: "out of space", Times: 1})
+	if _, _, err := c.CreateFilesystem(ctx, token, "vol2", 1<<30, &parameters, nil); grpcStatus.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expected ResourceExhausted, got %v", err)
+	}
+
+	zfssa.AddFault(zfssatest.Fault{Method: "GET", Status: http.StatusServiceUnavailable, Times: 1})
+	if _, err := c.GetPool(ctx, token, testPool); grpcStatus.Code(err) != codes.Unavailable {
+		t.Errorf("expected Unavailable, got %v", err)
+	}
+
+	// An appliance that cannot be reached is unavailable.
+	zfssa.Close()
+	if _, err := c.GetPool(ctx, token, testPool); grpcStatus.Code(err) != codes.Unavailable {
+		t.Errorf("expected Unavailable, got %v", err)
+	}
+}
@@ -8,6 +8,7 @@ package zfssarest
 import (
 	"github.com/oracle/zfssa-csi-driver/pkg/utils"
 	"context"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"google.golang.org/grpc/codes"
@@ -39,6 +40,10 @@ type Filesystem struct {
 	SpaceUnused			int64	`json:"space_unused_res"`
 	Project				string	`json:"project"`
 	Href				string	`json:"href"`
+	Origin				Origin	`json:"origin"`
+	NfsExport			string	`json:"custom:zcsiNfsExport"`
+	NfsNodes			string	`json:"custom:zcsiNfsNodes"`
+	Owner
 }
 
 type filesystemJSON struct {
@@ -56,19 +61,32 @@ var yml2fsProperty = map[string]string {
 	"rootGroup":"root_group",
 	"rootPermissions":"root_permissions",
 	"shareNFS":"sharenfs",
+	"shareSMB":"sharesmb",
 	"restrictChown":"rstchown",
+	"nfsExport":"custom:" + NfsExport,
 }
 
-func CreateFilesystem(ctx context.Context, token *Token, fsname string, volSize int64, 
-	parameters *map[string]string) (*Filesystem, int, error) {
+func (c *Client) CreateFilesystem(ctx context.Context, token *Token, fsname string, volSize int64, 
+	parameters *map[string]string, owner *Owner) (*Filesystem, int, error) {
 
 	pool := (*parameters)["pool"]
 	project := (*parameters)["project"]
-	url := fmt.Sprintf(zFilesystems, token.Name, pool, project)
+	url := fmt.Sprintf(zFilesystems, c.address, pool, project)
 	reqBody := buildFilesystemReq(ctx, fsname, volSize, parameters)
+	properties, err := FilesystemProperties(*parameters)
+	if err != nil {
+		return nil, 0, err
+	}
+	for name, value := range properties {
+		(*reqBody)[name] = value
+	}
+	for name, value := range owner.Properties() {
+		(*reqBody)[name] = value
+	}
 	rspBody := new(filesystemJSON)
 
-	_, code, err := MakeRequest(ctx, token, "POST", url, reqBody, http.StatusCreated, rspBody)
+	objectURL := fmt.Sprintf(zFilesystem, c.address, pool, project, fsname)
+	_, code, err := c.MakeCreateRequest(ctx, token, "POST", url, reqBody, http.StatusCreated, rspBody, objectURL)
 	if err != nil {
 		return nil, code, err
 	}
@@ -110,13 +128,13 @@ func buildFilesystemReq(ctx context.Context, name string, volSize int64,
 	return &fsReq
 }
 
-func GetFilesystem(ctx context.Context, token *Token, pool, project, filesystem string) (
+func (c *Client) GetFilesystem(ctx context.Context, token *Token, pool, project, filesystem string) (
 	*Filesystem, int, error) {
 
-	url := fmt.Sprintf(zFilesystem, token.Name, pool, project, filesystem)
+	url := fmt.Sprintf(zFilesystem, c.address, pool, project, filesystem)
 
 	rspJSON := &filesystemJSON{}
-	_, httpStatus, err := MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, rspJSON)
+	_, httpStatus, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, rspJSON)
 	if err != nil {
 		return nil, httpStatus, err
 	}
@@ -124,13 +142,13 @@ func GetFilesystem(ctx context.Context, token *Token, pool, project, filesystem
 	return &rspJSON.FileSystem, httpStatus, nil
 }
 
-func ModifyFilesystem(ctx context.Context, token *Token, href string, 
+func (c *Client) ModifyFilesystem(ctx context.Context, token *Token, href string, 
 	parameters *map[string]interface{}) (*Filesystem, int, error) {
 
-	url := fmt.Sprintf(zAppliance + href, token.Name)
+	url := fmt.Sprintf(zAppliance + href, c.address)
 
 	rspJSON := &filesystemJSON{}
-	_, httpStatus, err := MakeRequest(ctx, token, "PUT", url, parameters, http.StatusAccepted, rspJSON)
+	_, httpStatus, err := c.MakeRequest(ctx, token, "PUT", url, parameters, http.StatusAccepted, rspJSON)
 	if err != nil {
 		return nil, httpStatus, err
 	}
@@ -138,13 +156,13 @@ func ModifyFilesystem(ctx context.Context, token *Token, href string,
 	return &rspJSON.FileSystem, httpStatus, nil
 }
 
-func DeleteFilesystem(ctx context.Context, token *Token, hRef string) (bool, int, error) {
+func (c *Client) DeleteFilesystem(ctx context.Context, token *Token, hRef string) (bool, int, error) {
 
 	utils.GetLogREST(ctx, 5).Println("DeleteFilesystem", "appliance", token.Name, "Filesystem", hRef)
 
-	url := fmt.Sprintf(zAppliance + hRef, token.Name)
+	url := fmt.Sprintf(zAppliance + hRef, c.address)
 
-	_, httpStatus, err := MakeRequest(ctx, token, "DELETE", url, nil, http.StatusNoContent, nil)
+	_, httpStatus, err := c.MakeRequest(ctx, token, "DELETE", url, nil, http.StatusNoContent, nil)
 	if err != nil {
 		return false, httpStatus, err
 	}
@@ -161,13 +179,13 @@ func DeleteFilesystem(ctx context.Context, token *Token, hRef string) (bool, int
 
 // Returns the List of filesystems associated with the pool and project passed in. To
 // get a system wide List of file systems, the pool AND the project must be 'nil'
-func GetFilesystems(ctx context.Context, token *Token, pool, project string) ([]Filesystem, error) {
+func (c *Client) GetFilesystems(ctx context.Context, token *Token, pool, project string) ([]Filesystem, error) {
 
 	var url string
 	if pool != "" && project != "" {
-		url = fmt.Sprintf(zFilesystems, token.Name, pool, project)
+		url = fmt.Sprintf(zFilesystems, c.address, pool, project)
 	} else if pool == "" && project == "" {
-		url = fmt.Sprintf(zAllFilesystems, token.Name)
+		url = fmt.Sprintf(zAllFilesystems, c.address)
 	} else {
 		return nil, grpcStatus.Error(codes.InvalidArgument, "pool and project must be both nil or both not nil")
 	}
@@ -175,7 +193,7 @@ func GetFilesystems(ctx context.Context, token *Token, pool, project string) ([]
 	filesystems := new(filesystems)
 	filesystems.List = make([]Filesystem, 0)
 
-	_, _, err := MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, filesystems)
+	_, _, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, filesystems)
 	if err != nil {
 		return nil, err
 	}
@@ -183,18 +201,19 @@ func GetFilesystems(ctx context.Context, token *Token, pool, project string) ([]
 	return filesystems.List, nil
 }
 
-func (l *filesystems) UnmarshalJSON(b []byte) error {
-	return zfssaUnmarshalList(b, &l.List)
+func (l *filesystems) decodeList(dec *json.Decoder) error {
+	return decodeList(dec, "filesystems", &l.List)
 }
 
-func CloneFileSystemSnapshot(ctx context.Context, token *Token, hRef string, 
+func (c *Client) CloneFileSystemSnapshot(ctx context.Context, token *Token, hRef string, 
 	parameters map[string]interface{}) (*Filesystem, int, error) {
 
-	url := fmt.Sprintf(zAppliance + hRef + "/clone", token.Name)
+	url := fmt.Sprintf(zAppliance + hRef + "/clone", c.address)
 
 	rspBody := new(filesystemJSON)
 
-	_, code, err := MakeRequest(ctx, token, "PUT", url, &parameters, http.StatusCreated, rspBody)
+	objectURL := fmt.Sprintf(zFilesystem, c.address, poolFromHref(hRef), parameters["project"], parameters["share"])
+	_, code, err := c.MakeCreateRequest(ctx, token, "PUT", url, &parameters, http.StatusCreated, rspBody, objectURL)
 	if err != nil {
 		return nil, code, err
 	}
@@ -0,0 +1,207 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/oracle/zfssa-csi-driver/pkg/utils"
+)
+
+// The initiators and initiator groups are defined per protocol ("iscsi" or "fc"). An
+// initiator is identified by its name (IQN for iSCSI, WWN for Fibre Channel) and an
+// initiator group by its name. A LUN is only visible to the initiators of the groups
+// listed in its "initiatorgroup" property.
+
+type Initiator struct {
+	Alias     string `json:"alias"`
+	Initiator string `json:"initiator"`
+	Href      string `json:"href"`
+}
+
+type initiatorJSON struct {
+	Initiator Initiator `json:"initiator"`
+}
+
+type initiators struct {
+	List []Initiator `json:"initiators"`
+}
+
+func (l *initiators) decodeList(dec *json.Decoder) error {
+	return decodeList(dec, "initiators", &l.List)
+}
+
+type InitiatorGroup struct {
+	Name       string   `json:"name"`
+	Initiators []string `json:"initiators"`
+	Href       string   `json:"href"`
+}
+
+type initiatorGroupJSON struct {
+	Group InitiatorGroup `json:"group"`
+}
+
+type initiatorGroups struct {
+	List []InitiatorGroup `json:"groups"`
+}
+
+func (l *initiatorGroups) decodeList(dec *json.Decoder) error {
+	return decodeList(dec, "groups", &l.List)
+}
+
+// Returns the initiators of the protocol passed in.
+func (c *Client) GetInitiators(ctx context.Context, token *Token, protocol string) ([]Initiator, error) {
+
+	url := fmt.Sprintf(zInitiators, c.address, protocol)
+
+	list := new(initiators)
+	_, _, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, list)
+	if err != nil {
+		return nil, err
+	}
+
+	return list.List, nil
+}
+
+// Returns the initiator whose name (IQN or WWN) is passed in.
+func (c *Client) GetInitiator(ctx context.Context, token *Token, protocol, name string) (*Initiator, error) {
+
+	url := fmt.Sprintf(zInitiator, c.address, protocol, name)
+
+	rspJSON := &initiatorJSON{}
+	_, _, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, rspJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rspJSON.Initiator, nil
+}
+
+// Issues a request to the appliance to define an initiator.
+func (c *Client) CreateInitiator(ctx context.Context, token *Token, protocol, name, alias string) (
+	*Initiator, int, error) {
+
+	utils.GetLogREST(ctx, 5).Println("CreateInitiator", "appliance", token.Name, "protocol", protocol,
+		"initiator", name, "alias", alias)
+
+	url := fmt.Sprintf(zInitiators, c.address, protocol)
+	reqBody := map[string]interface{}{
+		"initiator": name,
+		"alias":     alias,
+	}
+
+	rspJSON := &initiatorJSON{}
+	objectURL := fmt.Sprintf(zInitiator, c.address, protocol, name)
+	_, httpStatus, err := c.MakeCreateRequest(ctx, token, "POST", url, reqBody, http.StatusCreated, rspJSON,
+		objectURL)
+	if err != nil {
+		return nil, httpStatus, err
+	}
+
+	return &rspJSON.Initiator, httpStatus, nil
+}
+
+// Issues a request to the appliance to remove an initiator.
+func (c *Client) DeleteInitiator(ctx context.Context, token *Token, protocol, name string) (int, error) {
+
+	utils.GetLogREST(ctx, 5).Println("DeleteInitiator", "appliance", token.Name, "protocol", protocol,
+		"initiator", name)
+
+	url := fmt.Sprintf(zInitiator, c.address, protocol, name)
+
+	_, httpStatus, err := c.MakeRequest(ctx, token, "DELETE", url, nil, http.StatusNoContent, nil)
+	return httpStatus, err
+}
+
+// Returns the initiator groups of the protocol passed in.
+func (c *Client) GetInitiatorGroups(ctx context.Context, token *Token, protocol string) ([]InitiatorGroup, error) {
+
+	url := fmt.Sprintf(zInitiatorGroups, c.address, protocol)
+
+	list := new(initiatorGroups)
+	_, _, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, list)
+	if err != nil {
+		return nil, err
+	}
+
+	return list.List, nil
+}
+
+// Returns the initiator group whose name is passed in.
+func (c *Client) GetInitiatorGroup(ctx context.Context, token *Token, protocol, name string) (
+	*InitiatorGroup, error) {
+
+	url := fmt.Sprintf(zInitiatorGroup, c.address, protocol, name)
+
+	rspJSON := &initiatorGroupJSON{}
+	_, _, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, rspJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rspJSON.Group, nil
+}
+
+// Issues a request to the appliance to create an initiator group made of the initiators
+// passed in.
+func (c *Client) CreateInitiatorGroup(ctx context.Context, token *Token, protocol, name string,
+	initiators []string) (*InitiatorGroup, int, error) {
+
+	utils.GetLogREST(ctx, 5).Println("CreateInitiatorGroup", "appliance", token.Name, "protocol", protocol,
+		"group", name, "initiators", initiators)
+
+	url := fmt.Sprintf(zInitiatorGroups, c.address, protocol)
+	reqBody := map[string]interface{}{
+		"name":       name,
+		"initiators": initiators,
+	}
+
+	rspJSON := &initiatorGroupJSON{}
+	objectURL := fmt.Sprintf(zInitiatorGroup, c.address, protocol, name)
+	_, httpStatus, err := c.MakeCreateRequest(ctx, token, "POST", url, reqBody, http.StatusCreated, rspJSON,
+		objectURL)
+	if err != nil {
+		return nil, httpStatus, err
+	}
+
+	return &rspJSON.Group, httpStatus, nil
+}
+
+// Issues a request to the appliance to replace the initiators of an initiator group.
+func (c *Client) ModifyInitiatorGroup(ctx context.Context, token *Token, protocol, name string,
+	initiators []string) (*InitiatorGroup, int, error) {
+
+	utils.GetLogREST(ctx, 5).Println("ModifyInitiatorGroup", "appliance", token.Name, "protocol", protocol,
+		"group", name, "initiators", initiators)
+
+	url := fmt.Sprintf(zInitiatorGroup, c.address, protocol, name)
+	reqBody := map[string]interface{}{
+		"initiators": initiators,
+	}
+
+	rspJSON := &initiatorGroupJSON{}
+	_, httpStatus, err := c.MakeRequest(ctx, token, "PUT", url, reqBody, http.StatusAccepted, rspJSON)
+	if err != nil {
+		return nil, httpStatus, err
+	}
+
+	return &rspJSON.Group, httpStatus, nil
+}
+
+// Issues a request to the appliance to delete an initiator group.
+func (c *Client) DeleteInitiatorGroup(ctx context.Context, token *Token, protocol, name string) (int, error) {
+
+	utils.GetLogREST(ctx, 5).Println("DeleteInitiatorGroup", "appliance", token.Name, "protocol", protocol,
+		"group", name)
+
+	url := fmt.Sprintf(zInitiatorGroup, c.address, protocol, name)
+
+	_, httpStatus, err := c.MakeRequest(ctx, token, "DELETE", url, nil, http.StatusNoContent, nil)
+	return httpStatus, err
+}
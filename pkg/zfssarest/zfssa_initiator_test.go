@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	grpcStatus "google.golang.org/grpc/status"
+)
+
+func TestInitiatorGroupLifecycle(t *testing.T) {
+	_, c, token := newTestAppliance(t)
+	ctx := context.Background()
+
+	const iqn1 = "iqn.1988-12.com.oracle:node1"
+	const iqn2 = "iqn.1988-12.com.oracle:node2"
+
+	initiator, _, err := c.CreateInitiator(ctx, token, "iscsi", iqn1, "node1")
+	if err != nil {
+		t.Fatalf("CreateInitiator failed: %v", err)
+	}
+	if initiator.Initiator != iqn1 || initiator.Alias != "node1" {
+		t.Errorf("unexpected initiator %+v", initiator)
+	}
+	if _, _, err := c.CreateInitiator(ctx, token, "iscsi", iqn1, "node1"); grpcStatus.Code(err) != codes.AlreadyExists {
+		t.Errorf("expected AlreadyExists creating the initiator again, got %v", err)
+	}
+	if _, err := c.GetInitiator(ctx, token, "iscsi", iqn1); err != nil {
+		t.Errorf("GetInitiator failed: %v", err)
+	}
+	list, err := c.GetInitiators(ctx, token, "iscsi")
+	if err != nil || len(list) != 1 {
+		t.Errorf("unexpected initiators %+v, %v", list, err)
+	}
+
+	if _, _, err := c.CreateInitiatorGroup(ctx, token, "iscsi", "node1", []string{iqn1}); err != nil {
+		t.Fatalf("CreateInitiatorGroup failed: %v", err)
+	}
+	if _, _, err := c.ModifyInitiatorGroup(ctx, token, "iscsi", "node1", []string{iqn1, iqn2}); err != nil {
+		t.Fatalf("ModifyInitiatorGroup failed: %v", err)
+	}
+	group, err := c.GetInitiatorGroup(ctx, token, "iscsi", "node1")
+	if err != nil || !reflect.DeepEqual(group.Initiators, []string{iqn1, iqn2}) {
+		t.Errorf("initiator group not modified: %+v, %v", group, err)
+	}
+	groups, err := c.GetInitiatorGroups(ctx, token, "iscsi")
+	if err != nil || len(groups) != 1 || groups[0].Name != "node1" {
+		t.Errorf("unexpected initiator groups %+v, %v", groups, err)
+	}
+
+	if _, err := c.DeleteInitiatorGroup(ctx, token, "iscsi", "node1"); err != nil {
+		t.Fatalf("DeleteInitiatorGroup failed: %v", err)
+	}
+	if _, err := c.DeleteInitiator(ctx, token, "iscsi", iqn1); err != nil {
+		t.Fatalf("DeleteInitiator failed: %v", err)
+	}
+	if _, err := c.GetInitiatorGroup(ctx, token, "iscsi", "node1"); grpcStatus.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound once the group is deleted, got %v", err)
+	}
+	if _, err := c.GetInitiator(ctx, token, "iscsi", iqn1); grpcStatus.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound once the initiator is deleted, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// The appliance answers a request listing objects with a JSON object whose member named
+// after the collection holds the list, for instance:
+//
+//	{"filesystems": [{...}, ..., {...}]}
+//
+// Such a response can be large (tens of thousands of snapshots), so it is decoded while
+// it is read from the connection: the objects of the list are decoded one by one and
+// the other members of the response are skipped.
+
+// listResponse is implemented by the responses made of a list of objects.
+type listResponse interface {
+	decodeList(dec *json.Decoder) error
+}
+
+// Error returned when a response does not have the structure expected.
+type listFormatError struct {
+	collection string
+	reason     string
+}
+
+func (e *listFormatError) Error() string {
+	return fmt.Sprintf("invalid list of %s: %s", e.collection, e.reason)
+}
+
+// Decodes the list of objects of the collection passed in into list. The content of
+// list is replaced.
+func decodeList[T any](dec *json.Decoder, collection string, list *[]T) error {
+
+	*list = make([]T, 0)
+	if err := expectDelim(dec, collection, '{'); err != nil {
+		return err
+	}
+
+	found := false
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return &listFormatError{collection, fmt.Sprintf("unexpected token %v", tok)}
+		}
+		if key != collection {
+			if err := skipValue(dec); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := expectDelim(dec, collection, '['); err != nil {
+			return err
+		}
+		for dec.More() {
+			var item T
+			if err := dec.Decode(&item); err != nil {
+				return err
+			}
+			*list = append(*list, item)
+		}
+		if err := expectDelim(dec, collection, ']'); err != nil {
+			return err
+		}
+		found = true
+	}
+
+	if err := expectDelim(dec, collection, '}'); err != nil {
+		return err
+	}
+	if !found {
+		return &listFormatError{collection, "collection missing from the response"}
+	}
+	return nil
+}
+
+// Reads the next token and verifies it is the delimiter passed in.
+func expectDelim(dec *json.Decoder, collection string, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != delim {
+		return &listFormatError{collection, fmt.Sprintf("expected %v, found %v", delim, tok)}
+	}
+	return nil
+}
+
+// Skips the next value, whatever its type, without keeping it in memory.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// Returns true if the error passed in was caused by the content of the response rather
+// than by its transfer.
+func isDecodingError(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var formatErr *listFormatError
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr) || errors.As(err, &formatErr)
+}
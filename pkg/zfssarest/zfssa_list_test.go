@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDecodeList(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		want  []string
+		valid bool
+	}{
+		{"plain", `{"snapshots": [{"name": "s1"}, {"name": "s2"}]}`, []string{"s1", "s2"}, true},
+		{"empty", `{"snapshots": []}`, []string{}, true},
+		{"trailing whitespace", "{\"snapshots\": [{\"name\": \"s1\"}]}\n\n  ", []string{"s1"}, true},
+		{"leading key with bracket", `{"filter[0]": "x", "snapshots": [{"name": "s1"}]}`, []string{"s1"}, true},
+		{"other members", `{"meta": {"list": [1, [2]], "n": null}, "snapshots": [{"name": "s1"}], "total": 1}`,
+			[]string{"s1"}, true},
+		{"missing collection", `{"filesystems": [{"name": "s1"}]}`, nil, false},
+		{"not a list", `{"snapshots": {"name": "s1"}}`, nil, false},
+		{"wrong item type", `{"snapshots": [{"name": 1}]}`, nil, false},
+		{"not an object", `[{"name": "s1"}]`, nil, false},
+	}
+	for _, tt := range tests {
+		l := new(snapshots)
+		err := l.decodeList(json.NewDecoder(strings.NewReader(tt.body)))
+		if !tt.valid {
+			if err == nil || !isDecodingError(err) {
+				t.Errorf("%s: expected a decoding error, got %v", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: decodeList failed: %v", tt.name, err)
+			continue
+		}
+		names := make([]string, 0, len(l.List))
+		for _, s := range l.List {
+			names = append(names, s.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(tt.want, ",") || l.List == nil {
+			t.Errorf("%s: got %v, want %v", tt.name, names, tt.want)
+		}
+	}
+}
+
+func TestDecodeTruncatedList(t *testing.T) {
+	l := &snapshots{List: []Snapshot{{Name: "previous"}}}
+	err := l.decodeList(json.NewDecoder(strings.NewReader(`{"snapshots": [{"name": "s1"}, {"na`)))
+	if err == nil || isDecodingError(err) {
+		t.Errorf("expected a transfer error, got %v", err)
+	}
+
+	// A retry decodes the list from scratch.
+	err = l.decodeList(json.NewDecoder(strings.NewReader(`{"snapshots": [{"name": "s1"}]}`)))
+	if err != nil || len(l.List) != 1 {
+		t.Errorf("unexpected list %v, %v", l.List, err)
+	}
+}
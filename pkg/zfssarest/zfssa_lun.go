@@ -7,6 +7,7 @@ package zfssarest
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -34,6 +35,8 @@ type Lun struct {
 	AssignedNumber	[]int32		`json:"assignednumber"`
 	InitiatorGroup	[]string	`json:"initiatorgroup"`
 	TargetGroup		string		`json:"targetgroup"`
+	Origin			Origin		`json:"origin"`
+	Owner
 }
 
 type LunJson struct {
@@ -48,22 +51,13 @@ type LunInitiatorGrps struct {
 	InitiatorGroup []string `json:"initiatorgroup"`
 }
 
-type createLunRequest struct {
-	Name			string		`json:"name"`
-	Size			int64		`json:"volsize"`
-	Blocksize		int			`json:"volblocksize"`
-	TargetGroup		string		`json:"targetgroup"`
-	Sparse  		bool		`json:"sparse"`
-	InitiatorGroup	[]string	`json:"initiatorgroup"`
-}
-
-func CreateLUN(ctx context.Context, token *Token, lunName string, volSize int64, 
-	parameters *map[string]string) (*utils.VolumeId, *Lun, int, error) {
+func (c *Client) CreateLUN(ctx context.Context, token *Token, lunName string, volSize int64, 
+	parameters *map[string]string, owner *Owner) (*utils.VolumeId, *Lun, int, error) {
 
 	pool := (*parameters)["pool"]
 	project := (*parameters)["project"]
 
-	url := fmt.Sprintf(zLUNs, token.Name, pool, project)
+	url := fmt.Sprintf(zLUNs, c.address, pool, project)
 
 	blockSizeString := (*parameters)["blockSize"]
 	blockSize, err := strconv.Atoi(blockSizeString)
@@ -79,17 +73,29 @@ func CreateLUN(ctx context.Context, token *Token, lunName string, volSize int64,
 		sparse = true
 	}
 
-	reqBody := createLunRequest{
-		Name:           lunName,
-		Size:           volSize,
-		Blocksize:      blockSize,
-		TargetGroup: 	(*parameters)["targetGroup"],
-		Sparse: 		sparse,
-		InitiatorGroup:	[]string{MaskAll},
+	properties, err := LunProperties(*parameters)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	reqBody := map[string]interface{}{
+		"name":				lunName,
+		"volsize":			volSize,
+		"volblocksize":		blockSize,
+		"targetgroup":		(*parameters)["targetGroup"],
+		"sparse":			sparse,
+		"initiatorgroup":	[]string{MaskAll},
+	}
+	for name, value := range properties {
+		reqBody[name] = value
+	}
+	for name, value := range owner.Properties() {
+		reqBody[name] = value
 	}
 
 	rspBody := &LunJson{}
-	_, code, err := MakeRequest(ctx, token, "POST", url, reqBody, http.StatusCreated, rspBody)
+	objectURL := fmt.Sprintf(zLUN, c.address, pool, project, lunName)
+	_, code, err := c.MakeCreateRequest(ctx, token, "POST", url, reqBody, http.StatusCreated, rspBody, objectURL)
 	if err != nil {
 		return nil, nil, code, err
 	}
@@ -99,12 +105,12 @@ func CreateLUN(ctx context.Context, token *Token, lunName string, volSize int64,
 	return volumeId, &rspBody.LUN, code, nil
 }
 
-func GetLun(ctx context.Context, token *Token, pool, project, lun string) (*Lun, int, error) {
+func (c *Client) GetLun(ctx context.Context, token *Token, pool, project, lun string) (*Lun, int, error) {
 
-	url := fmt.Sprintf(zLUN, token.Name, pool, project, lun)
+	url := fmt.Sprintf(zLUN, c.address, pool, project, lun)
 
 	rspJSON := &LunJson{}
-	_, httpStatus, err := MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, rspJSON)
+	_, httpStatus, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, rspJSON)
 	if err != nil {
 		return nil, httpStatus, err
 	}
@@ -114,13 +120,13 @@ func GetLun(ctx context.Context, token *Token, pool, project, lun string) (*Lun,
 
 // Returns the List of LUNs belonging to the pool and project passed in. To
 // get a system wide List of LUNs, the pool AND the project must be 'nil'
-func GetLuns(ctx context.Context, token *Token, pool, project string) ([]Lun, error) {
+func (c *Client) GetLuns(ctx context.Context, token *Token, pool, project string) ([]Lun, error) {
 
 	var url string
 	if pool != "" && project != "" {
-		url = fmt.Sprintf(zLUNs, token.Name, pool, project)
+		url = fmt.Sprintf(zLUNs, c.address, pool, project)
 	} else if pool == "" && project == "" {
-		url = fmt.Sprintf(zAllLUNs, token.Name)
+		url = fmt.Sprintf(zAllLUNs, c.address)
 	} else {
 		return nil, grpcStatus.Error(codes.InvalidArgument, "pool and project must be both nil or both not nil")
 	}
@@ -128,7 +134,7 @@ func GetLuns(ctx context.Context, token *Token, pool, project string) ([]Lun, er
 	luns := new(Luns)
 	luns.List = make([]Lun, 0)
 
-	_, _, err := MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, luns)
+	_, _, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, luns)
 	if err != nil {
 		return nil, err
 	}
@@ -136,11 +142,27 @@ func GetLuns(ctx context.Context, token *Token, pool, project string) ([]Lun, er
 	return luns.List, nil
 }
 
-func DeleteLun(ctx context.Context, token *Token, pool, project, lun string) (bool, int, error) {
+// Issues a request to the appliance to modify the properties of a LUN (its size for
+// instance, "volsize").
+func (c *Client) ModifyLun(ctx context.Context, token *Token, href string,
+	parameters *map[string]interface{}) (*Lun, int, error) {
+
+	url := fmt.Sprintf(zAppliance + href, c.address)
+
+	rspJSON := &LunJson{}
+	_, httpStatus, err := c.MakeRequest(ctx, token, "PUT", url, parameters, http.StatusAccepted, rspJSON)
+	if err != nil {
+		return nil, httpStatus, err
+	}
+
+	return &rspJSON.LUN, httpStatus, nil
+}
+
+func (c *Client) DeleteLun(ctx context.Context, token *Token, pool, project, lun string) (bool, int, error) {
 
-	url := fmt.Sprintf(zLUN, token.Name, pool, project, lun)
+	url := fmt.Sprintf(zLUN, c.address, pool, project, lun)
 
-	_, httpStatus, err := MakeRequest(ctx, token, "DELETE", url, nil, http.StatusNoContent, nil)
+	_, httpStatus, err := c.MakeRequest(ctx, token, "DELETE", url, nil, http.StatusNoContent, nil)
 	if err != nil {
 		return false, httpStatus, err
 	}
@@ -155,44 +177,46 @@ func DeleteLun(ctx context.Context, token *Token, pool, project, lun string) (bo
 	return true, httpStatus, nil
 }
 
-func GetInitiatorGroupList(ctx context.Context, token *Token, pool, project, 
+func (c *Client) GetInitiatorGroupList(ctx context.Context, token *Token, pool, project, 
 	lun string) ([]string, error) {
 
-	url := fmt.Sprintf(zLUN, token.Name, pool, project, lun)
+	url := fmt.Sprintf(zLUN, c.address, pool, project, lun)
 
 	rspBody := &LunJson{}
-	_, _, err := MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, rspBody)
+	_, _, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, rspBody)
 	if err != nil {
 		return nil, err
 	}
-	utils.GetLogREST(ctx, 2).Printf("Retrieved the initiator list: {}", rspBody)
+	utils.GetLogREST(ctx, 2).Printf("Retrieved the initiator list: %v", rspBody)
 
 	return rspBody.LUN.InitiatorGroup, nil
 }
 
-func SetInitiatorGroupList(ctx context.Context, token *Token, pool, project, lun, 
-	group string) (int, error) {
+// Replaces the initiator groups the LUN is visible to with the groups passed in.
+func (c *Client) SetInitiatorGroupList(ctx context.Context, token *Token, pool, project, lun string,
+	groups ...string) (int, error) {
 
-	url := fmt.Sprintf(zLUN, token.Name, pool, project, lun)
+	url := fmt.Sprintf(zLUN, c.address, pool, project, lun)
 
-	reqBody := &LunInitiatorGrps{InitiatorGroup: []string{group}}
-	utils.GetLogREST(ctx, 2).Printf("Setting up initiator list: {}", reqBody)
-	_, code, err := MakeRequest(ctx, token, "PUT", url, reqBody, http.StatusAccepted, nil)
+	reqBody := &LunInitiatorGrps{InitiatorGroup: groups}
+	utils.GetLogREST(ctx, 2).Printf("Setting up initiator list: %v", reqBody)
+	_, code, err := c.MakeRequest(ctx, token, "PUT", url, reqBody, http.StatusAccepted, nil)
 	return code, err
 }
 
-func (l *Luns) UnmarshalJSON(b []byte) error {
-	return zfssaUnmarshalList(b, &l.List)
+func (l *Luns) decodeList(dec *json.Decoder) error {
+	return decodeList(dec, "luns", &l.List)
 }
 
-func CloneLunSnapshot(ctx context.Context, token *Token, hRef string, 
+func (c *Client) CloneLunSnapshot(ctx context.Context, token *Token, hRef string, 
 	parameters map[string]interface{}) (*Lun, int, error) {
 
-	url := fmt.Sprintf(zAppliance + hRef + "/clone", token.Name)
+	url := fmt.Sprintf(zAppliance + hRef + "/clone", c.address)
 
 	rspBody := new(LunJson)
 
-	_, code, err := MakeRequest(ctx, token, "PUT", url, &parameters, http.StatusCreated, rspBody)
+	objectURL := fmt.Sprintf(zLUN, c.address, poolFromHref(hRef), parameters["project"], parameters["share"])
+	_, code, err := c.MakeCreateRequest(ctx, token, "PUT", url, &parameters, http.StatusCreated, rspBody, objectURL)
 	if err != nil {
 		return nil, code, err
 	}
@@ -0,0 +1,58 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	grpcStatus "google.golang.org/grpc/status"
+)
+
+func TestClientCertificate(t *testing.T) {
+	zfssa, _, _ := newTestAppliance(t)
+	c, err := NewClient(zfssa.Name(), zfssa.CertFile(), true)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	c.SetRetryPolicy(testRetryPolicy)
+
+	// Without a client certificate, a login without password is rejected.
+	token := c.LookUpToken(context.Background(), testUser, "")
+	if _, err := c.GetPool(context.Background(), token, testPool); grpcStatus.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated without a client certificate, got %v", err)
+	}
+
+	certPEM, keyPEM, err := zfssa.IssueClientCertificate(testUser)
+	if err != nil {
+		t.Fatalf("IssueClientCertificate failed: %v", err)
+	}
+	if err := c.SetClientCertificate(nil, certPEM, keyPEM); err != nil {
+		t.Fatalf("SetClientCertificate failed: %v", err)
+	}
+	token = c.LookUpToken(context.Background(), testUser, "")
+	if _, err := c.GetPool(context.Background(), token, testPool); err != nil {
+		t.Fatalf("request failed with a client certificate: %v", err)
+	}
+
+	// A certificate issued for another user is rejected.
+	certPEM, keyPEM, err = zfssa.IssueClientCertificate("other")
+	if err != nil {
+		t.Fatalf("IssueClientCertificate failed: %v", err)
+	}
+	if err := c.SetClientCertificate(nil, certPEM, keyPEM); err != nil {
+		t.Fatalf("SetClientCertificate failed: %v", err)
+	}
+	token = c.LookUpToken(context.Background(), testUser, "")
+	if _, err := c.GetPool(context.Background(), token, testPool); grpcStatus.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated with the certificate of another user, got %v", err)
+	}
+
+	if err := c.SetClientCertificate(nil, certPEM, []byte("not a key")); err == nil {
+		t.Errorf("invalid client key accepted")
+	}
+}
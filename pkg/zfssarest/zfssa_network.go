@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Network interface of the appliance. The addresses are in CIDR notation
+// (192.168.10.4/24).
+type NetworkInterface struct {
+	Interface string   `json:"interface"`
+	Label     string   `json:"label"`
+	State     string   `json:"state"`
+	Enable    bool     `json:"enable"`
+	V4Addrs   []string `json:"v4addrs"`
+	V6Addrs   []string `json:"v6addrs"`
+	Href      string   `json:"href"`
+}
+
+type networkInterfaceJSON struct {
+	Interface NetworkInterface `json:"interface"`
+}
+
+type networkInterfaces struct {
+	List []NetworkInterface `json:"interfaces"`
+}
+
+func (l *networkInterfaces) decodeList(dec *json.Decoder) error {
+	return decodeList(dec, "interfaces", &l.List)
+}
+
+// Returns the network interface whose name is passed in.
+func (c *Client) GetNetworkInterface(ctx context.Context, token *Token, name string) (*NetworkInterface, error) {
+
+	url := fmt.Sprintf(zNetworkInterface, c.address, name)
+
+	rspJSON := &networkInterfaceJSON{}
+	_, _, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, rspJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rspJSON.Interface, nil
+}
+
+// Returns all the network interfaces of the appliance.
+func (c *Client) GetNetworkInterfaces(ctx context.Context, token *Token) ([]NetworkInterface, error) {
+
+	url := fmt.Sprintf(zNetworkInterfaces, c.address)
+
+	list := new(networkInterfaces)
+	_, _, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, list)
+	if err != nil {
+		return nil, err
+	}
+
+	return list.List, nil
+}
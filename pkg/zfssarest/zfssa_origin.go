@@ -0,0 +1,44 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Snapshot a clone was created from, as reported by the property "origin" of the
+// clone. The fields are empty if the share is not a clone.
+type Origin struct {
+	Pool       string `json:"pool"`
+	Project    string `json:"project"`
+	Share      string `json:"share"`
+	Snapshot   string `json:"snapshot"`
+	Collection string `json:"collection"`
+}
+
+// The property "origin" of a share that is not a clone is not an object (it may be
+// missing or an empty string). Such a value decodes to an empty origin.
+func (o *Origin) UnmarshalJSON(data []byte) error {
+	if len(data) == 0 || data[0] != '{' {
+		*o = Origin{}
+		return nil
+	}
+	type origin Origin
+	return json.Unmarshal(data, (*origin)(o))
+}
+
+// Returns true if the share is a clone.
+func (o *Origin) IsClone() bool {
+	return o.Snapshot != ""
+}
+
+// Returns the HREF of the snapshot the share was cloned from. The kind of the share,
+// "filesystems" or "luns", is passed in.
+func (o *Origin) SnapshotHref(kind string) string {
+	return fmt.Sprintf("/api/storage/v2/pools/%s/projects/%s/%s/%s/snapshots/%s",
+		o.Pool, o.Project, kind, o.Share, o.Snapshot)
+}
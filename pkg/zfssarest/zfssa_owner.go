@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+// The owner of the shares created by the driver is recorded in custom properties of the
+// schema of the appliance. On the shares, the custom properties are prefixed with
+// "custom:" ("custom:zcsiClusterId").
+
+const (
+	OwnerClusterId    = "zcsiClusterId"
+	OwnerPvName       = "zcsiPvName"
+	OwnerPvcNamespace = "zcsiPvcNamespace"
+	OwnerPvcName      = "zcsiPvcName"
+	OwnerCreatedBy    = "zcsiCreatedBy"
+)
+
+// Custom properties of the schema recording the owner of the shares.
+var OwnerSchema = []Schema{
+	{Property: OwnerClusterId, Type: "String", Description: "Kubernetes cluster owning the share"},
+	{Property: OwnerPvName, Type: "String", Description: "Kubernetes persistent volume of the share"},
+	{Property: OwnerPvcNamespace, Type: "String", Description: "Namespace of the persistent volume claim"},
+	{Property: OwnerPvcName, Type: "String", Description: "Persistent volume claim of the share"},
+	{Property: OwnerCreatedBy, Type: "String", Description: "Driver that created the share"},
+}
+
+// Custom properties of the file systems exported per node by the driver: the NFS export
+// mode ("node" when their export is maintained per node) and the clients granted access
+// per node (JSON object mapping the name of a node to its access list entries).
+const (
+	NfsExport = "zcsiNfsExport"
+	NfsNodes  = "zcsiNfsNodes"
+)
+
+// Custom properties of the schema recording the NFS export of the file systems.
+var NfsExportSchema = []Schema{
+	{Property: NfsExport, Type: "String", Description: "NFS export mode of the share"},
+	{Property: NfsNodes, Type: "String", Description: "NFS clients of the nodes the share is exported to"},
+}
+
+// Owner of a share, as recorded in its custom properties. The fields are empty if the
+// share was not created by the driver or if its owner was not recorded.
+type Owner struct {
+	ClusterId    string `json:"custom:zcsiClusterId,omitempty"`
+	PvName       string `json:"custom:zcsiPvName,omitempty"`
+	PvcNamespace string `json:"custom:zcsiPvcNamespace,omitempty"`
+	PvcName      string `json:"custom:zcsiPvcName,omitempty"`
+	CreatedBy    string `json:"custom:zcsiCreatedBy,omitempty"`
+}
+
+// Returns the custom properties recording the owner, to be set on a share. Only the
+// fields that are not empty are returned.
+func (o *Owner) Properties() map[string]interface{} {
+	properties := make(map[string]interface{})
+	if o == nil {
+		return properties
+	}
+	for name, value := range map[string]string{
+		OwnerClusterId:    o.ClusterId,
+		OwnerPvName:       o.PvName,
+		OwnerPvcNamespace: o.PvcNamespace,
+		OwnerPvcName:      o.PvcName,
+		OwnerCreatedBy:    o.CreatedBy,
+	} {
+		if value != "" {
+			properties["custom:"+name] = value
+		}
+	}
+	return properties
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/oracle/zfssa-csi-driver/pkg/utils"
+)
+
+// Appliances often present self-signed certificates whose names or IP addresses do not
+// match the address used to reach them. Instead of being verified against a certificate
+// authority, such a certificate can be pinned: the connection is only accepted if the
+// certificate presented by the appliance has the expected SHA-256 fingerprint or public
+// key. With trust on first use, the certificate presented by the first connection is
+// pinned and the following connections must present the same certificate.
+
+// Pinning describes the certificate expected from an appliance.
+type Pinning struct {
+	// SHA-256 fingerprint of the certificate (DER), in hexadecimal. Colons are allowed
+	// (as printed by "openssl x509 -fingerprint -sha256").
+	Fingerprint string
+	// SHA-256 hash of the public key of the certificate (DER SubjectPublicKeyInfo), in
+	// base64 (as a "pin-sha256") or in hexadecimal.
+	PublicKey string
+	// Pin the fingerprint of the certificate presented by the first connection if
+	// neither a fingerprint nor a public key is given.
+	TrustOnFirstUse bool
+	// Called with the fingerprint pinned on first use, typically to record it.
+	Record func(fingerprint string) error
+}
+
+// Pins of a client.
+type pinState struct {
+	mtx         sync.Mutex
+	fingerprint []byte
+	publicKey   []byte
+	tofu        bool
+	record      func(fingerprint string) error
+}
+
+// Error returned when the certificate of the appliance does not match the pins.
+type pinError struct {
+	fingerprint string
+}
+
+func (e *pinError) Error() string {
+	return fmt.Sprintf("certificate of the appliance (fingerprint %s) does not match the pinned certificate",
+		e.fingerprint)
+}
+
+// Verifies the certificate of the appliance against the pins passed in instead of the
+// certificate authorities. The sessions opened previously are invalidated.
+func (c *Client) SetPinning(ctx context.Context, pinning Pinning) error {
+
+	pins := &pinState{tofu: pinning.TrustOnFirstUse, record: pinning.Record}
+	var err error
+	if pinning.Fingerprint != "" {
+		pins.fingerprint, err = parseFingerprint(pinning.Fingerprint)
+		if err != nil {
+			return err
+		}
+	}
+	if pinning.PublicKey != "" {
+		pins.publicKey, err = parsePublicKeyPin(pinning.PublicKey)
+		if err != nil {
+			return err
+		}
+	}
+	if pins.fingerprint == nil && pins.publicKey == nil && !pins.tofu {
+		return errors.New("no fingerprint or public key to pin")
+	}
+
+	c.secure = false
+	c.setTLSConfig(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// The chain and the name of the certificate are not verified, the certificate
+		// itself is verified by VerifyConnection.
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return pins.verify(cs)
+		},
+	})
+	c.InvalidateSessions(ctx, "")
+	return nil
+}
+
+// Verifies the certificate presented by the appliance.
+func (p *pinState) verify(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("the appliance presented no certificate")
+	}
+	leaf := cs.PeerCertificates[0]
+	fingerprint := sha256.Sum256(leaf.Raw)
+	publicKey := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.fingerprint == nil && p.publicKey == nil {
+		// Trust on first use
+		p.fingerprint = fingerprint[:]
+		formatted := formatFingerprint(p.fingerprint)
+		utils.GetLogREST(nil, 2).Println("Certificate of the appliance pinned on first use",
+			"fingerprint", formatted)
+		if p.record != nil {
+			if err := p.record(formatted); err != nil {
+				utils.GetLogREST(nil, 2).Println("Fingerprint of the appliance could not be recorded",
+					"error", err)
+			}
+		}
+		return nil
+	}
+
+	if p.fingerprint != nil && !bytes.Equal(p.fingerprint, fingerprint[:]) {
+		return &pinError{formatFingerprint(fingerprint[:])}
+	}
+	if p.publicKey != nil && !bytes.Equal(p.publicKey, publicKey[:]) {
+		return &pinError{formatFingerprint(fingerprint[:])}
+	}
+	return nil
+}
+
+// Parses a SHA-256 fingerprint in hexadecimal, colons and spaces are ignored.
+func parseFingerprint(fingerprint string) ([]byte, error) {
+	clean := strings.NewReplacer(":", "", " ", "").Replace(strings.TrimSpace(fingerprint))
+	value, err := hex.DecodeString(clean)
+	if err != nil || len(value) != sha256.Size {
+		return nil, errors.New(fmt.Sprintf("invalid SHA-256 fingerprint: <%s>", fingerprint))
+	}
+	return value, nil
+}
+
+// Formats a fingerprint as pairs of upper case hexadecimal digits separated by colons.
+func formatFingerprint(fingerprint []byte) string {
+	pairs := make([]string, len(fingerprint))
+	for i, b := range fingerprint {
+		pairs[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(pairs, ":")
+}
+
+// Parses the SHA-256 hash of a public key, in base64 or hexadecimal.
+func parsePublicKeyPin(pin string) ([]byte, error) {
+	pin = strings.TrimPrefix(strings.TrimSpace(pin), "sha256/")
+	if value, err := base64.StdEncoding.DecodeString(pin); err == nil && len(value) == sha256.Size {
+		return value, nil
+	}
+	if value, err := parseFingerprint(pin); err == nil {
+		return value, nil
+	}
+	return nil, errors.New(fmt.Sprintf("invalid SHA-256 public key pin: <%s>", pin))
+}
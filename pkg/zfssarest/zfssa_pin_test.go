@@ -0,0 +1,132 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"testing"
+
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest/zfssatest"
+	"google.golang.org/grpc/codes"
+	grpcStatus "google.golang.org/grpc/status"
+)
+
+// Returns the certificate of the fake appliance.
+func testCertificate(t *testing.T, zfssa *zfssatest.Server) *x509.Certificate {
+	t.Helper()
+	data, err := os.ReadFile(zfssa.CertFile())
+	if err != nil {
+		t.Fatalf("cannot read the certificate: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("cannot parse the certificate: %v", err)
+	}
+	return cert
+}
+
+// Returns a client of the fake appliance verifying its certificate with the pins passed in.
+func newPinnedClient(t *testing.T, zfssa *zfssatest.Server, pinning Pinning) (*Client, *Token) {
+	t.Helper()
+	c, err := NewClient(zfssa.Name(), "", false)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if err := c.SetPinning(nil, pinning); err != nil {
+		t.Fatalf("SetPinning failed: %v", err)
+	}
+	c.SetRetryPolicy(testRetryPolicy)
+	return c, c.LookUpToken(context.Background(), testUser, testPassword)
+}
+
+func TestPinnedCertificate(t *testing.T) {
+	zfssa, _, _ := newTestAppliance(t)
+	cert := testCertificate(t, zfssa)
+	fingerprint := sha256.Sum256(cert.Raw)
+	publicKey := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	other := sha256.Sum256([]byte("other"))
+
+	tests := []struct {
+		pinning Pinning
+		valid   bool
+	}{
+		{Pinning{Fingerprint: formatFingerprint(fingerprint[:])}, true},
+		{Pinning{PublicKey: base64.StdEncoding.EncodeToString(publicKey[:])}, true},
+		{Pinning{PublicKey: "sha256/" + base64.StdEncoding.EncodeToString(publicKey[:])}, true},
+		{Pinning{Fingerprint: formatFingerprint(other[:])}, false},
+		{Pinning{PublicKey: base64.StdEncoding.EncodeToString(other[:])}, false},
+		{Pinning{Fingerprint: formatFingerprint(fingerprint[:]),
+			PublicKey: base64.StdEncoding.EncodeToString(other[:])}, false},
+	}
+	for _, tt := range tests {
+		c, token := newPinnedClient(t, zfssa, tt.pinning)
+		_, err := c.GetPool(context.Background(), token, testPool)
+		switch {
+		case tt.valid && err != nil:
+			t.Errorf("request failed with pins %+v: %v", tt.pinning, err)
+		case !tt.valid && grpcStatus.Code(err) != codes.Unavailable:
+			t.Errorf("expected Unavailable with pins %+v, got %v", tt.pinning, err)
+		}
+	}
+}
+
+func TestTrustOnFirstUse(t *testing.T) {
+	zfssa, _, _ := newTestAppliance(t)
+	cert := testCertificate(t, zfssa)
+	fingerprint := sha256.Sum256(cert.Raw)
+
+	var recorded []string
+	c, token := newPinnedClient(t, zfssa, Pinning{
+		TrustOnFirstUse: true,
+		Record: func(fingerprint string) error {
+			recorded = append(recorded, fingerprint)
+			return nil
+		},
+	})
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetPool(context.Background(), token, testPool); err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		c.getHttpClient().CloseIdleConnections()
+	}
+	if len(recorded) != 1 || recorded[0] != formatFingerprint(fingerprint[:]) {
+		t.Errorf("unexpected fingerprints recorded %v", recorded)
+	}
+
+	// Once pinned, another certificate is rejected.
+	pins := &pinState{tofu: true}
+	if err := pins.verify(tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}); err != nil {
+		t.Fatalf("first certificate rejected: %v", err)
+	}
+	other := &x509.Certificate{Raw: []byte("other"), RawSubjectPublicKeyInfo: []byte("other")}
+	if err := pins.verify(tls.ConnectionState{PeerCertificates: []*x509.Certificate{other}}); err == nil {
+		t.Errorf("another certificate accepted after the first use")
+	}
+}
+
+func TestInvalidPins(t *testing.T) {
+	c, err := NewClient("zfssa", "", false)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	for _, pinning := range []Pinning{
+		{},
+		{Fingerprint: "AB:CD"},
+		{Fingerprint: "not hexadecimal"},
+		{PublicKey: "bm90IGEga2V5"},
+	} {
+		if err := c.SetPinning(nil, pinning); err == nil {
+			t.Errorf("pins %+v accepted", pinning)
+		}
+	}
+}
@@ -8,6 +8,7 @@ package zfssarest
 import (
 	"github.com/oracle/zfssa-csi-driver/pkg/utils"
 	"context"
+	"encoding/json"
 	"fmt"
 	"google.golang.org/grpc/codes"
 	grpcStatus "google.golang.org/grpc/status"
@@ -43,20 +44,21 @@ type pools struct {
 	List	[]Pool `json:"pools"`
 }
 
-func GetPool(ctx context.Context, token *Token, name string) (*Pool, error) {
+func (c *Client) GetPool(ctx context.Context, token *Token, name string) (*Pool, error) {
 
 	// We retrieve the information from the ZFSSA
-	url := fmt.Sprintf(zPool, token.Name, name)
+	url := fmt.Sprintf(zPool, c.address, name)
 
 	json := new(poolJSON)
-	_, httpstatus, err := MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, json)
+	_, httpstatus, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, json)
 	if err == nil && httpstatus == http.StatusOK {
 		return &json.Pool, nil
 	}
 
 	if err != nil {
-		utils.GetLogREST(ctx, 2).Println("Request for pool information failed with a local error",
+		utils.GetLogREST(ctx, 2).Println("Request for pool information failed",
 			"url", url, "error", err.Error())
+		return nil, err
 	} else {
 		utils.GetLogREST(ctx, 2).Println("Request for pool information failed with a ZFSSA error",
 			"url", url, "http status", httpstatus)
@@ -65,14 +67,14 @@ func GetPool(ctx context.Context, token *Token, name string) (*Pool, error) {
 	return nil, grpcStatus.Error(codes.NotFound,"Pool not found")
 }
 
-func GetPools(ctx context.Context, token *Token) (*[]Pool, error) {
+func (c *Client) GetPools(ctx context.Context, token *Token) (*[]Pool, error) {
 
-	url := fmt.Sprintf(zPools, token.Name)
+	url := fmt.Sprintf(zPools, c.address)
 
 	zfssaPools := new(pools)
 	zfssaPools.List = make([]Pool, 0)
 
-	_, _, err := MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, zfssaPools)
+	_, _, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, zfssaPools)
 	if err != nil {
 		return nil, err
 	}
@@ -80,6 +82,6 @@ func GetPools(ctx context.Context, token *Token) (*[]Pool, error) {
 	return &zfssaPools.List, nil
 }
 
-func (l *pools) UnmarshalJSON(b []byte) error {
-	return zfssaUnmarshalList(b, &l.List)
+func (l *pools) decodeList(dec *json.Decoder) error {
+	return decodeList(dec, "pools", &l.List)
 }
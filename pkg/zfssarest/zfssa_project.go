@@ -7,14 +7,19 @@ package zfssarest
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+
+	"github.com/oracle/zfssa-csi-driver/pkg/utils"
 )
 
 type Project struct {
 	Name			string	`json:"name"`
 	Pool			string	`json:"pool"`
 	SpaceAvailable	int64	`json:"space_available"`
+	Quota			int64	`json:"quota"`
+	Href			string	`json:"href"`
 }
 
 type ProjectJSON struct {
@@ -22,15 +27,15 @@ type ProjectJSON struct {
 }
 
 type projects struct {
-	list 		[]Project `json:"projects"`
+	List 		[]Project `json:"projects"`
 }
 
-func GetProject(ctx context.Context, token *Token, pool string, project string) (*Project, error) {
+func (c *Client) GetProject(ctx context.Context, token *Token, pool string, project string) (*Project, error) {
 
-	url := fmt.Sprintf(zProject, token.Name, pool, project)
+	url := fmt.Sprintf(zProject, c.address, pool, project)
 
 	jsonData := &ProjectJSON{}
-	_, _, err := MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, jsonData)
+	_, _, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, jsonData)
 	if err != nil {
 		return nil, err
 	}
@@ -38,28 +43,83 @@ func GetProject(ctx context.Context, token *Token, pool string, project string)
 	return &jsonData.Project, nil
 }
 
+// Issues a request to the appliance to create a project in the pool passed in. The
+// parameters are the properties of the new project, the properties not specified are
+// inherited from the pool (default values of the appliance).
+func (c *Client) CreateProject(ctx context.Context, token *Token, pool, name string,
+	parameters map[string]interface{}) (*Project, int, error) {
+
+	utils.GetLogREST(ctx, 5).Println("CreateProject", "appliance", token.Name, "pool", pool, "project", name)
+
+	url := fmt.Sprintf(zProjects, c.address, pool)
+	reqBody := make(map[string]interface{}, len(parameters)+1)
+	for key, value := range parameters {
+		reqBody[key] = value
+	}
+	reqBody["name"] = name
+
+	rspJSON := &ProjectJSON{}
+	objectURL := fmt.Sprintf(zProject, c.address, pool, name)
+	_, httpStatus, err := c.MakeCreateRequest(ctx, token, "POST", url, reqBody, http.StatusCreated, rspJSON,
+		objectURL)
+	if err != nil {
+		return nil, httpStatus, err
+	}
+
+	return &rspJSON.Project, httpStatus, nil
+}
+
+// Issues a request to the appliance to modify the properties of a project.
+func (c *Client) ModifyProject(ctx context.Context, token *Token, pool, name string,
+	parameters map[string]interface{}) (*Project, int, error) {
+
+	utils.GetLogREST(ctx, 5).Println("ModifyProject", "appliance", token.Name, "pool", pool, "project", name)
+
+	url := fmt.Sprintf(zProject, c.address, pool, name)
+
+	rspJSON := &ProjectJSON{}
+	_, httpStatus, err := c.MakeRequest(ctx, token, "PUT", url, parameters, http.StatusAccepted, rspJSON)
+	if err != nil {
+		return nil, httpStatus, err
+	}
+
+	return &rspJSON.Project, httpStatus, nil
+}
+
+// Issues a request to the appliance to delete a project. The appliance refuses to
+// delete a project that still has shares.
+func (c *Client) DeleteProject(ctx context.Context, token *Token, pool, name string) (int, error) {
+
+	utils.GetLogREST(ctx, 5).Println("DeleteProject", "appliance", token.Name, "pool", pool, "project", name)
+
+	url := fmt.Sprintf(zProject, c.address, pool, name)
+
+	_, httpStatus, err := c.MakeRequest(ctx, token, "DELETE", url, nil, http.StatusNoContent, nil)
+	return httpStatus, err
+}
+
 // Returns the List of filesystems associated with the pool and project passed in. To
 // get a system wide List of file systems, the pool must be 'nil'
-func GetProjects(ctx context.Context, token *Token, pool string) ([]Project, error) {
+func (c *Client) GetProjects(ctx context.Context, token *Token, pool string) ([]Project, error) {
 
 	var url string
 	if pool != "" {
-		url = fmt.Sprintf(zProjects, token.Name, pool)
+		url = fmt.Sprintf(zProjects, c.address, pool)
 	} else {
-		url = fmt.Sprintf(zAllProjects, token.Name)
+		url = fmt.Sprintf(zAllProjects, c.address)
 	}
 
 	projects := new(projects)
-	projects.list = make([]Project, 0)
+	projects.List = make([]Project, 0)
 
-	_, _, err := MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, projects)
+	_, _, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, projects)
 	if err != nil {
 		return nil, err
 	}
 
-	return projects.list, nil
+	return projects.List, nil
 }
 
-func (l *projects) UnmarshalJSON(b []byte) error {
-	return zfssaUnmarshalList(b, &l.list)
+func (l *projects) decodeList(dec *json.Decoder) error {
+	return decodeList(dec, "projects", &l.List)
 }
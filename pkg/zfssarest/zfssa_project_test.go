@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	grpcStatus "google.golang.org/grpc/status"
+)
+
+func TestProjectLifecycle(t *testing.T) {
+	_, c, token := newTestAppliance(t)
+	ctx := context.Background()
+
+	project, _, err := c.CreateProject(ctx, token, testPool, "team-a", map[string]interface{}{"quota": 1 << 30})
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	if project.Name != "team-a" || project.Pool != testPool || project.Quota != 1<<30 {
+		t.Errorf("unexpected project %+v", project)
+	}
+	if _, _, err := c.CreateProject(ctx, token, testPool, "team-a", nil); grpcStatus.Code(err) != codes.AlreadyExists {
+		t.Errorf("expected AlreadyExists creating the project again, got %v", err)
+	}
+
+	if _, _, err := c.ModifyProject(ctx, token, testPool, "team-a", map[string]interface{}{"quota": 2 << 30}); err != nil {
+		t.Fatalf("ModifyProject failed: %v", err)
+	}
+	project, err = c.GetProject(ctx, token, testPool, "team-a")
+	if err != nil || project.Quota != 2<<30 {
+		t.Errorf("project not modified: %+v, %v", project, err)
+	}
+
+	// A project with shares is not deleted.
+	params := map[string]string{"pool": testPool, "project": "team-a"}
+	if _, _, err := c.CreateFilesystem(ctx, token, "fs1", 1<<20, &params, nil); err != nil {
+		t.Fatalf("CreateFilesystem failed: %v", err)
+	}
+	if _, err := c.DeleteProject(ctx, token, testPool, "team-a"); grpcStatus.Code(err) != codes.AlreadyExists {
+		t.Errorf("expected a conflict deleting a project with shares, got %v", err)
+	}
+	if _, _, err := c.DeleteFilesystem(ctx, token, project.Href+"/filesystems/fs1"); err != nil {
+		t.Fatalf("DeleteFilesystem failed: %v", err)
+	}
+	if _, err := c.DeleteProject(ctx, token, testPool, "team-a"); err != nil {
+		t.Fatalf("DeleteProject failed: %v", err)
+	}
+	if _, err := c.GetProject(ctx, token, testPool, "team-a"); grpcStatus.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound once the project is deleted, got %v", err)
+	}
+}
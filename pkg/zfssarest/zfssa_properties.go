@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	grpcStatus "google.golang.org/grpc/status"
+)
+
+// Data properties of the shares that can be set by the parameters of a StorageClass. They
+// are validated and passed to the appliance when the share is created or cloned:
+//
+//	compression	off, lzjb, gzip-2, gzip or gzip-9
+//	deduplication	true or false (on or off)
+//	checksum	fletcher2, fletcher4 or sha256
+//	logBias		latency or throughput
+//	sync		standard, always or disabled
+//	recordSize	512 to 1048576 bytes, power of two (file systems only)
+//	atime		true or false (file systems only)
+//	copies		1, 2 or 3
+//	secondaryCache	all, metadata or none
+//	writeCache	true or false (LUNs only)
+//	readOnly	true or false
+
+type dataProperty struct {
+	name       string   // Name of the property on the appliance
+	values     []string // Values allowed, if the property is an enumeration
+	kind       string   // "string", "bool" or "int"
+	min, max   int64    // Range of an integer property
+	powerOfTwo bool     // Integer property required to be a power of two
+	filesystem bool     // Property of the file systems
+	lun        bool     // Property of the LUNs
+}
+
+// This variable provides a mapping between the name of the parameters used in the storage
+// class yaml file and the data properties of the shares of the appliance.
+var yml2dataProperty = map[string]dataProperty{
+	"compression": {name: "compression", kind: "string", filesystem: true, lun: true,
+		values: []string{"off", "lzjb", "gzip-2", "gzip", "gzip-9"}},
+	"deduplication": {name: "dedup", kind: "bool", filesystem: true, lun: true},
+	"checksum": {name: "checksum", kind: "string", filesystem: true, lun: true,
+		values: []string{"fletcher2", "fletcher4", "sha256"}},
+	"logBias": {name: "logbias", kind: "string", filesystem: true, lun: true,
+		values: []string{"latency", "throughput"}},
+	"sync": {name: "sync", kind: "string", filesystem: true, lun: true,
+		values: []string{"standard", "always", "disabled"}},
+	"recordSize": {name: "recordsize", kind: "int", min: 512, max: 1048576, powerOfTwo: true,
+		filesystem: true},
+	"atime":  {name: "atime", kind: "bool", filesystem: true},
+	"copies": {name: "copies", kind: "int", min: 1, max: 3, filesystem: true, lun: true},
+	"secondaryCache": {name: "secondarycache", kind: "string", filesystem: true, lun: true,
+		values: []string{"all", "metadata", "none"}},
+	"writeCache": {name: "writecache", kind: "bool", lun: true},
+	"readOnly":   {name: "readonly", kind: "bool", filesystem: true, lun: true},
+}
+
+// Returns the data properties of a file system set by the parameters passed in.
+func FilesystemProperties(parameters map[string]string) (map[string]interface{}, error) {
+	return dataProperties(parameters, false)
+}
+
+// Returns the data properties of a LUN set by the parameters passed in.
+func LunProperties(parameters map[string]string) (map[string]interface{}, error) {
+	return dataProperties(parameters, true)
+}
+
+// Validates the data properties set by the parameters passed in and returns them, keyed by
+// their name on the appliance. An InvalidArgument error is returned if a value is not
+// allowed or if the property does not apply to the type of share.
+func dataProperties(parameters map[string]string, lun bool) (map[string]interface{}, error) {
+	properties := make(map[string]interface{})
+
+	keys := make([]string, 0, len(parameters))
+	for key := range parameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		prop, ok := yml2dataProperty[key]
+		if !ok {
+			continue
+		}
+		param := strings.TrimSpace(parameters[key])
+		if lun && !prop.lun || !lun && !prop.filesystem {
+			shareType := "file systems"
+			if lun {
+				shareType = "LUNs"
+			}
+			return nil, grpcStatus.Errorf(codes.InvalidArgument, "%s does not apply to %s", key, shareType)
+		}
+
+		switch prop.kind {
+		case "bool":
+			val, err := parseOnOff(param)
+			if err != nil {
+				return nil, grpcStatus.Errorf(codes.InvalidArgument, "invalid %s (%s), true or false expected",
+					key, param)
+			}
+			properties[prop.name] = val
+		case "int":
+			val, err := strconv.ParseInt(param, 10, 64)
+			if err != nil || val < prop.min || val > prop.max || prop.powerOfTwo && val&(val-1) != 0 {
+				return nil, grpcStatus.Errorf(codes.InvalidArgument, "invalid %s (%s)", key, param)
+			}
+			properties[prop.name] = val
+		default:
+			val := strings.ToLower(param)
+			if !containsValue(prop.values, val) {
+				return nil, grpcStatus.Errorf(codes.InvalidArgument, "invalid %s (%s), %v expected", key,
+					param, prop.values)
+			}
+			properties[prop.name] = val
+		}
+	}
+
+	return properties, nil
+}
+
+// Parses a boolean, also accepting "on" and "off".
+func parseOnOff(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
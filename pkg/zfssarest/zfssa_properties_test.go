@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	grpcStatus "google.golang.org/grpc/status"
+)
+
+func TestDataProperties(t *testing.T) {
+	properties, err := FilesystemProperties(map[string]string{
+		"pool":           "p0",
+		"compression":    "GZIP-2",
+		"deduplication":  "false",
+		"recordSize":     "131072",
+		"atime":          "off",
+		"copies":         "2",
+		"secondaryCache": "metadata",
+		"readOnly":       "true",
+	})
+	if err != nil {
+		t.Fatalf("FilesystemProperties failed: %v", err)
+	}
+	expected := map[string]interface{}{
+		"compression":    "gzip-2",
+		"dedup":          false,
+		"recordsize":     int64(131072),
+		"atime":          false,
+		"copies":         int64(2),
+		"secondarycache": "metadata",
+		"readonly":       true,
+	}
+	if !reflect.DeepEqual(properties, expected) {
+		t.Errorf("unexpected file system properties %v", properties)
+	}
+
+	properties, err = LunProperties(map[string]string{"writeCache": "true", "logBias": "throughput",
+		"sync": "always", "checksum": "sha256"})
+	expected = map[string]interface{}{"writecache": true, "logbias": "throughput", "sync": "always",
+		"checksum": "sha256"}
+	if err != nil || !reflect.DeepEqual(properties, expected) {
+		t.Errorf("unexpected LUN properties %v, %v", properties, err)
+	}
+
+	for _, tt := range []struct {
+		parameters map[string]string
+		lun        bool
+	}{
+		{map[string]string{"compression": "zstd"}, false},
+		{map[string]string{"deduplication": "maybe"}, true},
+		{map[string]string{"recordSize": "100000"}, false},
+		{map[string]string{"recordSize": "2097152"}, false},
+		{map[string]string{"copies": "4"}, true},
+		{map[string]string{"writeCache": "true"}, false},
+		{map[string]string{"recordSize": "8192"}, true},
+		{map[string]string{"atime": "true"}, true},
+	} {
+		_, err := dataProperties(tt.parameters, tt.lun)
+		if grpcStatus.Code(err) != codes.InvalidArgument {
+			t.Errorf("%v: InvalidArgument expected, got %v", tt.parameters, err)
+		}
+	}
+}
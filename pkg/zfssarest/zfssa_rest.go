@@ -14,9 +14,11 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/oracle/zfssa-csi-driver/pkg/utils"
@@ -24,12 +26,16 @@ import (
 	grpcStatus "google.golang.org/grpc/status"
 )
 
+// Port the REST service of the appliance listens on.
+const zPort = "215"
+
 // Use RESTapi v2 as it returns scriptable and consistent values
 const (
-	zAppliance               string = "https://%s:215"
+	zAppliance               string = "https://%s"
 	zServices                       = zAppliance + "/api/access/v2"
 	zStorage                        = zAppliance + "/api/storage/v2"
 	zSan                            = zAppliance + "/api/san/v2"
+	zNetwork                        = zAppliance + "/api/network/v2"
 	zPools                          = zStorage + "/pools"
 	zPool                           = zPools + "/%s"
 	zAllProjects                    = zStorage + "/projects"
@@ -51,8 +57,16 @@ const (
 	zLUNSnapshot                    = zLUNSnapshots + "/%s"
 	zFilesystemDependents           = zFilesystemSnapshot + "/dependents"
 	zLUNDependents                  = zLUNSnapshot + "/dependents"
+	zTargets                        = zSan + "/%s/targets"
+	zTarget                         = zTargets + "/%s"
 	zTargetGroups                   = zSan + "/%s/target-groups"
 	zTargetGroup                    = zTargetGroups + "/%s"
+	zInitiators                     = zSan + "/%s/initiators"
+	zInitiator                      = zInitiators + "/%s"
+	zInitiatorGroups                = zSan + "/%s/initiator-groups"
+	zInitiatorGroup                 = zInitiatorGroups + "/%s"
+	zNetworkInterfaces              = zNetwork + "/interfaces"
+	zNetworkInterface               = zNetworkInterfaces + "/%s"
 	zProperties                     = zAppliance + "/api/storage/v2/schema"
 	zProperty                       = zProperties + "/%s"
 )
@@ -72,6 +86,8 @@ type Token struct {
 	state        int
 	xAuthSession string
 	xAuthName    string
+	created      time.Time // Creation of the session
+	lastUsed     time.Time // Last use of the session
 }
 
 type tokenList struct {
@@ -89,78 +105,225 @@ type faultResponse struct {
 	Fault faultInfo `json:"fault"`
 }
 
-var httpTransport = http.Transport{TLSClientConfig: &tls.Config{}}
-var httpClient = &http.Client{Transport: &httpTransport}
-var zServicesURL string
-var zName string
-var tokens tokenList
-var zfssaCertLocation string
-
-// Initializes the ZFSSA REST API interface
-func InitREST(name string, certLocation string, secure bool) error {
-	httpTransport.TLSClientConfig.InsecureSkipVerify = !secure
-	httpTransport.MaxConnsPerHost = 16
-	httpTransport.MaxIdleConnsPerHost = 16
-	httpTransport.IdleConnTimeout = 30 * time.Second
+// Client is the interface to the REST service of an appliance. It owns the HTTP
+// transport and the TLS configuration used to reach the appliance as well as the
+// sessions (tokens) opened on it. All the operations offered by the appliance are
+// methods of this type.
+type Client struct {
+	name           string
+	address        string
+	servicesURL    string
+	certLocation   string
+	secure         bool
+	httpMtx        sync.RWMutex
+	transport      *http.Transport
+	httpClient     *http.Client
+	clientCert     atomic.Pointer[tls.Certificate]
+	tokens         tokenList
+	retryPolicy    RetryPolicy
+	retry          retryBudget
+	timeouts       Timeouts
+	sessionTimeout time.Duration
+}
 
-	zfssaCertLocation = certLocation
+// Creates a client for the appliance passed in. If secure is true, the certificate
+// of the appliance is verified against the certificate stored at certLocation.
+func NewClient(name string, certLocation string, secure bool) (*Client, error) {
+	c := new(Client)
+	c.name = name
+	c.address = applianceAddress(name)
+	c.servicesURL = fmt.Sprintf(zServices, c.address)
+	c.certLocation = certLocation
+	c.secure = secure
+	c.tokens.list = make(map[string]*Token)
+	c.SetRetryPolicy(DefaultRetryPolicy)
+	c.SetTimeouts(DefaultTimeouts)
+	c.SetSessionTimeout(DefaultSessionTimeout)
+
+	if !secure {
+		c.setTLSConfig(&tls.Config{InsecureSkipVerify: true})
+		return c, nil
+	}
 
-	err := resetHttpTlsClient(nil)
+	err := c.resetHttpTlsClient(nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	zServicesURL = fmt.Sprintf(zServices, name)
-	zName = name
+	return c, nil
+}
+
+// Replaces the HTTP transport of the client by one using the TLS configuration passed in.
+// The requests in progress complete with the previous transport.
+func (c *Client) setTLSConfig(tlsConfig *tls.Config) {
+	tlsConfig.GetClientCertificate = c.getClientCertificate
+	transport := &http.Transport{TLSClientConfig: tlsConfig}
+	transport.MaxConnsPerHost = 16
+	transport.MaxIdleConnsPerHost = 16
+	transport.IdleConnTimeout = 30 * time.Second
+
+	c.httpMtx.Lock()
+	previous := c.transport
+	c.transport = transport
+	c.httpClient = &http.Client{Transport: transport}
+	c.httpMtx.Unlock()
+
+	if previous != nil {
+		previous.CloseIdleConnections()
+	}
+}
 
-	return nil
+// Returns the HTTP client to send a request with.
+func (c *Client) getHttpClient() *http.Client {
+	c.httpMtx.RLock()
+	defer c.httpMtx.RUnlock()
+	return c.httpClient
 }
 
-func resetHttpTlsClient(ctx context.Context) error {
-	if httpTransport.TLSClientConfig.InsecureSkipVerify {
-		utils.GetLogREST(ctx, 2).Println("resetHttpTransport skipped")
-		return nil
+// Returns the name of the appliance the client talks to.
+func (c *Client) Name() string {
+	return c.name
+}
+
+// Returns the address (host:port) of the REST service of the appliance. Unless the
+// name passed in already specifies a port, the default port of the appliance is used.
+func applianceAddress(name string) string {
+	if _, _, err := net.SplitHostPort(name); err == nil {
+		return name
 	}
-	// set TLSv1.2 for the minimum version of supporting TLS
-	httpTransport.TLSClientConfig.MinVersion = tls.VersionTLS12
+	return net.JoinHostPort(strings.Trim(name, "[]"), zPort)
+}
 
-	// Get the SystemCertPool, continue with an empty pool on error
-	utils.GetLogREST(ctx, 2).Println("loading RootCAs")
-	httpTransport.TLSClientConfig.RootCAs, _ = x509.SystemCertPool()
-	if httpTransport.TLSClientConfig.RootCAs == nil {
-		httpTransport.TLSClientConfig.RootCAs = x509.NewCertPool()
+// Returns the name of the pool in the href of a share or a snapshot
+// (/api/storage/v2/pools/<pool>/...).
+func poolFromHref(href string) string {
+	result := strings.Split(href, "/")
+	if len(result) < 6 {
+		return ""
+	}
+	return result[5]
+}
+
+// Reloads the certificate of the appliance from its location.
+func (c *Client) resetHttpTlsClient(ctx context.Context) error {
+	if !c.secure {
+		utils.GetLogREST(ctx, 2).Println("resetHttpTransport skipped")
+		return nil
 	}
 
-	certs, err := ioutil.ReadFile(zfssaCertLocation)
+	certs, err := ioutil.ReadFile(c.certLocation)
 	if err != nil {
 		return errors.New("failed to read ZFSSA certificate")
 	}
 
-	if ok := httpTransport.TLSClientConfig.RootCAs.AppendCertsFromPEM(certs); !ok {
+	return c.SetCertificate(ctx, certs)
+}
+
+// Makes the client trust the certificates (PEM format) passed in, in addition to the
+// certificates of the system. The sessions opened with the previous configuration are
+// invalidated. If the certificates cannot be used the configuration is left unchanged.
+func (c *Client) SetCertificate(ctx context.Context, certs []byte) error {
+	if !c.secure {
+		return nil
+	}
+
+	// Get the SystemCertPool, continue with an empty pool on error
+	utils.GetLogREST(ctx, 2).Println("loading RootCAs")
+	rootCAs, _ := x509.SystemCertPool()
+	if rootCAs == nil {
+		rootCAs = x509.NewCertPool()
+	}
+	if ok := rootCAs.AppendCertsFromPEM(certs); !ok {
 		return errors.New("failed to append the certificate")
 	}
 
-	tokens.list = make(map[string]*Token)
+	// set TLSv1.2 for the minimum version of supporting TLS
+	c.setTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12, RootCAs: rootCAs})
+	c.InvalidateSessions(ctx, "")
 	utils.GetLogREST(ctx, 5).Println("resetHttpTransport done")
 
 	return nil
 }
 
+// Makes the client present the certificate and private key (PEM format) passed in when
+// the appliance requests a client certificate. The sessions of the users without a
+// password are then created by authenticating with the certificate alone. The sessions
+// opened previously are invalidated.
+func (c *Client) SetClientCertificate(ctx context.Context, certPEM, keyPEM []byte) error {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return errors.New(fmt.Sprintf("invalid client certificate or key: %s", err))
+	}
+	c.clientCert.Store(&cert)
+
+	// Connections established with the previous certificate are not reused.
+	c.httpMtx.RLock()
+	c.transport.CloseIdleConnections()
+	c.httpMtx.RUnlock()
+	c.InvalidateSessions(ctx, "")
+	utils.GetLogREST(ctx, 5).Println("Client certificate loaded")
+	return nil
+}
+
+// Returns true if the client has a client certificate.
+func (c *Client) hasClientCertificate() bool {
+	return c.clientCert.Load() != nil
+}
+
+// Returns the certificate presented to the appliance. An empty certificate is returned
+// if the client has none, no certificate is sent in this case.
+func (c *Client) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	if cert := c.clientCert.Load(); cert != nil {
+		return cert, nil
+	}
+	return &tls.Certificate{}, nil
+}
+
+// Invalidates and closes the sessions opened for the user passed in (all the sessions if user is
+// empty). A new session is created by the next request using the token.
+func (c *Client) InvalidateSessions(ctx context.Context, user string) {
+	var sessions []string
+	c.tokens.mtx.Lock()
+	for name, token := range c.tokens.list {
+		if user != "" && name != user {
+			continue
+		}
+		utils.GetLogREST(ctx, 5).Println("Invalidating ZFSSA session", "user", name)
+		if xAuthSession := token.invalidate(); xAuthSession != "" {
+			sessions = append(sessions, xAuthSession)
+		}
+		delete(c.tokens.list, name)
+	}
+	c.tokens.mtx.Unlock()
+
+	for _, xAuthSession := range sessions {
+		c.logout(ctx, xAuthSession)
+	}
+}
+
 // Looks up a token context based on the user name passed in. If one doesn't exist
 // yet, it is created.
-func LookUpToken(ctx context.Context, user, password string) *Token {
-	tokens.mtx.Lock()
-	if token, ok := tokens.list[user]; ok {
+func (c *Client) LookUpToken(ctx context.Context, user, password string) *Token {
+	c.tokens.mtx.Lock()
+	if token, ok := c.tokens.list[user]; ok {
+		xAuthSession := ""
 		if password != "" && password != token.password {
+			// The session opened with the previous password is closed.
 			utils.GetLogREST(ctx, 2).Println("Target ZFSSA password updated for session")
+			xAuthSession = token.invalidate()
+			token.mtx.Lock()
 			token.password = password
+			token.mtx.Unlock()
+		}
+		c.tokens.mtx.Unlock()
+		if xAuthSession != "" {
+			c.logout(ctx, xAuthSession)
 		}
-		tokens.mtx.Unlock()
 		return token
 	}
 
 	token := new(Token)
-	token.Name = zName
+	token.Name = c.name
 	token.user = user
 	token.password = password
 	token.state = zfssaTokenInvalid
@@ -168,8 +331,8 @@ func LookUpToken(ctx context.Context, user, password string) *Token {
 	token.xAuthSession = ""
 	token.cv = sync.NewCond(&token.mtx)
 
-	tokens.list[user] = token
-	tokens.mtx.Unlock()
+	c.tokens.list[user] = token
+	c.tokens.mtx.Unlock()
 	return token
 }
 
@@ -177,7 +340,8 @@ func LookUpToken(ctx context.Context, user, password string) *Token {
 // token is passed in, it assumes that the caller received a status 401 from the ZFSSA
 // (probably because the token has expired). In that case this function will try to
 // create another one or, if another thread is already in the process of creating one,
-// it will wait until the creation has completed.
+// it will wait until the creation has completed. A session about to expire is replaced
+// by a new one and closed.
 //
 // The possible return values are:
 //
@@ -186,11 +350,14 @@ func LookUpToken(ctx context.Context, user, password string) *Token {
 //		nil											Valid
 //	 codes.Internal	"Failure getting token"		""
 //		codes.Internal	"Failure creating token"	""
+//		codes.Unauthenticated	"Failure creating token"	""
+//		codes.Unavailable	"Failure creating token"	""
 //
 //		In case of failure, the message logged will provide more information
 //		as to where the problem occurred.
-func getToken(ctx context.Context, token *Token, previous *string) (string, error) {
+func (c *Client) getToken(ctx context.Context, token *Token, previous *string) (string, error) {
 
+	expired := ""
 	token.mtx.Lock()
 	for {
 		switch token.state {
@@ -201,7 +368,7 @@ func getToken(ctx context.Context, token *Token, previous *string) (string, erro
 			token.mtx.Unlock()
 
 			var err error
-			token.xAuthSession, token.xAuthName, err = createZfssaSession(ctx, token)
+			token.xAuthSession, token.xAuthName, err = c.createZfssaSession(ctx, token)
 			xAuthSession := token.xAuthSession
 
 			token.mtx.Lock()
@@ -209,9 +376,14 @@ func getToken(ctx context.Context, token *Token, previous *string) (string, erro
 				token.state = zfssaTokenInvalid
 			} else {
 				token.state = zfssaTokenValid
+				token.created = time.Now()
+				token.lastUsed = token.created
 			}
 			token.cv.Broadcast()
 			token.mtx.Unlock()
+			if expired != "" {
+				c.logout(ctx, expired)
+			}
 			return xAuthSession, err
 
 		case zfssaTokenCreating:
@@ -220,9 +392,18 @@ func getToken(ctx context.Context, token *Token, previous *string) (string, erro
 			continue
 
 		case zfssaTokenValid:
+			now := time.Now()
+			if c.sessionExpiring(token, now) {
+				utils.GetLogREST(ctx, 5).Println("ZFSSA session about to expire, refreshing it",
+					"age", now.Sub(token.created).Round(time.Second))
+				expired = token.xAuthSession
+				token.state = zfssaTokenInvalid
+				continue
+			}
 			// We can use the current token.
 			if previous == nil || *previous != token.xAuthSession {
 				xAuthSession := token.xAuthSession
+				token.lastUsed = now
 				token.mtx.Unlock()
 				return xAuthSession, nil
 			}
@@ -239,59 +420,93 @@ func getToken(ctx context.Context, token *Token, previous *string) (string, erro
 // Send an HTTP request to the ZFSSA to create a non-persistent, reusable session.
 //
 // A non-persistent token is specific to the cluster node on which the ID was
-// created and is not synchronized between the cluster peers.
-func createZfssaSession(ctx context.Context, token *Token) (string, string, error) {
+// created and is not synchronized between the cluster peers. A token without a
+// password is authenticated by the client certificate, if the client has one.
+func (c *Client) createZfssaSession(ctx context.Context, token *Token) (string, string, error) {
 
-	httpReq, err := http.NewRequest("POST", zServicesURL, bytes.NewBuffer(nil))
+	httpReq, err := http.NewRequestWithContext(httpContext(ctx), "POST", c.servicesURL, bytes.NewBuffer(nil))
 	if err != nil {
 		utils.GetLogREST(ctx, 2).Println("Could not build a request to create a token",
-			"method", "POST", "url", zServicesURL, "error", err.Error())
+			"method", "POST", "url", c.servicesURL, "error", err.Error())
 		return "", "", grpcStatus.Error(codes.Internal, "Failure creating token")
 	}
 
-	httpReq.Header.Add("X-Auth-User", token.user)
-	httpReq.Header.Add("X-Auth-Key", token.password)
+	// Without a password, the client certificate authenticates the session.
+	if token.password != "" || !c.hasClientCertificate() {
+		httpReq.Header.Add("X-Auth-User", token.user)
+		httpReq.Header.Add("X-Auth-Key", token.password)
+	}
 
-	httpRsp, err := httpClient.Do(httpReq)
+	httpRsp, err := c.getHttpClient().Do(httpReq)
 	if err != nil {
 		utils.GetLogREST(ctx, 2).Println("Token creation failed in Do",
-			"url", zServicesURL, "error", err.Error())
+			"url", c.servicesURL, "error", err.Error())
+		if ctxErr := contextError(ctx); ctxErr != nil {
+			return "", "", ctxErr
+		}
+		var pinErr *pinError
+		if errors.As(err, &pinErr) {
+			return "", "", grpcStatus.Error(codes.Unavailable, pinErr.Error())
+		}
 		if strings.Contains(err.Error(), "failed to verify certificate") {
-			resetHttpTlsClient(ctx)
+			c.resetHttpTlsClient(ctx)
+			return "", "", grpcStatus.Error(codes.Internal, "Failure creating token")
 		}
-		return "", "", grpcStatus.Error(codes.Internal, "Failure creating token")
+		return "", "", &transientError{grpcStatus.Error(codes.Unavailable, "Failure creating token")}
 	}
 
 	defer httpRsp.Body.Close()
 
 	if httpRsp.StatusCode != http.StatusCreated {
 		utils.GetLogREST(ctx, 2).Println("Token creation failed in ZFSSA",
-			"url", zServicesURL, "StatusCode", httpRsp.StatusCode)
+			"url", c.servicesURL, "StatusCode", httpRsp.StatusCode)
+		switch {
+		case isTransientStatus(httpRsp.StatusCode):
+			return "", "", &transientError{grpcStatus.Error(codes.Unavailable, "Failure creating token")}
+		case httpRsp.StatusCode == http.StatusUnauthorized:
+			return "", "", grpcStatus.Error(codes.Unauthenticated, "Failure creating token")
+		}
 		return "", "", grpcStatus.Error(codes.Internal, "Failure creating token")
 	}
 
 	return httpRsp.Header.Get("X-Auth-Session"), httpRsp.Header.Get("X-Auth-Name"), nil
 }
 
-// Makes a request to a target appliance updating the token if needed.
-func MakeRequest(ctx context.Context, token *Token, method, url string, reqbody interface{}, status int,
+// Makes a request to a target appliance updating the token if needed. Requests other
+// than POST are retried if they fail for a transient reason.
+func (c *Client) MakeRequest(ctx context.Context, token *Token, method, url string, reqbody interface{}, status int,
 	rspbody interface{}) (interface{}, int, error) {
 
-	rsp, code, err := makeRequest(ctx, token, method, url, reqbody, status, rspbody)
-	if code == http.StatusUnauthorized && err == nil {
-		rsp, code, err = makeRequest(ctx, token, method, url, reqbody, status, rspbody)
+	return c.makeRetriedRequest(ctx, token, method, url, reqbody, status, rspbody, method != "POST", "")
+}
+
+// Makes a request creating the object whose URL is objectURL. The request is retried if
+// it fails for a transient reason, a conflict on a retry is resolved by reading the object
+// back into rspbody.
+func (c *Client) MakeCreateRequest(ctx context.Context, token *Token, method, url string, reqbody interface{},
+	status int, rspbody interface{}, objectURL string) (interface{}, int, error) {
+
+	return c.makeRetriedRequest(ctx, token, method, url, reqbody, status, rspbody, true, objectURL)
+}
+
+// Returns true if the HTTP status passed in denotes a failure that may not happen again,
+// typically while the appliance is taken over by its peer.
+func isTransientStatus(code int) bool {
+	switch code {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
 	}
-	return rsp, code, err
+	return false
 }
 
 // Local function makes the actual request to the ZFSSA.
-func makeRequest(ctx context.Context, token *Token, method, url string, reqbody interface{}, status int,
+func (c *Client) makeRequest(ctx context.Context, token *Token, method, url string, reqbody interface{}, status int,
 	rspbody interface{}) (interface{}, int, error) {
 
 	utils.GetLogREST(ctx, 5).Println("MakeRequest to ZFSSA",
 		"method", method, "url", url, "body", reqbody)
 
-	xAuthSession, err := getToken(ctx, token, nil)
+	xAuthSession, err := c.getToken(ctx, token, nil)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -303,36 +518,61 @@ func makeRequest(ctx context.Context, token *Token, method, url string, reqbody
 		return nil, 0, grpcStatus.Error(codes.Unknown, "json.Marshal call failed")
 	}
 
-	reqhttp, err := http.NewRequest(method, url, bytes.NewBuffer(reqjson))
+	reqhttp, err := http.NewRequestWithContext(httpContext(ctx), method, url, bytes.NewBuffer(reqjson))
 	if err != nil {
-		utils.GetLogREST(ctx, 2).Println("http.NewRequest call failed",
+		utils.GetLogREST(ctx, 2).Println("http.NewRequestWithContext call failed",
 			"method", method, "url", url, "body", reqbody, "error", err.Error())
-		return nil, 0, grpcStatus.Error(codes.Unknown, "http.NewRequest call failed")
+		return nil, 0, grpcStatus.Error(codes.Unknown, "http.NewRequestWithContext call failed")
 	}
 
 	reqhttp.Header.Add("X-Auth-Session", xAuthSession)
 	reqhttp.Header.Set("Content-Type", "application/json")
 	reqhttp.Header.Set("Accept", "application/json")
 
-	rsphttp, err := httpClient.Do(reqhttp)
+	rsphttp, err := c.getHttpClient().Do(reqhttp)
 	if err != nil {
 		utils.GetLogREST(ctx, 2).Println("client.do call failed",
 			"method", method, "url", url, "error", err.Error())
+		if ctxErr := contextError(ctx); ctxErr != nil {
+			return nil, 0, ctxErr
+		}
+		var pinErr *pinError
+		if errors.As(err, &pinErr) {
+			return nil, 0, grpcStatus.Error(codes.Unavailable, pinErr.Error())
+		}
 		if strings.Contains(err.Error(), "failed to verify certificate") {
 			utils.GetLogREST(ctx, 2).Println("mark token as invalid")
 			token.state = zfssaTokenInvalid
-			resetHttpTlsClient(ctx)
+			c.resetHttpTlsClient(ctx)
 
 			return nil, http.StatusUnauthorized, err
 		}
-		return nil, 0, grpcStatus.Error(codes.Unknown, "client.do call failed")
+		return nil, 0, &transientError{grpcStatus.Errorf(codes.Unavailable,
+			"target appliance could not be reached: %s", err.Error())}
 	}
 
 	// when err is nil, response body is always non-nil
 	defer rsphttp.Body.Close()
 
-	//d := json.NewDecoder(rsphttp.Body)
-	//err = d.Decode(rspbody)
+	// Lists are decoded while they are read, see decodeList.
+	if list, ok := rspbody.(listResponse); ok && rsphttp.StatusCode == status {
+		err = list.decodeList(json.NewDecoder(rsphttp.Body))
+		if err != nil {
+			utils.GetLogREST(ctx, 2).Println("Decoding of the list failed",
+				"method", method, "url", url, "error", err)
+			if ctxErr := contextError(ctx); ctxErr != nil {
+				return nil, rsphttp.StatusCode, ctxErr
+			}
+			if isDecodingError(err) {
+				return nil, rsphttp.StatusCode, grpcStatus.Error(codes.Unknown, "json decoding of the list failed")
+			}
+			return nil, rsphttp.StatusCode, &transientError{grpcStatus.Error(codes.Unavailable,
+				"reading of the list failed")}
+		}
+		utils.GetLogREST(ctx, 5).Println("Successful response from ZFSSA",
+			"method", method, "url", url, "result", rsphttp.StatusCode)
+		return rspbody, rsphttp.StatusCode, nil
+	}
 
 	// read json http response
 	rspjson, err := ioutil.ReadAll(rsphttp.Body)
@@ -340,7 +580,10 @@ func makeRequest(ctx context.Context, token *Token, method, url string, reqbody
 		utils.GetLogREST(ctx, 2).Println("ioutil.ReadAll call failed",
 			"method", method, "url", url, "code", rsphttp.StatusCode,
 			"status", rsphttp.Status, "error", err.Error())
-		return nil, rsphttp.StatusCode, grpcStatus.Error(codes.Unknown, "ioutil.ReadAll call failed")
+		if ctxErr := contextError(ctx); ctxErr != nil {
+			return nil, rsphttp.StatusCode, ctxErr
+		}
+		return nil, rsphttp.StatusCode, &transientError{grpcStatus.Error(codes.Unavailable, "ioutil.ReadAll call failed")}
 	}
 
 	if rsphttp.StatusCode == status {
@@ -361,34 +604,19 @@ func makeRequest(ctx context.Context, token *Token, method, url string, reqbody
 	// We check here whether the token may have expired and renew it if needed.
 	if rsphttp.StatusCode == http.StatusUnauthorized {
 		// Refresh token and secret
-		_, err = getToken(ctx, token, &xAuthSession)
+		_, err = c.getToken(ctx, token, &xAuthSession)
 		return nil, http.StatusUnauthorized, err
 	}
 
-	// status code was not what the user expected, attempt to unpack
+	// status code was not what the user expected, translate the fault
+	err = faultError(rsphttp.StatusCode, rspjson)
 	utils.GetLogREST(ctx, 2).Println("MakeRequest to ZFSSA resulted in an unexpected status",
 		"method", method, "url", url, "expected", status, "code", rsphttp.StatusCode,
-		"status", rsphttp.Status)
-
-	failure := &faultResponse{}
-	err = json.Unmarshal(rspjson, failure)
-	var responseString string
-	if err != nil {
-		utils.GetLogREST(ctx, 2).Println("Failure from ZFSSA could not be un-marshalled",
-			"method", method, "url", url, "code", rsphttp.StatusCode,
-			"status", rsphttp.Status, "body", rspjson)
-		responseString = string(rspjson)
-	} else {
-		responseString = failure.Fault.Message
-	}
+		"status", rsphttp.Status, "error", err)
 
-	switch rsphttp.StatusCode {
-	case http.StatusNotFound:
-		err = grpcStatus.Errorf(codes.NotFound, "Resource not found on target appliance: %s", responseString)
-	default:
-		err = grpcStatus.Errorf(codes.Unknown, "Unknown Error Occurred on target appliance: %s", responseString)
+	if isTransientStatus(rsphttp.StatusCode) {
+		return nil, rsphttp.StatusCode, &transientError{err}
 	}
-
 	return nil, rsphttp.StatusCode, err
 }
 
@@ -402,11 +630,11 @@ type Service struct {
 	URI     string `json:"uri"`
 }
 
-func GetServices(ctx context.Context, token *Token) (*[]Service, error) {
+func (c *Client) GetServices(ctx context.Context, token *Token) (*[]Service, error) {
 
 	rspJSON := new(services)
 	rspJSON.List = make([]Service, 0)
-	_, _, err := MakeRequest(ctx, token, "GET", zServicesURL, nil, http.StatusOK, rspJSON)
+	_, _, err := c.MakeRequest(ctx, token, "GET", c.servicesURL, nil, http.StatusOK, rspJSON)
 	if err != nil {
 		return nil, err
 	}
@@ -414,35 +642,13 @@ func GetServices(ctx context.Context, token *Token) (*[]Service, error) {
 	return &rspJSON.List, nil
 }
 
-// Unmarshalling of a "List" structure. This structure is the ZFSSA response to
+// Decoding of a "List" structure. This structure is the ZFSSA response to
 // the http request:
 //
 //	GET /api/access/v1 HTTP/1.1
 //	Host: zfs-storage.example.com
 //	X-Auth-User: admin
 //	X-Auth-Key: password
-func (l *services) UnmarshalJSON(b []byte) error {
-	return zfssaUnmarshalList(b, &l.List)
-}
-
-// Unmarshalling of a List sent by the ZFSSA
-func zfssaUnmarshalList(b []byte, l interface{}) error {
-
-	// 'b' starts and ends like this:
-	// {List:[{...},...,{...}]}
-	b = b[0 : len(b)-1]
-	for i := 1; i < len(b); i++ {
-		if b[i] == '[' {
-			b = b[i:]
-			break
-		}
-	}
-	// Now 'b' starts and ends like this:
-	// [{...},...,{...}]
-	err := json.Unmarshal(b, l)
-	if err != nil {
-		return err
-	}
-
-	return nil
+func (l *services) decodeList(dec *json.Decoder) error {
+	return decodeList(dec, "services", &l.List)
 }
@@ -0,0 +1,276 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/oracle/zfssa-csi-driver/pkg/utils"
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest/zfssatest"
+	"google.golang.org/grpc/codes"
+	grpcStatus "google.golang.org/grpc/status"
+)
+
+const (
+	testUser     = "csi-user"
+	testPassword = "csi-password"
+	testPool     = "p0"
+	testProject  = "k8s"
+)
+
+func TestMain(m *testing.M) {
+	utils.InitLogs("0", "zfssa-csi-driver", "test", "test-node")
+	os.Exit(m.Run())
+}
+
+// Starts a fake appliance with a pool and a project and returns a client talking to it.
+func newTestAppliance(t *testing.T) (*zfssatest.Server, *Client, *Token) {
+	t.Helper()
+	zfssa := zfssatest.NewServer(testUser, testPassword)
+	t.Cleanup(zfssa.Close)
+	zfssa.AddPool(testPool, 100<<30)
+	zfssa.AddProject(testPool, testProject)
+	zfssa.AddTargetGroup("iscsi", "tg0", "iqn.1986-03.com.sun:02:test")
+
+	c, err := NewClient(zfssa.Name(), zfssa.CertFile(), true)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	return zfssa, c, c.LookUpToken(context.Background(), testUser, testPassword)
+}
+
+func TestApplianceAddress(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"zfssa.example.com", "zfssa.example.com:215"},
+		{"10.0.0.1", "10.0.0.1:215"},
+		{"10.0.0.1:8215", "10.0.0.1:8215"},
+		{"fe80::1", "[fe80::1]:215"},
+		{"[fe80::1]", "[fe80::1]:215"},
+		{"[fe80::1]:8215", "[fe80::1]:8215"},
+	}
+	for _, tt := range tests {
+		if got := applianceAddress(tt.name); got != tt.want {
+			t.Errorf("applianceAddress(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGetServices(t *testing.T) {
+	zfssa, c, token := newTestAppliance(t)
+
+	services, err := c.GetServices(context.Background(), token)
+	if err != nil {
+		t.Fatalf("GetServices failed: %v", err)
+	}
+	if len(*services) == 0 {
+		t.Errorf("GetServices returned no service")
+	}
+	if zfssa.Sessions() != 1 {
+		t.Errorf("expected 1 session, got %d", zfssa.Sessions())
+	}
+}
+
+func TestInvalidCredentials(t *testing.T) {
+	_, c, _ := newTestAppliance(t)
+
+	token := c.LookUpToken(context.Background(), testUser, "wrong-password")
+	_, err := c.GetServices(context.Background(), token)
+	if err == nil {
+		t.Fatalf("GetServices succeeded with invalid credentials")
+	}
+}
+
+func TestExpiredSession(t *testing.T) {
+	zfssa, c, token := newTestAppliance(t)
+	ctx := context.Background()
+
+	if _, err := c.GetPool(ctx, token, testPool); err != nil {
+		t.Fatalf("GetPool failed: %v", err)
+	}
+	zfssa.ExpireSessions()
+	if _, err := c.GetPool(ctx, token, testPool); err != nil {
+		t.Fatalf("GetPool failed after the session expired: %v", err)
+	}
+	if zfssa.Sessions() != 2 {
+		t.Errorf("expected 2 sessions, got %d", zfssa.Sessions())
+	}
+}
+
+func TestFilesystemLifecycle(t *testing.T) {
+	zfssa, c, token := newTestAppliance(t)
+	ctx := context.Background()
+
+	parameters := map[string]string{
+		"pool":          testPool,
+		"project":       testProject,
+		"shareNFS":      "on",
+		"restrictChown": "false",
+	}
+	fs, code, err := c.CreateFilesystem(ctx, token, "vol1", 1<<30, &parameters, nil)
+	if err != nil {
+		t.Fatalf("CreateFilesystem failed (%d): %v", code, err)
+	}
+	if fs.Quota != 1<<30 || fs.ShareNFS != "on" || fs.MountPoint == "" {
+		t.Errorf("unexpected filesystem %+v", fs)
+	}
+
+	_, code, err = c.CreateFilesystem(ctx, token, "vol1", 1<<30, &parameters, nil)
+	if err == nil || code != http.StatusConflict {
+		t.Errorf("expected a conflict creating a duplicate filesystem, got (%d) %v", code, err)
+	}
+
+	modified, _, err := c.ModifyFilesystem(ctx, token, fs.Href, &map[string]interface{}{"quota": 2 << 30})
+	if err != nil {
+		t.Fatalf("ModifyFilesystem failed: %v", err)
+	}
+	if modified.Quota != 2<<30 {
+		t.Errorf("expected quota %d, got %d", 2<<30, modified.Quota)
+	}
+
+	list, err := c.GetFilesystems(ctx, token, testPool, testProject)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("GetFilesystems returned %v, %v", list, err)
+	}
+
+	snap, _, err := c.CreateSnapshot(ctx, token, fs.Href, "snap1")
+	if err != nil {
+		t.Fatalf("CreateSnapshot failed: %v", err)
+	}
+	if _, err = utils.DateToUnix(snap.Creation); err != nil {
+		t.Errorf("snapshot creation date %q cannot be parsed: %v", snap.Creation, err)
+	}
+
+	clone, _, err := c.CloneFileSystemSnapshot(ctx, token, snap.Href,
+		map[string]interface{}{"project": testProject, "share": "vol2"})
+	if err != nil {
+		t.Fatalf("CloneFileSystemSnapshot failed: %v", err)
+	}
+	if clone.Quota != 2<<30 {
+		t.Errorf("clone did not inherit the quota of its origin: %d", clone.Quota)
+	}
+
+	dependents, err := c.GetSnapshotDependents(ctx, token, snap.Href)
+	if err != nil || len(*dependents) != 1 || (*dependents)[0].Share != "vol2" {
+		t.Fatalf("GetSnapshotDependents returned %v, %v", dependents, err)
+	}
+
+	_, code, err = c.DeleteSnapshot(ctx, token, snap.Href)
+	if err == nil || code != http.StatusConflict {
+		t.Errorf("expected a conflict deleting a snapshot with clones, got (%d) %v", code, err)
+	}
+
+	if _, _, err = c.DeleteFilesystem(ctx, token, clone.Href); err != nil {
+		t.Fatalf("DeleteFilesystem failed: %v", err)
+	}
+	if _, _, err = c.DeleteSnapshot(ctx, token, snap.Href); err != nil {
+		t.Fatalf("DeleteSnapshot failed: %v", err)
+	}
+	if _, _, err = c.DeleteFilesystem(ctx, token, fs.Href); err != nil {
+		t.Fatalf("DeleteFilesystem failed: %v", err)
+	}
+
+	_, code, err = c.GetFilesystem(ctx, token, testPool, testProject, "vol1")
+	if code != http.StatusNotFound || grpcStatus.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got (%d) %v", code, err)
+	}
+	if _, found := zfssa.Lookup(fs.Href); found {
+		t.Errorf("filesystem still present on the appliance")
+	}
+}
+
+func TestLunLifecycle(t *testing.T) {
+	_, c, token := newTestAppliance(t)
+	ctx := context.Background()
+
+	parameters := map[string]string{
+		"pool":        testPool,
+		"project":     testProject,
+		"targetGroup": "tg0",
+		"blockSize":   "8192",
+		"volumeType":  "thin",
+	}
+	vid, lun, _, err := c.CreateLUN(ctx, token, "lun1", 1<<30, &parameters, nil)
+	if err != nil {
+		t.Fatalf("CreateLUN failed: %v", err)
+	}
+	if vid.String() != "/lun/"+token.Name+"/p0/k8s/lun1" {
+		t.Errorf("unexpected volume ID %s", vid.String())
+	}
+	if len(lun.AssignedNumber) != 1 || lun.TargetGroup != "tg0" || int64(lun.VolumeSize) != 1<<30 {
+		t.Errorf("unexpected LUN %+v", lun)
+	}
+
+	group, err := c.GetTargetGroup(ctx, token, "iscsi", lun.TargetGroup)
+	if err != nil || len(group.Targets) != 1 {
+		t.Fatalf("GetTargetGroup returned %v, %v", group, err)
+	}
+
+	list, err := c.GetInitiatorGroupList(ctx, token, testPool, testProject, "lun1")
+	if err != nil || len(list) != 1 || list[0] != MaskAll {
+		t.Fatalf("GetInitiatorGroupList returned %v, %v", list, err)
+	}
+	if _, err = c.SetInitiatorGroupList(ctx, token, testPool, testProject, "lun1", "node1"); err != nil {
+		t.Fatalf("SetInitiatorGroupList failed: %v", err)
+	}
+	list, err = c.GetInitiatorGroupList(ctx, token, testPool, testProject, "lun1")
+	if err != nil || len(list) != 1 || list[0] != "node1" {
+		t.Fatalf("GetInitiatorGroupList returned %v, %v", list, err)
+	}
+
+	luns, err := c.GetLuns(ctx, token, "", "")
+	if err != nil || len(luns) != 1 {
+		t.Fatalf("GetLuns returned %v, %v", luns, err)
+	}
+
+	if _, _, err = c.DeleteLun(ctx, token, testPool, testProject, "lun1"); err != nil {
+		t.Fatalf("DeleteLun failed: %v", err)
+	}
+}
+
+func TestInjectedFault(t *testing.T) {
+	zfssa, c, token := newTestAppliance(t)
+	ctx := context.Background()
+
+	zfssa.AddFault(zfssatest.Fault{
+		Method: "GET",
+		Path:   "/pools/" + testPool + "/projects/" + testProject,
+		Status: http.StatusNotFound,
+		Times:  1,
+	})
+
+	if _, err := c.GetProject(ctx, token, testPool, testProject); grpcStatus.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+	if _, err := c.GetProject(ctx, token, testPool, testProject); err != nil {
+		t.Errorf("GetProject failed once the fault was consumed: %v", err)
+	}
+}
+
+func TestIndependentClients(t *testing.T) {
+	zfssa1, c1, token1 := newTestAppliance(t)
+	zfssa2, c2, token2 := newTestAppliance(t)
+	ctx := context.Background()
+
+	parameters := map[string]string{"pool": testPool, "project": testProject}
+	if _, _, err := c1.CreateFilesystem(ctx, token1, "vol1", 1<<30, &parameters, nil); err != nil {
+		t.Fatalf("CreateFilesystem failed: %v", err)
+	}
+	if _, _, err := c2.GetFilesystem(ctx, token2, testPool, testProject, "vol1"); grpcStatus.Code(err) != codes.NotFound {
+		t.Errorf("filesystem created on the wrong appliance: %v", err)
+	}
+	if token1 == token2 || token1.Name == token2.Name {
+		t.Errorf("clients share their tokens")
+	}
+	if zfssa1.Sessions() != 1 || zfssa2.Sessions() != 1 {
+		t.Errorf("unexpected sessions %d/%d", zfssa1.Sessions(), zfssa2.Sessions())
+	}
+}
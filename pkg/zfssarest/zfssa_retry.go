@@ -0,0 +1,202 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/oracle/zfssa-csi-driver/pkg/utils"
+)
+
+// Requests failing for a transient reason (connection failure, appliance unavailable
+// during a takeover...) are retried following a RetryPolicy. Only the requests that can
+// safely be sent again are retried:
+//
+//   - GET, PUT and DELETE requests.
+//   - Requests creating an object (POST or PUT) sent with MakeCreateRequest. If the
+//     response to a previous attempt was lost, the object may already exist and the
+//     retry be answered with a conflict (409). The object is then read back and the
+//     request considered successful.
+//
+// A retried DELETE answered with a 404 is considered successful as well.
+//
+// The delay between attempts grows exponentially and is randomized (jitter). No retry
+// is attempted if the delay would go beyond the deadline of the context of the request.
+// Finally, to avoid overloading an appliance already in trouble, the retries of a client
+// are limited by a budget: each retry consumes a token, each successful request gives
+// back a fraction of token.
+
+// RetryPolicy describes how the requests failing for a transient reason are retried.
+type RetryPolicy struct {
+	MaxAttempts int           // Attempts of a request, the first one included
+	BaseDelay   time.Duration // Delay before the first retry
+	MaxDelay    time.Duration // Maximum delay between two attempts
+	Budget      float64       // Maximum number of tokens of the retry budget
+	BudgetRatio float64       // Tokens given back to the budget by a successful request
+}
+
+// Retry policy of new clients.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+	Budget:      10,
+	BudgetRatio: 0.1,
+}
+
+// Failure of a request that may succeed if the request is sent again. This type never
+// leaves the package, the error wrapped is returned to the caller.
+type transientError struct {
+	error
+}
+
+func (e *transientError) Unwrap() error { return e.error }
+
+type retryBudget struct {
+	mtx    sync.Mutex
+	tokens float64
+}
+
+// Sets the retry policy of the client. The budget of the client is refilled.
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	c.retry.mtx.Lock()
+	c.retryPolicy = policy
+	c.retry.tokens = policy.Budget
+	c.retry.mtx.Unlock()
+}
+
+// Returns the retry policy of the client.
+func (c *Client) getRetryPolicy() RetryPolicy {
+	c.retry.mtx.Lock()
+	defer c.retry.mtx.Unlock()
+	return c.retryPolicy
+}
+
+// Takes a token from the budget. Returns false if the budget is exhausted.
+func (c *Client) withdrawRetry() bool {
+	c.retry.mtx.Lock()
+	defer c.retry.mtx.Unlock()
+	if c.retry.tokens < 1 {
+		return false
+	}
+	c.retry.tokens--
+	return true
+}
+
+// Gives back a fraction of token to the budget after a successful request.
+func (c *Client) depositRetry() {
+	c.retry.mtx.Lock()
+	c.retry.tokens += c.retryPolicy.BudgetRatio
+	if c.retry.tokens > c.retryPolicy.Budget {
+		c.retry.tokens = c.retryPolicy.Budget
+	}
+	c.retry.mtx.Unlock()
+}
+
+// Returns the delay to wait before the next attempt, attempt being the number of
+// attempts already made. The delay is randomly chosen between half and all of the
+// exponential delay.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// Waits before the next attempt. Returns false if the context is done or would be
+// past its deadline at the end of the wait.
+func waitRetry(ctx context.Context, delay time.Duration) bool {
+	if ctx == nil {
+		time.Sleep(delay)
+		return true
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return false
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// Sends a request to the appliance, retrying it if it fails for a transient reason and
+// idempotent is true. For a request creating an object, objectURL is the URL of the
+// object created.
+func (c *Client) makeRetriedRequest(ctx context.Context, token *Token, method, url string,
+	reqbody interface{}, status int, rspbody interface{}, idempotent bool,
+	objectURL string) (interface{}, int, error) {
+
+	ctx, cancel := c.requestContext(ctx, method, objectURL != "")
+	defer cancel()
+
+	policy := c.getRetryPolicy()
+	for attempt := 1; ; attempt++ {
+		rsp, code, err := c.makeRequest(ctx, token, method, url, reqbody, status, rspbody)
+		if code == http.StatusUnauthorized && err == nil {
+			rsp, code, err = c.makeRequest(ctx, token, method, url, reqbody, status, rspbody)
+		}
+
+		if attempt > 1 && err != nil {
+			switch {
+			case code == http.StatusConflict && objectURL != "":
+				// The object may have been created by a previous attempt.
+				utils.GetLogREST(ctx, 2).Println("Conflict on a retried request, reading the object back",
+					"method", method, "url", url, "object", objectURL)
+				if _, _, errGet := c.MakeRequest(ctx, token, "GET", objectURL, nil, http.StatusOK,
+					rspbody); errGet == nil {
+					return rspbody, status, nil
+				}
+			case code == http.StatusNotFound && method == "DELETE":
+				// The object was deleted by a previous attempt.
+				return nil, status, nil
+			}
+		}
+
+		var transient *transientError
+		if !errors.As(err, &transient) {
+			if err == nil {
+				c.depositRetry()
+			}
+			return rsp, code, err
+		}
+
+		if !idempotent || attempt >= policy.MaxAttempts {
+			return nil, code, transient.error
+		}
+		if !c.withdrawRetry() {
+			utils.GetLogREST(ctx, 2).Println("Request not retried, retry budget exhausted",
+				"method", method, "url", url)
+			return nil, code, transient.error
+		}
+		delay := policy.backoff(attempt)
+		if !waitRetry(ctx, delay) {
+			utils.GetLogREST(ctx, 2).Println("Request not retried, context done or deadline too close",
+				"method", method, "url", url)
+			if ctxErr := contextError(ctx); ctxErr != nil {
+				return nil, code, ctxErr
+			}
+			return nil, code, transient.error
+		}
+		utils.GetLogREST(ctx, 2).Println("Retrying request", "method", method, "url", url,
+			"attempt", attempt+1, "delay", delay, "error", transient.error)
+	}
+}
@@ -0,0 +1,182 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest/zfssatest"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   time.Millisecond,
+	MaxDelay:    5 * time.Millisecond,
+	Budget:      10,
+	BudgetRatio: 0.1,
+}
+
+// Returns the number of requests received by the fake appliance with the method passed
+// in and a path ending with suffix.
+func countRequests(zfssa *zfssatest.Server, method, suffix string) int {
+	count := 0
+	for _, r := range zfssa.Requests() {
+		if r.Method == method && strings.HasSuffix(r.Path, suffix) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		for i := 0; i < 20; i++ {
+			delay := policy.backoff(attempt + 1)
+			if delay < max/2 || delay > max {
+				t.Fatalf("backoff(%d) = %v, expected between %v and %v", attempt+1, delay, max/2, max)
+			}
+		}
+	}
+}
+
+func TestRetryTransientFailures(t *testing.T) {
+	zfssa, c, token := newTestAppliance(t)
+	c.SetRetryPolicy(testRetryPolicy)
+	ctx := context.Background()
+
+	zfssa.AddFault(zfssatest.Fault{Method: "GET", Path: "/pools/" + testPool,
+		Status: http.StatusServiceUnavailable, Times: 1})
+	zfssa.AddFault(zfssatest.Fault{Method: "GET", Path: "/pools/" + testPool, Drop: true, Times: 1})
+	zfssa.ResetRequests()
+
+	if _, err := c.GetPool(ctx, token, testPool); err != nil {
+		t.Fatalf("GetPool failed: %v", err)
+	}
+	if n := countRequests(zfssa, "GET", "/pools/"+testPool); n != 3 {
+		t.Errorf("expected 3 attempts, got %d", n)
+	}
+
+	// The number of attempts is bounded.
+	zfssa.AddFault(zfssatest.Fault{Method: "GET", Path: "/pools/" + testPool,
+		Status: http.StatusServiceUnavailable})
+	zfssa.ResetRequests()
+	if _, err := c.GetPool(ctx, token, testPool); err == nil {
+		t.Fatalf("GetPool succeeded on an unavailable appliance")
+	}
+	if n := countRequests(zfssa, "GET", "/pools/"+testPool); n != testRetryPolicy.MaxAttempts {
+		t.Errorf("expected %d attempts, got %d", testRetryPolicy.MaxAttempts, n)
+	}
+}
+
+func TestNoRetryOnPost(t *testing.T) {
+	zfssa, c, token := newTestAppliance(t)
+	c.SetRetryPolicy(testRetryPolicy)
+
+	zfssa.AddFault(zfssatest.Fault{Method: "POST", Path: "/schema", Status: http.StatusServiceUnavailable})
+	zfssa.ResetRequests()
+
+	url := "https://" + c.address + "/api/storage/v2/schema"
+	_, _, err := c.MakeRequest(context.Background(), token, "POST", url,
+		Schema{Type: "String", Property: "p1"}, http.StatusCreated, nil)
+	if err == nil {
+		t.Fatalf("POST succeeded on an unavailable appliance")
+	}
+	if n := countRequests(zfssa, "POST", "/schema"); n != 1 {
+		t.Errorf("a POST was retried, %d attempts", n)
+	}
+}
+
+func TestRetryLostResponses(t *testing.T) {
+	zfssa, c, token := newTestAppliance(t)
+	c.SetRetryPolicy(testRetryPolicy)
+	ctx := context.Background()
+
+	// The filesystem is created but the response is lost, the retry gets a conflict.
+	zfssa.AddFault(zfssatest.Fault{Method: "POST", Path: "/filesystems",
+		Status: http.StatusServiceUnavailable, Processed: true, Times: 1})
+	parameters := map[string]string{"pool": testPool, "project": testProject}
+	fs, code, err := c.CreateFilesystem(ctx, token, "vol1", 1<<30, &parameters, nil)
+	if err != nil || code != http.StatusCreated {
+		t.Fatalf("CreateFilesystem failed (%d): %v", code, err)
+	}
+	if fs.Name != "vol1" || fs.Quota != 1<<30 {
+		t.Errorf("unexpected filesystem %+v", fs)
+	}
+
+	// Same thing for a snapshot and its deletion.
+	zfssa.AddFault(zfssatest.Fault{Method: "POST", Path: "/snapshots",
+		Status: http.StatusGatewayTimeout, Processed: true, Times: 1})
+	snap, _, err := c.CreateSnapshot(ctx, token, fs.Href, "snap1")
+	if err != nil || snap.Name != "snap1" {
+		t.Fatalf("CreateSnapshot returned %v, %v", snap, err)
+	}
+	zfssa.AddFault(zfssatest.Fault{Method: "DELETE", Path: "/snapshots/snap1",
+		Status: http.StatusServiceUnavailable, Processed: true, Times: 1})
+	if _, _, err = c.DeleteSnapshot(ctx, token, snap.Href); err != nil {
+		t.Fatalf("DeleteSnapshot failed: %v", err)
+	}
+	if _, found := zfssa.Lookup(snap.Href); found {
+		t.Errorf("snapshot still present on the appliance")
+	}
+
+	// Without a retry, a conflict is still reported.
+	if _, code, err = c.CreateFilesystem(ctx, token, "vol1", 1<<30, &parameters, nil); code != http.StatusConflict {
+		t.Errorf("expected a conflict, got (%d) %v", code, err)
+	}
+}
+
+func TestRetryDeadline(t *testing.T) {
+	zfssa, c, token := newTestAppliance(t)
+	policy := testRetryPolicy
+	policy.BaseDelay = time.Minute
+	policy.MaxDelay = time.Minute
+	c.SetRetryPolicy(policy)
+
+	zfssa.AddFault(zfssatest.Fault{Method: "GET", Path: "/pools/" + testPool,
+		Status: http.StatusServiceUnavailable})
+	zfssa.ResetRequests()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.GetPool(ctx, token, testPool); err == nil {
+		t.Fatalf("GetPool succeeded on an unavailable appliance")
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("the request waited beyond its deadline: %v", time.Since(start))
+	}
+	if n := countRequests(zfssa, "GET", "/pools/"+testPool); n != 1 {
+		t.Errorf("expected 1 attempt, got %d", n)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	zfssa, c, token := newTestAppliance(t)
+	policy := testRetryPolicy
+	policy.Budget = 2
+	c.SetRetryPolicy(policy)
+	ctx := context.Background()
+
+	zfssa.AddFault(zfssatest.Fault{Method: "GET", Path: "/pools/" + testPool,
+		Status: http.StatusServiceUnavailable})
+	zfssa.ResetRequests()
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetPool(ctx, token, testPool); err == nil {
+			t.Fatalf("GetPool succeeded on an unavailable appliance")
+		}
+	}
+	// 2 first attempts and the 2 retries of the budget.
+	if n := countRequests(zfssa, "GET", "/pools/"+testPool); n != 4 {
+		t.Errorf("expected 4 attempts, got %d", n)
+	}
+}
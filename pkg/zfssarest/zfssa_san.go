@@ -34,12 +34,27 @@ type targetGroupJSON struct {
 	Group 	TargetGroup `json:"group"`
 }
 
-func GetTargetGroup(ctx context.Context, token *Token, protocol, groupName string) (*TargetGroup, error) {
+// Returns the target whose name (IQN or alias for iSCSI, WWN for Fibre Channel) is
+// passed in.
+func (c *Client) GetTarget(ctx context.Context, token *Token, protocol, name string) (*Target, error) {
 
-	url := fmt.Sprintf(zTargetGroup, token.Name, protocol, groupName)
+	url := fmt.Sprintf(zTarget, c.address, protocol, name)
+
+	rspBody := &targetJSON{}
+	_, _, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, rspBody)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rspBody.Target, nil
+}
+
+func (c *Client) GetTargetGroup(ctx context.Context, token *Token, protocol, groupName string) (*TargetGroup, error) {
+
+	url := fmt.Sprintf(zTargetGroup, c.address, protocol, groupName)
 
 	rspBody := &targetGroupJSON{}
-	_, _, err := MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, rspBody)
+	_, _, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, rspBody)
 	if err != nil {
 		return nil, err
 	}
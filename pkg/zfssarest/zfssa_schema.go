@@ -28,14 +28,15 @@ type Property struct {
 	Property Schema `json:"property"`
 }
 
-func CreateProperty(ctx context.Context, token *Token, s Schema) (*Schema, error) {
+func (c *Client) CreateProperty(ctx context.Context, token *Token, s Schema) (*Schema, error) {
 
 	utils.GetLogREST(ctx, 5).Println("CreateSchema", "schema", s, "target", token.Name)
 
-	url := fmt.Sprintf(zProperties, token.Name)
+	url := fmt.Sprintf(zProperties, c.address)
 
 	resultSchema := &Property{}
-	_, _, err := MakeRequest(nil, token, "POST", url, s, http.StatusCreated, resultSchema)
+	objectURL := fmt.Sprintf(zProperty, c.address, s.Property)
+	_, _, err := c.MakeCreateRequest(ctx, token, "POST", url, s, http.StatusCreated, resultSchema, objectURL)
 	if err != nil {
 		return nil, err
 	}
@@ -43,12 +44,12 @@ func CreateProperty(ctx context.Context, token *Token, s Schema) (*Schema, error
 	return &resultSchema.Property, nil
 }
 
-func GetProperty(ctx context.Context, token *Token, property string) (*Schema, error) {
+func (c *Client) GetProperty(ctx context.Context, token *Token, property string) (*Schema, error) {
 	utils.GetLogREST(ctx, 5).Println("GetSchema", "property", property, "target", token.Name)
-	url := fmt.Sprintf(zProperty, token.Name, property)
+	url := fmt.Sprintf(zProperty, c.address, property)
 
 	resultSchema := &Property{}
-	_, _, err := MakeRequest(nil, token, "GET", url, nil, http.StatusOK, resultSchema)
+	_, _, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, resultSchema)
 	if err != nil {
 		return nil, err
 	}
@@ -56,13 +57,13 @@ func GetProperty(ctx context.Context, token *Token, property string) (*Schema, e
 	return &resultSchema.Property, nil
 }
 
-func GetSchema(ctx context.Context, token *Token) (*SchemaList, error) {
+func (c *Client) GetSchema(ctx context.Context, token *Token) (*SchemaList, error) {
 	utils.GetLogREST(ctx, 5).Println("GetSchema")
 
-	url := fmt.Sprintf(zProperties, token.Name)
+	url := fmt.Sprintf(zProperties, c.address)
 
 	jsonData := &SchemaList{}
-	_, _, err := MakeRequest(nil, token, "GET", url, nil, http.StatusOK, jsonData)
+	_, _, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, jsonData)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/oracle/zfssa-csi-driver/pkg/utils"
+)
+
+// The sessions opened on an appliance are non-persistent: the appliance closes a session
+// that has not been used for its session timeout (15 minutes by default). A session idle
+// for almost that long is replaced by a new one before it is used again, instead of
+// waiting for the appliance to reject a request with a 401. Sessions no longer needed
+// (replaced, opened with a previous password or certificate, or still open when the
+// driver stops) are explicitly closed on the appliance.
+
+// Session timeout of the appliance assumed by new clients.
+const DefaultSessionTimeout = 15 * time.Minute
+
+// A session is refreshed when it would expire within this margin.
+const sessionRefreshMargin = time.Minute
+
+// Maximum time spent closing a session.
+const logoutTimeout = 10 * time.Second
+
+// Sets the session timeout of the appliance. It must be called before the client is
+// used. A timeout of zero disables the refresh of idle sessions.
+func (c *Client) SetSessionTimeout(timeout time.Duration) {
+	c.sessionTimeout = timeout
+}
+
+// Returns true if the session of the token will expire soon. The token must be locked.
+func (c *Client) sessionExpiring(token *Token, now time.Time) bool {
+	if c.sessionTimeout <= 0 {
+		return false
+	}
+	return now.Sub(token.lastUsed) >= c.sessionTimeout-sessionRefreshMargin
+}
+
+// Closes all the sessions of the client, typically when the driver stops.
+func (c *Client) CloseSessions(ctx context.Context) {
+	c.tokens.mtx.Lock()
+	tokens := make([]*Token, 0, len(c.tokens.list))
+	for _, token := range c.tokens.list {
+		tokens = append(tokens, token)
+	}
+	c.tokens.mtx.Unlock()
+
+	for _, token := range tokens {
+		if xAuthSession := token.invalidate(); xAuthSession != "" {
+			c.logout(ctx, xAuthSession)
+		}
+	}
+}
+
+// Marks the token as invalid and returns the session it held, if any.
+func (t *Token) invalidate() string {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.state != zfssaTokenValid {
+		return ""
+	}
+	t.state = zfssaTokenInvalid
+	xAuthSession := t.xAuthSession
+	t.xAuthSession = ""
+	return xAuthSession
+}
+
+// Closes the session passed in on the appliance. A failure is only logged, the session
+// eventually expires on the appliance anyway.
+func (c *Client) logout(ctx context.Context, xAuthSession string) {
+	reqCtx, cancel := context.WithTimeout(httpContext(ctx), logoutTimeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(reqCtx, "DELETE", c.servicesURL, bytes.NewBuffer(nil))
+	if err != nil {
+		utils.GetLogREST(ctx, 2).Println("Could not build a request to close a session",
+			"url", c.servicesURL, "error", err.Error())
+		return
+	}
+	httpReq.Header.Add("X-Auth-Session", xAuthSession)
+
+	httpRsp, err := c.getHttpClient().Do(httpReq)
+	if err != nil {
+		utils.GetLogREST(ctx, 2).Println("Session could not be closed",
+			"url", c.servicesURL, "error", err.Error())
+		return
+	}
+	httpRsp.Body.Close()
+
+	switch httpRsp.StatusCode {
+	case http.StatusNoContent, http.StatusUnauthorized:
+		// A session that already expired is rejected with 401.
+		utils.GetLogREST(ctx, 5).Println("ZFSSA session closed", "appliance", c.name)
+	default:
+		utils.GetLogREST(ctx, 2).Println("Session could not be closed",
+			"url", c.servicesURL, "StatusCode", httpRsp.StatusCode)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSessionRefresh(t *testing.T) {
+	zfssa, c, token := newTestAppliance(t)
+	if _, err := c.GetPool(context.Background(), token, testPool); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	// A session used recently is reused.
+	token.mtx.Lock()
+	token.lastUsed = time.Now().Add(-time.Minute)
+	token.mtx.Unlock()
+	if _, err := c.GetPool(context.Background(), token, testPool); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if zfssa.Sessions() != 1 {
+		t.Fatalf("session renewed although it was recently used")
+	}
+
+	// A session about to expire is replaced and closed.
+	token.mtx.Lock()
+	token.lastUsed = time.Now().Add(-DefaultSessionTimeout + sessionRefreshMargin/2)
+	token.mtx.Unlock()
+	if _, err := c.GetPool(context.Background(), token, testPool); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if zfssa.Sessions() != 2 || zfssa.OpenSessions() != 1 {
+		t.Errorf("session not refreshed: %d sessions created, %d open", zfssa.Sessions(),
+			zfssa.OpenSessions())
+	}
+}
+
+func TestSessionLogout(t *testing.T) {
+	zfssa, c, token := newTestAppliance(t)
+	if _, err := c.GetPool(context.Background(), token, testPool); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	// The session opened with the previous password is closed.
+	c.LookUpToken(context.Background(), testUser, testPassword+"-new")
+	if zfssa.OpenSessions() != 0 {
+		t.Errorf("session not closed after a password change")
+	}
+
+	token = c.LookUpToken(context.Background(), testUser, testPassword)
+	if _, err := c.GetPool(context.Background(), token, testPool); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if zfssa.OpenSessions() != 1 {
+		t.Fatalf("unexpected number of sessions open: %d", zfssa.OpenSessions())
+	}
+	c.CloseSessions(context.Background())
+	if zfssa.OpenSessions() != 0 {
+		t.Errorf("session not closed")
+	}
+
+	// A closed session is opened again when needed.
+	if _, err := c.GetPool(context.Background(), token, testPool); err != nil {
+		t.Errorf("request failed after the sessions were closed: %v", err)
+	}
+}
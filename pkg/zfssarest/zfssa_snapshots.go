@@ -8,6 +8,7 @@ package zfssarest
 import (
 	"github.com/oracle/zfssa-csi-driver/pkg/utils"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 )
@@ -47,15 +48,16 @@ type dependents struct {
 
 // Issues a request to the appliance to create a snapshot. The source of the snapshot, LUN
 // or filesystem, is determine by the HREF passed in.
-func CreateSnapshot(ctx context.Context, token *Token, href, name string) (*Snapshot, int, error) {
+func (c *Client) CreateSnapshot(ctx context.Context, token *Token, href, name string) (*Snapshot, int, error) {
 
-	url := fmt.Sprintf(zAppliance + href + "/snapshots", token.Name)
+	url := fmt.Sprintf(zAppliance + href + "/snapshots", c.address)
 
 	reqBody := make(map[string]interface{})
 	reqBody["name"] = name
 	rspBody := new(snapshotJSON)
 
-	_, code, err := MakeRequest(ctx, token, "POST", url, &reqBody, http.StatusCreated, rspBody)
+	objectURL := fmt.Sprintf(zAppliance + href + "/snapshots/%s", c.address, name)
+	_, code, err := c.MakeCreateRequest(ctx, token, "POST", url, &reqBody, http.StatusCreated, rspBody, objectURL)
 	if err != nil {
 		return nil, code, err
 	}
@@ -65,12 +67,12 @@ func CreateSnapshot(ctx context.Context, token *Token, href, name string) (*Snap
 
 // Issues a request to the appliance to delete a snapshot. The source of the snapshot, LUN
 // or filesystem, is determine by the HREF passed in.
-func DeleteSnapshot(ctx context.Context, token *Token, href string) (
+func (c *Client) DeleteSnapshot(ctx context.Context, token *Token, href string) (
 	bool, int, error) {
 
-	url := fmt.Sprintf(zAppliance + href, token.Name)
+	url := fmt.Sprintf(zAppliance + href, c.address)
 
-	_, httpStatus, err := MakeRequest(ctx, token, "DELETE", url, nil, http.StatusNoContent, nil)
+	_, httpStatus, err := c.MakeRequest(ctx, token, "DELETE", url, nil, http.StatusNoContent, nil)
 	if err != nil {
 		return false, httpStatus, err
 	}
@@ -86,12 +88,12 @@ func DeleteSnapshot(ctx context.Context, token *Token, href string) (
 }
 
 // Issues a request to the appliance asking for the detailed information of a snapshot.
-func GetSnapshot(ctx context.Context, token *Token, href, name string) (*Snapshot, int, error) {
+func (c *Client) GetSnapshot(ctx context.Context, token *Token, href, name string) (*Snapshot, int, error) {
 
-	url := fmt.Sprintf(zAppliance + href + "/snapshots/%s", token.Name, name)
+	url := fmt.Sprintf(zAppliance + href + "/snapshots/%s", c.address, name)
 
 	rspJSON := &snapshotJSON{}
-	_, httpStatus, err := MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, rspJSON)
+	_, httpStatus, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, rspJSON)
 	if err != nil {
 		return nil, httpStatus, err
 	}
@@ -100,20 +102,20 @@ func GetSnapshot(ctx context.Context, token *Token, href, name string) (*Snapsho
 }
 
 // Issues a request to the appliance asking for a volume's snapshot list.
-func GetSnapshots(ctx context.Context, token *Token, href string) ([]Snapshot, error) {
+func (c *Client) GetSnapshots(ctx context.Context, token *Token, href string) ([]Snapshot, error) {
 
 	var url string
 
 	if len(href) > 0  {
-		url = fmt.Sprintf(zAppliance + href + "/snapshots", token.Name)
+		url = fmt.Sprintf(zAppliance + href + "/snapshots", c.address)
 	} else {
-		url = fmt.Sprintf(zAllSnapshots, token.Name)
+		url = fmt.Sprintf(zAllSnapshots, c.address)
 	}
 
 	snapshots := new(snapshots)
 	snapshots.List = make([]Snapshot, 0)
 
-	_, _, err := MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, snapshots)
+	_, _, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, snapshots)
 	if err != nil {
 		return nil, err
 	}
@@ -122,14 +124,14 @@ func GetSnapshots(ctx context.Context, token *Token, href string) ([]Snapshot, e
 }
 
 // Returns the clones depending on a snapshot.
-func GetSnapshotDependents(ctx context.Context, token *Token, href string) (*[]Dependent, error) {
+func (c *Client) GetSnapshotDependents(ctx context.Context, token *Token, href string) (*[]Dependent, error) {
 
-	url := fmt.Sprintf(zAppliance + href + "/dependents", token.Name)
+	url := fmt.Sprintf(zAppliance + href + "/dependents", c.address)
 
 	dependents := new(dependents)
 	dependents.List = make([]Dependent, 0)
 
-	_, _, err := MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, dependents)
+	_, _, err := c.MakeRequest(ctx, token, "GET", url, nil, http.StatusOK, dependents)
 	if err != nil {
 		return nil, err
 	}
@@ -137,10 +139,10 @@ func GetSnapshotDependents(ctx context.Context, token *Token, href string) (*[]D
 	return &dependents.List, nil
 }
 
-func (l *snapshots) UnmarshalJSON(b []byte) error {
-	return zfssaUnmarshalList(b, &l.List)
+func (l *snapshots) decodeList(dec *json.Decoder) error {
+	return decodeList(dec, "snapshots", &l.List)
 }
 
-func (l *dependents) UnmarshalJSON(b []byte) error {
-	return zfssaUnmarshalList(b, &l.List)
+func (l *dependents) decodeList(dec *json.Decoder) error {
+	return decodeList(dec, "dependents", &l.List)
 }
@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	grpcStatus "google.golang.org/grpc/status"
+)
+
+// The requests sent to the appliance are bound to the context of the caller, typically
+// the context of the gRPC request being processed. Besides, each request is given a
+// timeout depending on the kind of operation it performs. The timeout covers all the
+// attempts of the request (see RetryPolicy). A request interrupted because its context
+// was canceled or went past its deadline fails with codes.Canceled or
+// codes.DeadlineExceeded.
+
+// Timeouts of the requests sent to the appliance. A timeout of zero means the request is
+// only bound to the context of the caller.
+type Timeouts struct {
+	Create time.Duration // Requests creating an object
+	Delete time.Duration // DELETE requests
+	List   time.Duration // GET requests, reading an object or a list of objects
+	Other  time.Duration // Any other request (modification of an object...)
+}
+
+// Timeouts of new clients.
+var DefaultTimeouts = Timeouts{
+	Create: 2 * time.Minute,
+	Delete: 2 * time.Minute,
+	List:   time.Minute,
+	Other:  time.Minute,
+}
+
+// Sets the timeouts of the requests of the client. It must be called before the client
+// is used.
+func (c *Client) SetTimeouts(timeouts Timeouts) {
+	c.timeouts = timeouts
+}
+
+// Returns the timeout of a request given its method and whether it creates an object.
+func (c *Client) getTimeout(method string, create bool) time.Duration {
+	switch {
+	case create || method == "POST":
+		return c.timeouts.Create
+	case method == "DELETE":
+		return c.timeouts.Delete
+	case method == "GET":
+		return c.timeouts.List
+	}
+	return c.timeouts.Other
+}
+
+// Returns the context a request is sent with: the context passed in bounded by the
+// timeout of the request. Without context (requests sent by the driver itself, when it
+// starts for instance), the request is only bounded by its timeout.
+func (c *Client) requestContext(ctx context.Context, method string, create bool) (
+	context.Context, context.CancelFunc) {
+
+	ctx = httpContext(ctx)
+	timeout := c.getTimeout(method, create)
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Returns the context to build an HTTP request with.
+func httpContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
+
+// Returns the error to report if the context is done, nil otherwise.
+func contextError(ctx context.Context) error {
+	if ctx == nil {
+		return nil
+	}
+	switch err := ctx.Err(); {
+	case errors.Is(err, context.DeadlineExceeded):
+		return grpcStatus.Error(codes.DeadlineExceeded, "request to the target appliance timed out")
+	case errors.Is(err, context.Canceled):
+		return grpcStatus.Error(codes.Canceled, "request to the target appliance was canceled")
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssarest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/oracle/zfssa-csi-driver/pkg/zfssarest/zfssatest"
+	"google.golang.org/grpc/codes"
+	grpcStatus "google.golang.org/grpc/status"
+)
+
+func TestGetTimeout(t *testing.T) {
+	c := &Client{}
+	c.SetTimeouts(Timeouts{Create: 1, Delete: 2, List: 3, Other: 4})
+	tests := []struct {
+		method string
+		create bool
+		want   time.Duration
+	}{
+		{"POST", false, 1},
+		{"PUT", true, 1},
+		{"DELETE", false, 2},
+		{"GET", false, 3},
+		{"PUT", false, 4},
+	}
+	for _, tt := range tests {
+		if got := c.getTimeout(tt.method, tt.create); got != tt.want {
+			t.Errorf("getTimeout(%s, %v) = %v, want %v", tt.method, tt.create, got, tt.want)
+		}
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	zfssa, c, token := newTestAppliance(t)
+	c.SetRetryPolicy(testRetryPolicy)
+	c.SetTimeouts(Timeouts{List: 50 * time.Millisecond})
+
+	zfssa.AddFault(zfssatest.Fault{Method: "GET", Path: "/pools/" + testPool, Delay: time.Minute, Times: 1})
+	start := time.Now()
+	_, err := c.GetPool(context.Background(), token, testPool)
+	if grpcStatus.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Errorf("the request outlived its timeout: %v", time.Since(start))
+	}
+
+	// Other requests are not affected.
+	if _, err := c.GetProject(context.Background(), token, testPool, testProject); err != nil {
+		t.Errorf("GetProject failed: %v", err)
+	}
+}
+
+// The requests sent without context, as the ones sent when the driver starts, are bounded
+// by their timeout as well, the creation of the session included.
+func TestRequestTimeoutWithoutContext(t *testing.T) {
+	zfssa, c, token := newTestAppliance(t)
+	c.SetRetryPolicy(testRetryPolicy)
+	c.SetTimeouts(Timeouts{List: 50 * time.Millisecond})
+
+	zfssa.AddFault(zfssatest.Fault{Method: "POST", Path: "/access/", Delay: time.Minute, Times: 1})
+	start := time.Now()
+	_, err := c.GetPool(nil, token, testPool)
+	if grpcStatus.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Errorf("the request outlived its timeout: %v", time.Since(start))
+	}
+}
+
+func TestRequestCanceled(t *testing.T) {
+	zfssa, c, token := newTestAppliance(t)
+	zfssa.AddFault(zfssatest.Fault{Method: "GET", Path: "/pools/" + testPool, Delay: time.Minute, Times: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := c.GetPool(ctx, token, testPool); grpcStatus.Code(err) != codes.Canceled {
+		t.Errorf("expected Canceled, got %v", err)
+	}
+}
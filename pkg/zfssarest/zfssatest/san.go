@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssatest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Dispatches the requests sent to the SAN service (/api/san/v2).
+func (s *Server) serveSan(w http.ResponseWriter, r *http.Request, path []string, body object) {
+
+	if c, ok := match(path, "*", "target-groups"); ok {
+		switch r.Method {
+		case http.MethodGet:
+			reply(w, http.StatusOK, object{"groups": sortedList(withPrefix(s.targetGroups, c[0]+"/"))})
+		case http.MethodPost:
+			name := toString(body["name"])
+			if name == "" {
+				replyFault(w, http.StatusBadRequest, "name is required")
+				return
+			}
+			if _, exists := s.targetGroups[c[0]+"/"+name]; exists {
+				replyFault(w, http.StatusConflict, "target group %s already exists", name)
+				return
+			}
+			group := copyObject(body)
+			group["href"] = fmt.Sprintf("%s/%s/target-groups/%s", apiSan, c[0], name)
+			s.targetGroups[c[0]+"/"+name] = group
+			reply(w, http.StatusCreated, object{"group": group})
+		default:
+			replyFault(w, http.StatusMethodNotAllowed, "method %s not supported", r.Method)
+		}
+		return
+	}
+
+	if c, ok := match(path, "*", "target-groups", "*"); ok && r.Method == http.MethodGet {
+		group, found := s.targetGroups[c[0]+"/"+c[1]]
+		if !found {
+			replyFault(w, http.StatusNotFound, "target group %s not found", c[1])
+			return
+		}
+		reply(w, http.StatusOK, object{"group": group})
+		return
+	}
+
+	if c, ok := match(path, "*", "targets", "*"); ok && r.Method == http.MethodGet {
+		// A target is designated by its name (IQN) or its alias.
+		for k, target := range withPrefix(s.targets, c[0]+"/") {
+			if k == c[0]+"/"+c[1] || toString(target["alias"]) == c[1] {
+				reply(w, http.StatusOK, object{"target": target})
+				return
+			}
+		}
+		replyFault(w, http.StatusNotFound, "target %s not found", c[1])
+		return
+	}
+
+	if c, ok := match(path, "*", "initiators"); ok {
+		switch r.Method {
+		case http.MethodGet:
+			reply(w, http.StatusOK, object{"initiators": sortedList(withPrefix(s.initiators, c[0]+"/"))})
+		case http.MethodPost:
+			name := toString(body["initiator"])
+			if name == "" {
+				replyFault(w, http.StatusBadRequest, "initiator is required")
+				return
+			}
+			if _, exists := s.initiators[c[0]+"/"+name]; exists {
+				replyFault(w, http.StatusConflict, "initiator %s already exists", name)
+				return
+			}
+			initiator := copyObject(body)
+			initiator["href"] = fmt.Sprintf("%s/%s/initiators/%s", apiSan, c[0], name)
+			s.initiators[c[0]+"/"+name] = initiator
+			reply(w, http.StatusCreated, object{"initiator": initiator})
+		default:
+			replyFault(w, http.StatusMethodNotAllowed, "method %s not supported", r.Method)
+		}
+		return
+	}
+
+	if c, ok := match(path, "*", "initiators", "*"); ok {
+		key := c[0] + "/" + c[1]
+		initiator, found := s.initiators[key]
+		if !found {
+			replyFault(w, http.StatusNotFound, "initiator %s not found", c[1])
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			reply(w, http.StatusOK, object{"initiator": initiator})
+		case http.MethodDelete:
+			delete(s.initiators, key)
+			reply(w, http.StatusNoContent, nil)
+		default:
+			replyFault(w, http.StatusMethodNotAllowed, "method %s not supported", r.Method)
+		}
+		return
+	}
+
+	if c, ok := match(path, "*", "initiator-groups"); ok {
+		switch r.Method {
+		case http.MethodGet:
+			reply(w, http.StatusOK, object{"groups": sortedList(withPrefix(s.initGroups, c[0]+"/"))})
+		case http.MethodPost:
+			name := toString(body["name"])
+			if name == "" {
+				replyFault(w, http.StatusBadRequest, "name is required")
+				return
+			}
+			if _, exists := s.initGroups[c[0]+"/"+name]; exists {
+				replyFault(w, http.StatusConflict, "initiator group %s already exists", name)
+				return
+			}
+			group := copyObject(body)
+			if group["initiators"] == nil {
+				group["initiators"] = []interface{}{}
+			}
+			group["href"] = fmt.Sprintf("%s/%s/initiator-groups/%s", apiSan, c[0], name)
+			s.initGroups[c[0]+"/"+name] = group
+			reply(w, http.StatusCreated, object{"group": group})
+		default:
+			replyFault(w, http.StatusMethodNotAllowed, "method %s not supported", r.Method)
+		}
+		return
+	}
+
+	if c, ok := match(path, "*", "initiator-groups", "*"); ok {
+		key := c[0] + "/" + c[1]
+		group, found := s.initGroups[key]
+		if !found {
+			replyFault(w, http.StatusNotFound, "initiator group %s not found", c[1])
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			reply(w, http.StatusOK, object{"group": group})
+		case http.MethodPut:
+			if initiators, ok := body["initiators"]; ok {
+				group["initiators"] = initiators
+			}
+			reply(w, http.StatusAccepted, object{"group": group})
+		case http.MethodDelete:
+			delete(s.initGroups, key)
+			reply(w, http.StatusNoContent, nil)
+		default:
+			replyFault(w, http.StatusMethodNotAllowed, "method %s not supported", r.Method)
+		}
+		return
+	}
+
+	replyFault(w, http.StatusNotFound, "unknown resource %s", strings.Join(path, "/"))
+}
+
+// Returns the objects of the map passed in whose key starts with prefix.
+func withPrefix(m map[string]object, prefix string) map[string]object {
+	selected := make(map[string]object)
+	for k, v := range m {
+		if strings.HasPrefix(k, prefix) {
+			selected[k] = v
+		}
+	}
+	return selected
+}
+
+// Dispatches the requests sent to the network service (/api/network/v2).
+func (s *Server) serveNetwork(w http.ResponseWriter, r *http.Request, path []string) {
+
+	if r.Method != http.MethodGet {
+		replyFault(w, http.StatusMethodNotAllowed, "method %s not supported", r.Method)
+		return
+	}
+
+	if _, ok := match(path, "interfaces"); ok {
+		reply(w, http.StatusOK, object{"interfaces": sortedList(s.interfaces)})
+		return
+	}
+
+	if c, ok := match(path, "interfaces", "*"); ok {
+		iface, found := s.interfaces[c[0]]
+		if !found {
+			replyFault(w, http.StatusNotFound, "interface %s not found", c[0])
+			return
+		}
+		reply(w, http.StatusOK, object{"interface": iface})
+		return
+	}
+
+	replyFault(w, http.StatusNotFound, "unknown resource %s", strings.Join(path, "/"))
+}
@@ -0,0 +1,627 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+// Package zfssatest provides an in-process fake of the ZFSSA REST interface. The fake
+// serves the access, storage and SAN services of the RESTapi v2 over TLS and keeps the
+// pools, projects, shares and snapshots it is asked to create in memory. Faults (expired
+// sessions, conflicts, missing resources, lost responses, dropped connections or latency)
+// can be injected to exercise the error paths of the driver without an appliance.
+//
+// A typical test starts a server, seeds it and creates a REST client talking to it:
+//
+//	zfssa := zfssatest.NewServer("admin", "secret")
+//	defer zfssa.Close()
+//	zfssa.AddPool("p0", 100<<30)
+//	zfssa.AddProject("p0", "default")
+//	client, err := zfssarest.NewClient(zfssa.Name(), zfssa.CertFile(), true)
+package zfssatest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	apiAccess  = "/api/access/v2"
+	apiStorage = "/api/storage/v2"
+	apiSan     = "/api/san/v2"
+	apiNetwork = "/api/network/v2"
+)
+
+// Fault describes an error or a delay injected into the responses of the server. A
+// request is affected by a fault when both its method and its path match.
+type Fault struct {
+	Method  string        // Method matched, any method if empty
+	Path    string        // Substring of the URL path matched, any path if empty
+	Status  int           // HTTP status returned, the request is processed normally if 0
+	Message string        // Message of the fault returned
+	Delay   time.Duration // Delay added before the request is processed
+	Times   int           // Number of requests affected, unlimited if 0
+	// The request is processed normally before the fault is returned, as if the
+	// response had been lost.
+	Processed bool
+	// The connection is closed without a response.
+	Drop bool
+}
+
+// Request is the record of a request received by the server.
+type Request struct {
+	Method string
+	Path   string
+	Status int
+}
+
+type object map[string]interface{}
+
+// A filesystem or a LUN.
+type share struct {
+	kind   string
+	props  object
+	origin *snapshot
+}
+
+type snapshot struct {
+	share  *share
+	props  object
+	clones map[string]*share
+}
+
+// Server is a fake ZFSSA. All its methods are safe for concurrent use.
+type Server struct {
+	srv      *httptest.Server
+	dir      string
+	certFile string
+	user     string
+	password string
+	clientCA *x509.Certificate
+	caKey    *ecdsa.PrivateKey
+
+	mtx          sync.Mutex
+	latency      time.Duration
+	faults       []*Fault
+	requests     []Request
+	sessions     map[string]bool
+	sessionCount int
+	lunCount     int
+	pools        map[string]object
+	projects     map[string]object
+	shares       map[string]*share
+	snapshots    map[string]*snapshot
+	schema       map[string]object
+	targetGroups map[string]object
+	targets      map[string]object
+	initiators   map[string]object
+	initGroups   map[string]object
+	interfaces   map[string]object
+}
+
+// NewServer starts a fake appliance accepting the credentials passed in. The server
+// must be stopped by calling Close.
+func NewServer(user, password string) *Server {
+	s := &Server{
+		user:         user,
+		password:     password,
+		sessions:     make(map[string]bool),
+		pools:        make(map[string]object),
+		projects:     make(map[string]object),
+		shares:       make(map[string]*share),
+		snapshots:    make(map[string]*snapshot),
+		schema:       make(map[string]object),
+		targetGroups: make(map[string]object),
+		targets:      make(map[string]object),
+		initiators:   make(map[string]object),
+		initGroups:   make(map[string]object),
+		interfaces:   make(map[string]object),
+	}
+	if err := s.newClientCA(); err != nil {
+		panic(fmt.Sprintf("zfssatest: cannot create the client CA: %v", err))
+	}
+	s.srv = httptest.NewUnstartedServer(http.HandlerFunc(s.serveHTTP))
+	// Client certificates are verified when logging in.
+	s.srv.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	s.srv.StartTLS()
+
+	dir, err := os.MkdirTemp("", "zfssatest")
+	if err != nil {
+		s.srv.Close()
+		panic(fmt.Sprintf("zfssatest: cannot create directory: %v", err))
+	}
+	s.dir = dir
+	s.certFile = filepath.Join(dir, "zfssa.crt")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.srv.Certificate().Raw})
+	if err := os.WriteFile(s.certFile, cert, 0600); err != nil {
+		s.Close()
+		panic(fmt.Sprintf("zfssatest: cannot write certificate: %v", err))
+	}
+
+	return s
+}
+
+// Close shuts down the server and removes its certificate file.
+func (s *Server) Close() {
+	s.srv.Close()
+	_ = os.RemoveAll(s.dir)
+}
+
+// Name returns the name of the appliance (host:port) to hand over to the REST layer.
+func (s *Server) Name() string { return s.srv.Listener.Addr().String() }
+
+// CertFile returns the path of a PEM file containing the certificate of the server.
+func (s *Server) CertFile() string { return s.certFile }
+
+// Client returns an HTTP client trusting the certificate of the server.
+func (s *Server) Client() *http.Client { return s.srv.Client() }
+
+// SetLatency sets the delay applied to every request.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mtx.Lock()
+	s.latency = d
+	s.mtx.Unlock()
+}
+
+// AddFault injects a fault. Faults are evaluated in the order they were added.
+func (s *Server) AddFault(f Fault) {
+	s.mtx.Lock()
+	s.faults = append(s.faults, &f)
+	s.mtx.Unlock()
+}
+
+// ClearFaults removes all the faults injected.
+func (s *Server) ClearFaults() {
+	s.mtx.Lock()
+	s.faults = nil
+	s.mtx.Unlock()
+}
+
+// ExpireSessions invalidates all the sessions created so far. The requests carrying
+// one of them will be answered with a status 401.
+func (s *Server) ExpireSessions() {
+	s.mtx.Lock()
+	s.sessions = make(map[string]bool)
+	s.mtx.Unlock()
+}
+
+// Sessions returns the number of sessions successfully created since the server started.
+func (s *Server) Sessions() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.sessionCount
+}
+
+// OpenSessions returns the number of sessions neither closed nor expired.
+func (s *Server) OpenSessions() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return len(s.sessions)
+}
+
+// Requests returns the list of the requests received so far.
+func (s *Server) Requests() []Request {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// ResetRequests clears the list of requests received.
+func (s *Server) ResetRequests() {
+	s.mtx.Lock()
+	s.requests = nil
+	s.mtx.Unlock()
+}
+
+// AddPool creates a pool with the amount of space passed in available.
+func (s *Server) AddPool(name string, available int64) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.pools[name] = object{
+		"name":   name,
+		"status": "online",
+		"asn":    "2f5b2b6c-4b1a-4d2e-9c1a-7a6b5c4d3e2f",
+		"href":   apiStorage + "/pools/" + name,
+		"usage": object{
+			"available": available,
+			"free":      available,
+			"used":      0,
+			"total":     available,
+		},
+	}
+}
+
+// SetPoolStatus changes the status ("online", "degraded", "faulted"...) of a pool.
+func (s *Server) SetPoolStatus(name, status string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if pool, ok := s.pools[name]; ok {
+		pool["status"] = status
+	}
+}
+
+// AddProject creates a project in an existing pool.
+func (s *Server) AddProject(pool, name string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.newProject(pool, name, nil)
+}
+
+// AddTargetGroup creates a target group for the protocol ("iscsi" or "fc") passed in.
+func (s *Server) AddTargetGroup(protocol, name string, targets ...string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.targetGroups[protocol+"/"+name] = object{
+		"name":    name,
+		"targets": append([]string{}, targets...),
+		"href":    fmt.Sprintf("%s/%s/target-groups/%s", apiSan, protocol, name),
+	}
+}
+
+// AddTarget creates an iSCSI target listening on the network interfaces passed in, on
+// all the interfaces if none is passed in.
+func (s *Server) AddTarget(iqn, alias string, interfaces ...string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.targets["iscsi/"+iqn] = object{
+		"iqn":        iqn,
+		"alias":      alias,
+		"auth":       "none",
+		"interfaces": append([]string{}, interfaces...),
+		"href":       fmt.Sprintf("%s/iscsi/targets/%s", apiSan, iqn),
+	}
+}
+
+// AddNetworkInterface creates a network interface with the addresses (CIDR notation,
+// IPv4 or IPv6) passed in. A state other than "up" denotes an interface unusable.
+func (s *Server) AddNetworkInterface(name, state string, addrs ...string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	v4addrs, v6addrs := []string{}, []string{}
+	for _, addr := range addrs {
+		if strings.Contains(addr, ":") {
+			v6addrs = append(v6addrs, addr)
+		} else {
+			v4addrs = append(v4addrs, addr)
+		}
+	}
+	s.interfaces[name] = object{
+		"interface": name,
+		"label":     name,
+		"state":     state,
+		"enable":    true,
+		"v4addrs":   v4addrs,
+		"v6addrs":   v6addrs,
+		"href":      apiNetwork + "/interfaces/" + name,
+	}
+}
+
+// Lookup returns a copy of the properties of the share, snapshot or schema property
+// identified by the href passed in.
+func (s *Server) Lookup(href string) (map[string]interface{}, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if sh, ok := s.shares[href]; ok {
+		return copyObject(sh.props), true
+	}
+	if snap, ok := s.snapshots[href]; ok {
+		return copyObject(snap.props), true
+	}
+	if name, ok := strings.CutPrefix(href, apiStorage+"/schema/"); ok {
+		if property, found := s.schema[name]; found {
+			return copyObject(property), true
+		}
+	}
+	return nil, false
+}
+
+// Entry point of all requests.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+
+	rec := &recorder{ResponseWriter: w}
+	defer func() {
+		s.mtx.Lock()
+		s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Status: rec.status})
+		s.mtx.Unlock()
+	}()
+
+	// The body is read upfront, the server only notices a client going away once the
+	// body of the request has been consumed.
+	if r.Body != nil {
+		data, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(data))
+	}
+
+	fault := s.delay(r)
+	if r.Context().Err() != nil {
+		return
+	}
+	if fault != nil && fault.Drop {
+		if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			_ = conn.Close()
+		}
+		return
+	}
+	if fault != nil {
+		message := fault.Message
+		if message == "" {
+			message = "injected fault"
+		}
+		if fault.Processed {
+			s.serveRequest(httptest.NewRecorder(), r)
+		}
+		replyFault(rec, fault.Status, message)
+		return
+	}
+
+	s.serveRequest(rec, r)
+}
+
+// Processes a request.
+func (s *Server) serveRequest(rec http.ResponseWriter, r *http.Request) {
+
+	var body object
+	if r.Body != nil {
+		d := json.NewDecoder(r.Body)
+		d.UseNumber()
+		if err := d.Decode(&body); err != nil && err != io.EOF {
+			replyFault(rec, http.StatusBadRequest, "malformed request body: %v", err)
+			return
+		}
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if r.URL.Path == apiAccess && r.Method == http.MethodPost {
+		s.login(rec, r)
+		return
+	}
+
+	if !s.sessions[r.Header.Get("X-Auth-Session")] {
+		replyFault(rec, http.StatusUnauthorized, "invalid or expired session")
+		return
+	}
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case path == apiAccess:
+		s.serveAccess(rec, r)
+	case strings.HasPrefix(path, apiStorage+"/"):
+		s.serveStorage(rec, r, split(strings.TrimPrefix(path, apiStorage+"/")), body)
+	case strings.HasPrefix(path, apiSan+"/"):
+		s.serveSan(rec, r, split(strings.TrimPrefix(path, apiSan+"/")), body)
+	case strings.HasPrefix(path, apiNetwork+"/"):
+		s.serveNetwork(rec, r, split(strings.TrimPrefix(path, apiNetwork+"/")))
+	default:
+		replyFault(rec, http.StatusNotFound, "unknown service %s", path)
+	}
+}
+
+// Applies the latency configured and the delay of the first fault matching the request.
+// The fault is returned if it carries a status or drops the connection.
+func (s *Server) delay(r *http.Request) *Fault {
+	s.mtx.Lock()
+	delay := s.latency
+	var match *Fault
+	for i, f := range s.faults {
+		if (f.Method != "" && f.Method != r.Method) || !strings.Contains(r.URL.Path, f.Path) {
+			continue
+		}
+		match = f
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		break
+	}
+	if match != nil {
+		delay += match.Delay
+	}
+	s.mtx.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			timer.Stop()
+		}
+	}
+
+	if match != nil && (match.Status != 0 || match.Drop) {
+		return match
+	}
+	return nil
+}
+
+// Creates a session if the credentials are valid.
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Auth-User") == "" && r.Header.Get("X-Auth-Key") == "" {
+		if !s.verifyClientCertificate(r) {
+			replyFault(w, http.StatusUnauthorized, "invalid client certificate")
+			return
+		}
+	} else if r.Header.Get("X-Auth-User") != s.user || r.Header.Get("X-Auth-Key") != s.password {
+		replyFault(w, http.StatusUnauthorized, "invalid credentials")
+		return
+	}
+	s.sessionCount++
+	session := fmt.Sprintf("session-%08d", s.sessionCount)
+	s.sessions[session] = true
+	w.Header().Set("X-Auth-Session", session)
+	w.Header().Set("X-Auth-Name", s.user)
+	reply(w, http.StatusCreated, object{"access": object{"user": s.user}})
+}
+
+func (s *Server) serveAccess(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		services := []object{}
+		for _, name := range []string{"access", "storage", "san", "network"} {
+			services = append(services, object{
+				"name":    name,
+				"version": "2.0",
+				"uri":     fmt.Sprintf("https://%s/api/%s/v2", s.Name(), name),
+			})
+		}
+		reply(w, http.StatusOK, object{"services": services})
+	case http.MethodDelete:
+		delete(s.sessions, r.Header.Get("X-Auth-Session"))
+		reply(w, http.StatusNoContent, nil)
+	default:
+		replyFault(w, http.StatusMethodNotAllowed, "method %s not supported", r.Method)
+	}
+}
+
+// Captures the status of the response for the request log.
+type recorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Sends a JSON response. Like the appliance, the body is not terminated by a new line.
+func reply(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if body == nil {
+		return
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		panic(fmt.Sprintf("zfssatest: cannot marshal response: %v", err))
+	}
+	_, _ = w.Write(b)
+}
+
+// Sends a fault in the format used by the appliance.
+func replyFault(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	reply(w, status, object{"fault": object{
+		"message": fmt.Sprintf(format, args...),
+		"code":    status,
+		"name":    faultName(status),
+	}})
+}
+
+func faultName(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "ERR_INVALID_ARG"
+	case http.StatusUnauthorized:
+		return "ERR_UNAUTHORIZED"
+	case http.StatusForbidden:
+		return "ERR_DENIED"
+	case http.StatusNotFound:
+		return "ERR_NOT_FOUND"
+	case http.StatusConflict:
+		return "ERR_OBJECT_EXISTS"
+	case http.StatusServiceUnavailable:
+		return "ERR_BUSY"
+	default:
+		return "ERR_INTERNAL"
+	}
+}
+
+func split(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// Matches the segments of a path against a pattern in which "*" matches any segment.
+// The segments matching a "*" are returned.
+func match(segments []string, pattern ...string) ([]string, bool) {
+	if len(segments) != len(pattern) {
+		return nil, false
+	}
+	var captured []string
+	for i, p := range pattern {
+		if p == "*" {
+			captured = append(captured, segments[i])
+		} else if p != segments[i] {
+			return nil, false
+		}
+	}
+	return captured, true
+}
+
+func copyObject(o object) object {
+	var c object
+	b, _ := json.Marshal(o)
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	_ = d.Decode(&c)
+	return c
+}
+
+// Returns the list of the objects of the map passed in sorted by key.
+func sortedList(m map[string]object) []object {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	list := make([]object, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, m[k])
+	}
+	return list
+}
+
+func toInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case json.Number:
+		i, err := n.Int64()
+		if err != nil {
+			f, _ := n.Float64()
+			return int64(f)
+		}
+		return i
+	case int:
+		return int64(n)
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	case string:
+		i, _ := strconv.ParseInt(n, 10, 64)
+		return i
+	}
+	return 0
+}
+
+func toString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if str, ok := v.(string); ok {
+		return str
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// Format of the dates returned by the appliance.
+func now() string {
+	return time.Now().UTC().Format("2006-01-02T15:04:05Z")
+}
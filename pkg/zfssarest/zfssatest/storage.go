@@ -0,0 +1,518 @@
+/*
+ * Copyright (c) 2026, Oracle and/or its affiliates.
+ * Licensed under the Universal Permissive License v 1.0 as shown at https://oss.oracle.com/licenses/upl/
+ */
+
+package zfssatest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Name of a single element of each share collection in the responses.
+var shareElement = map[string]string{
+	"filesystems": "filesystem",
+	"luns":        "lun",
+}
+
+// Properties of a share that a request cannot modify.
+var readOnlyProperties = map[string]bool{
+	"name":           true,
+	"pool":           true,
+	"project":        true,
+	"href":           true,
+	"canonical_name": true,
+	"creation":       true,
+	"origin":         true,
+	"assignednumber": true,
+	"lunguid":        true,
+}
+
+// Dispatches the requests sent to the storage service (/api/storage/v2).
+func (s *Server) serveStorage(w http.ResponseWriter, r *http.Request, path []string, body object) {
+
+	if _, ok := match(path, "pools"); ok && r.Method == http.MethodGet {
+		reply(w, http.StatusOK, object{"pools": sortedList(s.pools)})
+		return
+	}
+	if c, ok := match(path, "pools", "*"); ok && r.Method == http.MethodGet {
+		pool, found := s.pools[c[0]]
+		if !found {
+			replyFault(w, http.StatusNotFound, "pool %s not found", c[0])
+			return
+		}
+		reply(w, http.StatusOK, object{"pool": pool})
+		return
+	}
+	if _, ok := match(path, "projects"); ok && r.Method == http.MethodGet {
+		reply(w, http.StatusOK, object{"projects": sortedList(s.projects)})
+		return
+	}
+	if c, ok := match(path, "pools", "*", "projects"); ok && r.Method == http.MethodGet {
+		if _, found := s.pools[c[0]]; !found {
+			replyFault(w, http.StatusNotFound, "pool %s not found", c[0])
+			return
+		}
+		projects := make(map[string]object)
+		for k, v := range s.projects {
+			if strings.HasPrefix(k, c[0]+"/") {
+				projects[k] = v
+			}
+		}
+		reply(w, http.StatusOK, object{"projects": sortedList(projects)})
+		return
+	}
+	if c, ok := match(path, "pools", "*", "projects"); ok && r.Method == http.MethodPost {
+		project, status, err := s.newProject(c[0], toString(body["name"]), body)
+		if err != nil {
+			replyFault(w, status, "%v", err)
+			return
+		}
+		reply(w, http.StatusCreated, object{"project": project})
+		return
+	}
+	if c, ok := match(path, "pools", "*", "projects", "*"); ok {
+		s.serveProject(w, r, c[0], c[1], body)
+		return
+	}
+	if c, ok := match(path, "pools", "*", "projects", "*", "snapshots"); ok && r.Method == http.MethodGet {
+		if _, found := s.projects[c[0]+"/"+c[1]]; !found {
+			replyFault(w, http.StatusNotFound, "project %s not found", c[1])
+			return
+		}
+		// Project level snapshots are not supported.
+		reply(w, http.StatusOK, object{"snapshots": []object{}})
+		return
+	}
+	if c, ok := match(path, "*"); ok && r.Method == http.MethodGet {
+		switch c[0] {
+		case "filesystems", "luns":
+			reply(w, http.StatusOK, object{c[0]: s.shareList(c[0], "")})
+			return
+		case "snapshots":
+			reply(w, http.StatusOK, object{"snapshots": s.snapshotList("")})
+			return
+		case "schema":
+			reply(w, http.StatusOK, object{"schema": sortedList(s.schema)})
+			return
+		}
+	}
+	if _, ok := match(path, "schema"); ok && r.Method == http.MethodPost {
+		s.createProperty(w, body)
+		return
+	}
+	if c, ok := match(path, "schema", "*"); ok && r.Method == http.MethodGet {
+		property, found := s.schema[c[0]]
+		if !found {
+			replyFault(w, http.StatusNotFound, "property %s not found", c[0])
+			return
+		}
+		reply(w, http.StatusOK, object{"property": property})
+		return
+	}
+
+	// Everything below is a share, a snapshot of a share or one of their collections.
+	if len(path) < 5 || path[0] != "pools" || path[2] != "projects" || shareElement[path[4]] == "" {
+		replyFault(w, http.StatusNotFound, "unknown resource %s", strings.Join(path, "/"))
+		return
+	}
+	pool, project, kind := path[1], path[3], path[4]
+	prefix := fmt.Sprintf("%s/pools/%s/projects/%s/%s", apiStorage, pool, project, kind)
+
+	switch {
+	case len(path) == 5:
+		s.serveShares(w, r, pool, project, kind, body)
+		return
+	case len(path) == 6:
+		s.serveShare(w, r, prefix+"/"+path[5], body)
+		return
+	case len(path) == 7 && path[6] == "snapshots":
+		s.serveSnapshots(w, r, prefix+"/"+path[5], body)
+		return
+	case len(path) == 8 && path[6] == "snapshots":
+		s.serveSnapshot(w, r, prefix+"/"+path[5]+"/snapshots/"+path[7])
+		return
+	case len(path) == 9 && path[6] == "snapshots":
+		href := prefix + "/" + path[5] + "/snapshots/" + path[7]
+		if path[8] == "clone" && r.Method == http.MethodPut {
+			s.cloneSnapshot(w, href, body)
+			return
+		}
+		if path[8] == "dependents" && r.Method == http.MethodGet {
+			s.dependents(w, href)
+			return
+		}
+	}
+
+	replyFault(w, http.StatusNotFound, "unknown resource %s", strings.Join(path, "/"))
+}
+
+// Handles the requests addressed to a project.
+func (s *Server) serveProject(w http.ResponseWriter, r *http.Request, pool, name string, body object) {
+
+	project, found := s.projects[pool+"/"+name]
+	if !found {
+		replyFault(w, http.StatusNotFound, "project %s not found", name)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		reply(w, http.StatusOK, object{"project": project})
+	case http.MethodPut:
+		for k, v := range body {
+			if !readOnlyProperties[k] {
+				project[k] = v
+			}
+		}
+		reply(w, http.StatusAccepted, object{"project": project})
+	case http.MethodDelete:
+		for _, sh := range s.shares {
+			if sh.props["pool"] == pool && sh.props["project"] == name {
+				replyFault(w, http.StatusConflict, "project %s is not empty", name)
+				return
+			}
+		}
+		delete(s.projects, pool+"/"+name)
+		reply(w, http.StatusNoContent, nil)
+	default:
+		replyFault(w, http.StatusMethodNotAllowed, "method %s not supported", r.Method)
+	}
+}
+
+// Handles the requests addressed to a collection of shares of a project.
+func (s *Server) serveShares(w http.ResponseWriter, r *http.Request, pool, project, kind string, body object) {
+
+	if _, found := s.projects[pool+"/"+project]; !found {
+		replyFault(w, http.StatusNotFound, "project %s not found", project)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		reply(w, http.StatusOK, object{kind: s.shareList(kind, pool+"/"+project)})
+	case http.MethodPost:
+		name := toString(body["name"])
+		if name == "" {
+			replyFault(w, http.StatusBadRequest, "name is required")
+			return
+		}
+		sh, status, err := s.newShare(pool, project, kind, name, body)
+		if err != nil {
+			replyFault(w, status, "%v", err)
+			return
+		}
+		reply(w, http.StatusCreated, object{shareElement[kind]: sh.props})
+	default:
+		replyFault(w, http.StatusMethodNotAllowed, "method %s not supported", r.Method)
+	}
+}
+
+// Handles the requests addressed to a share.
+func (s *Server) serveShare(w http.ResponseWriter, r *http.Request, href string, body object) {
+
+	sh, found := s.shares[href]
+	if !found {
+		replyFault(w, http.StatusNotFound, "share %s not found", href)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		reply(w, http.StatusOK, object{shareElement[sh.kind]: sh.props})
+	case http.MethodPut:
+		for k, v := range body {
+			if !readOnlyProperties[k] {
+				sh.props[k] = v
+			}
+		}
+		if sh.kind == "filesystems" {
+			sh.props["space_available"] = sh.props["quota"]
+		}
+		reply(w, http.StatusAccepted, object{shareElement[sh.kind]: sh.props})
+	case http.MethodDelete:
+		for _, snap := range s.snapshots {
+			if snap.share == sh && len(snap.clones) > 0 {
+				replyFault(w, http.StatusConflict, "share %s has snapshots with dependent clones",
+					sh.props["name"])
+				return
+			}
+		}
+		for snapHref, snap := range s.snapshots {
+			if snap.share == sh {
+				delete(s.snapshots, snapHref)
+			}
+		}
+		if sh.origin != nil {
+			delete(sh.origin.clones, href)
+			sh.origin.props["numclones"] = len(sh.origin.clones)
+		}
+		delete(s.shares, href)
+		reply(w, http.StatusNoContent, nil)
+	default:
+		replyFault(w, http.StatusMethodNotAllowed, "method %s not supported", r.Method)
+	}
+}
+
+// Handles the requests addressed to the snapshot collection of a share.
+func (s *Server) serveSnapshots(w http.ResponseWriter, r *http.Request, shareHref string, body object) {
+
+	sh, found := s.shares[shareHref]
+	if !found {
+		replyFault(w, http.StatusNotFound, "share %s not found", shareHref)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		reply(w, http.StatusOK, object{"snapshots": s.snapshotList(shareHref)})
+	case http.MethodPost:
+		name := toString(body["name"])
+		if name == "" {
+			replyFault(w, http.StatusBadRequest, "name is required")
+			return
+		}
+		href := shareHref + "/snapshots/" + name
+		if _, exists := s.snapshots[href]; exists {
+			replyFault(w, http.StatusConflict, "snapshot %s already exists", name)
+			return
+		}
+		snap := &snapshot{
+			share:  sh,
+			clones: make(map[string]*share),
+			props: object{
+				"name":           name,
+				"numclones":      0,
+				"creation":       now(),
+				"collection":     "local",
+				"project":        sh.props["project"],
+				"pool":           sh.props["pool"],
+				"canonical_name": fmt.Sprintf("%s@%s", sh.props["canonical_name"], name),
+				"space_unique":   0,
+				"space_data":     sh.props["space_data"],
+				"type":           "snapshot",
+				"id":             fmt.Sprintf("%016x", len(s.snapshots)+1),
+				"href":           href,
+			},
+		}
+		s.snapshots[href] = snap
+		reply(w, http.StatusCreated, object{"snapshot": snap.props})
+	default:
+		replyFault(w, http.StatusMethodNotAllowed, "method %s not supported", r.Method)
+	}
+}
+
+// Handles the requests addressed to a snapshot.
+func (s *Server) serveSnapshot(w http.ResponseWriter, r *http.Request, href string) {
+
+	snap, found := s.snapshots[href]
+	if !found {
+		replyFault(w, http.StatusNotFound, "snapshot %s not found", href)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		reply(w, http.StatusOK, object{"snapshot": snap.props})
+	case http.MethodDelete:
+		if len(snap.clones) > 0 {
+			replyFault(w, http.StatusConflict, "snapshot %s has dependent clones", snap.props["name"])
+			return
+		}
+		delete(s.snapshots, href)
+		reply(w, http.StatusNoContent, nil)
+	default:
+		replyFault(w, http.StatusMethodNotAllowed, "method %s not supported", r.Method)
+	}
+}
+
+// Creates a share from a snapshot. The project and the name of the new share are passed
+// in the body, any other property overrides the one inherited from the source share.
+func (s *Server) cloneSnapshot(w http.ResponseWriter, href string, body object) {
+
+	snap, found := s.snapshots[href]
+	if !found {
+		replyFault(w, http.StatusNotFound, "snapshot %s not found", href)
+		return
+	}
+
+	project := toString(body["project"])
+	name := toString(body["share"])
+	if project == "" || name == "" {
+		replyFault(w, http.StatusBadRequest, "project and share are required")
+		return
+	}
+
+	props := copyObject(snap.share.props)
+	for k, v := range body {
+		if k != "project" && k != "share" {
+			props[k] = v
+		}
+	}
+
+	pool := toString(snap.share.props["pool"])
+	sh, status, err := s.newShare(pool, project, snap.share.kind, name, props)
+	if err != nil {
+		replyFault(w, status, "%v", err)
+		return
+	}
+	sh.origin = snap
+	sh.props["origin"] = object{
+		"pool":       pool,
+		"project":    snap.share.props["project"],
+		"share":      snap.share.props["name"],
+		"snapshot":   snap.props["name"],
+		"collection": "local",
+	}
+	snap.clones[toString(sh.props["href"])] = sh
+	snap.props["numclones"] = len(snap.clones)
+
+	reply(w, http.StatusCreated, object{shareElement[sh.kind]: sh.props})
+}
+
+// Returns the list of the clones of a snapshot.
+func (s *Server) dependents(w http.ResponseWriter, href string) {
+
+	snap, found := s.snapshots[href]
+	if !found {
+		replyFault(w, http.StatusNotFound, "snapshot %s not found", href)
+		return
+	}
+
+	list := make(map[string]object)
+	for cloneHref, clone := range snap.clones {
+		list[cloneHref] = object{
+			"project": clone.props["project"],
+			"share":   clone.props["name"],
+			"href":    cloneHref,
+		}
+	}
+	reply(w, http.StatusOK, object{"dependents": sortedList(list)})
+}
+
+// Adds a property to the schema.
+func (s *Server) createProperty(w http.ResponseWriter, body object) {
+	name := toString(body["property"])
+	if name == "" {
+		replyFault(w, http.StatusBadRequest, "property is required")
+		return
+	}
+	if _, exists := s.schema[name]; exists {
+		replyFault(w, http.StatusConflict, "property %s already exists", name)
+		return
+	}
+	property := copyObject(body)
+	property["href"] = apiStorage + "/schema/" + name
+	s.schema[name] = property
+	reply(w, http.StatusCreated, object{"property": property})
+}
+
+// Creates a project, the caller must hold the lock of the server.
+func (s *Server) newProject(pool, name string, props object) (object, int, error) {
+	if name == "" {
+		return nil, http.StatusBadRequest, fmt.Errorf("name is required")
+	}
+	if _, found := s.pools[pool]; !found {
+		return nil, http.StatusNotFound, fmt.Errorf("pool %s not found", pool)
+	}
+	if _, exists := s.projects[pool+"/"+name]; exists {
+		return nil, http.StatusConflict, fmt.Errorf("project %s already exists", name)
+	}
+	project := object{}
+	for k, v := range props {
+		project[k] = v
+	}
+	project["name"] = name
+	project["pool"] = pool
+	project["href"] = fmt.Sprintf("%s/pools/%s/projects/%s", apiStorage, pool, name)
+	project["canonical_name"] = fmt.Sprintf("%s/local/%s", pool, name)
+	project["creation"] = now()
+	project["space_available"] = s.pools[pool]["usage"].(object)["available"]
+	s.projects[pool+"/"+name] = project
+	return project, http.StatusCreated, nil
+}
+
+// Creates a filesystem or a LUN, the caller must hold the lock of the server.
+func (s *Server) newShare(pool, project, kind, name string, props object) (*share, int, error) {
+
+	if _, found := s.projects[pool+"/"+project]; !found {
+		return nil, http.StatusNotFound, fmt.Errorf("project %s not found", project)
+	}
+
+	href := fmt.Sprintf("%s/pools/%s/projects/%s/%s/%s", apiStorage, pool, project, kind, name)
+	for _, k := range []string{"filesystems", "luns"} {
+		other := fmt.Sprintf("%s/pools/%s/projects/%s/%s/%s", apiStorage, pool, project, k, name)
+		if _, exists := s.shares[other]; exists {
+			return nil, http.StatusConflict, fmt.Errorf("share %s already exists", name)
+		}
+	}
+
+	sh := &share{kind: kind, props: object{}}
+	for k, v := range props {
+		if !readOnlyProperties[k] {
+			sh.props[k] = v
+		}
+	}
+	sh.props["name"] = name
+	sh.props["pool"] = pool
+	sh.props["project"] = project
+	sh.props["href"] = href
+	sh.props["canonical_name"] = fmt.Sprintf("%s/local/%s/%s", pool, project, name)
+	sh.props["creation"] = now()
+	if _, ok := sh.props["space_data"]; !ok {
+		sh.props["space_data"] = 0
+	}
+
+	switch kind {
+	case "filesystems":
+		sh.props["mountpoint"] = fmt.Sprintf("/export/%s", name)
+		if _, ok := sh.props["quota"]; !ok {
+			sh.props["quota"] = 0
+		}
+		sh.props["space_available"] = sh.props["quota"]
+		sh.props["readonly"] = sh.props["readonly"] == true
+	case "luns":
+		if toInt64(sh.props["volsize"]) <= 0 {
+			return nil, http.StatusBadRequest, fmt.Errorf("invalid volume size")
+		}
+		if _, ok := sh.props["initiatorgroup"]; !ok {
+			sh.props["initiatorgroup"] = []string{"com.sun.ms.vss.hg.maskAll"}
+		}
+		s.lunCount++
+		sh.props["assignednumber"] = []int{s.lunCount}
+		sh.props["lunguid"] = fmt.Sprintf("600144F0%024X", s.lunCount)
+	}
+
+	s.shares[href] = sh
+	return sh, http.StatusCreated, nil
+}
+
+// Returns the shares of a kind, all of them or only those of the project (pool/project)
+// passed in, sorted by href.
+func (s *Server) shareList(kind, project string) []object {
+	list := make(map[string]object)
+	for href, sh := range s.shares {
+		if sh.kind != kind {
+			continue
+		}
+		if project != "" && toString(sh.props["pool"])+"/"+toString(sh.props["project"]) != project {
+			continue
+		}
+		list[href] = sh.props
+	}
+	return sortedList(list)
+}
+
+// Returns the snapshots of a share or, if no share is passed in, all the snapshots.
+func (s *Server) snapshotList(shareHref string) []object {
+	list := make(map[string]object)
+	for href, snap := range s.snapshots {
+		if shareHref != "" && toString(snap.share.props["href"]) != shareHref {
+			continue
+		}
+		list[href] = snap.props
+	}
+	return sortedList(list)
+}